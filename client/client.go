@@ -0,0 +1,270 @@
+// Package client is a Go client for the weather dashboard HTTP API. It
+// retries transient failures, honours context cancellation and decodes API
+// errors into *Error.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"weather-dashboard/internal/backoff"
+	"weather-dashboard/logging"
+	"weather-dashboard/models"
+)
+
+// Defaults applied to zero Options fields
+const (
+	DefaultTimeout        = 10 * time.Second
+	DefaultMaxRetries     = 2
+	DefaultRetryBaseDelay = 200 * time.Millisecond
+	DefaultRetryMaxDelay  = 5 * time.Second
+)
+
+const (
+	// apiPrefix is the API version this client speaks
+	apiPrefix = "/api/v1"
+
+	// Headers understood by the API, see the middleware package
+	apiKeyHeader    = "X-API-Key"
+	requestIDHeader = "X-Request-ID"
+
+	// maxResponseBytes bounds how much of a response is read
+	maxResponseBytes = 10 << 20
+)
+
+// Options configures a Client. Zero values use the defaults.
+type Options struct {
+	// HTTPClient sends the requests. It defaults to a client with
+	// DefaultTimeout per attempt.
+	HTTPClient *http.Client
+	// APIKey is sent as X-API-Key. Keys configured on the server get their
+	// own rate limit; others are limited by client IP.
+	APIKey string
+	// UserAgent identifies the calling service
+	UserAgent string
+	// MaxRetries is the number of retries after the first attempt. Negative
+	// disables retries.
+	MaxRetries int
+	// RetryBaseDelay is the backoff before the first retry, doubling for
+	// each further retry up to RetryMaxDelay
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the backoff. A longer Retry-After from the API
+	// fails the call instead of waiting.
+	RetryMaxDelay time.Duration
+}
+
+// Client calls the weather dashboard HTTP API. It is safe for concurrent
+// use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiKey     string
+	userAgent  string
+	maxRetries int
+	backoff    backoff.Policy
+}
+
+// New creates a client for the API served at baseURL, such as
+// "https://weather.example.com"
+func New(baseURL string, opts Options) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: must be an absolute http(s) URL", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: opts.HTTPClient,
+		apiKey:     opts.APIKey,
+		userAgent:  opts.UserAgent,
+		maxRetries: opts.MaxRetries,
+		backoff:    backoff.Policy{BaseDelay: opts.RetryBaseDelay, MaxDelay: opts.RetryMaxDelay},
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	switch {
+	case c.maxRetries == 0:
+		c.maxRetries = DefaultMaxRetries
+	case c.maxRetries < 0:
+		c.maxRetries = 0
+	}
+	if c.backoff.BaseDelay <= 0 {
+		c.backoff.BaseDelay = DefaultRetryBaseDelay
+	}
+	if c.backoff.MaxDelay <= 0 {
+		c.backoff.MaxDelay = DefaultRetryMaxDelay
+	}
+	return c, nil
+}
+
+// GetWeatherByCity returns the current weather for a city
+func (c *Client) GetWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
+	var data models.WeatherData
+	if err := c.get(ctx, "/weather/"+url.PathEscape(city), nil, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// GetWeatherByCoordinates returns the current weather at a latitude and
+// longitude in decimal degrees
+func (c *Client) GetWeatherByCoordinates(ctx context.Context, lat, lon float64) (*models.WeatherData, error) {
+	path := fmt.Sprintf("/weather/coordinates/%s/%s", formatCoordinate(lat), formatCoordinate(lon))
+
+	var data models.WeatherData
+	if err := c.get(ctx, path, nil, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// GetForecast returns a daily forecast for a city. A zero days uses the
+// API's default.
+func (c *Client) GetForecast(ctx context.Context, city string, days int) (*models.Forecast, error) {
+	var query url.Values
+	if days != 0 {
+		query = url.Values{"days": {strconv.Itoa(days)}}
+	}
+
+	var forecast models.Forecast
+	if err := c.get(ctx, "/forecast/"+url.PathEscape(city), query, &forecast); err != nil {
+		return nil, err
+	}
+	return &forecast, nil
+}
+
+// SearchCity returns the locations matching a query
+func (c *Client) SearchCity(ctx context.Context, query string) ([]models.WeatherAPISearchResult, error) {
+	var results []models.WeatherAPISearchResult
+	if err := c.get(ctx, "/search", url.Values{"q": {query}}, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// History returns up to limit stored observations, newest first. A zero
+// limit applies the server's configured history limit.
+func (c *Client) History(ctx context.Context, limit int) ([]models.WeatherData, error) {
+	var query url.Values
+	if limit != 0 {
+		query = url.Values{"limit": {strconv.Itoa(limit)}}
+	}
+
+	var history []models.WeatherData
+	if err := c.get(ctx, "/history", query, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// get sends a GET request to an escaped API path, retrying transient
+// failures, and decodes the JSON response into out
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	endpoint := c.baseURL.JoinPath(apiPrefix + path)
+	endpoint.RawQuery = query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		err := c.do(ctx, endpoint.String(), out)
+		if err == nil {
+			return nil
+		}
+
+		retryAfter, retryable := retryable(err)
+		if !retryable || attempt >= c.maxRetries || ctx.Err() != nil {
+			return err
+		}
+		delay, ok := c.backoff.Delay(attempt, retryAfter)
+		if !ok {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// do makes one attempt of a GET request
+func (c *Client) do(ctx context.Context, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set(apiKeyHeader, c.apiKey)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		req.Header.Set(requestIDHeader, requestID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &transportError{err: err}
+	}
+	defer resp.Body.Close()
+
+	body := io.LimitReader(resp.Body, maxResponseBytes)
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp, body)
+	}
+
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", req.URL.Path, err)
+	}
+	return nil
+}
+
+// transportError marks a request that failed before a response arrived
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+// retryable reports whether a failed attempt may be retried, and the delay
+// the API asked for if any. Requests are only GETs, so connection failures
+// are safe to retry.
+func retryable(err error) (time.Duration, bool) {
+	var transportErr *transportError
+	if errors.As(err, &transportErr) {
+		return 0, true
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		// Neither clears up by itself within a few seconds
+		if apiErr.Code == models.ErrorCodeQuotaExceeded || apiErr.Code == models.ErrorCodeUpstreamUnauthorized {
+			return 0, false
+		}
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return apiErr.RetryAfter, true
+		}
+	}
+	return 0, false
+}
+
+// formatCoordinate formats a coordinate without redundant digits
+func formatCoordinate(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
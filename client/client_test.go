@@ -0,0 +1,373 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"weather-dashboard/handlers"
+	"weather-dashboard/logging"
+	"weather-dashboard/models"
+)
+
+// stubWeatherService knows a few cities and fails upstream for "Offline"
+type stubWeatherService struct {
+	lastLat, lastLon string
+	lastDays         int
+}
+
+func (s *stubWeatherService) SearchCity(ctx context.Context, city string) ([]models.WeatherAPISearchResult, error) {
+	if strings.EqualFold(city, "nowhere") {
+		return nil, nil
+	}
+	return []models.WeatherAPISearchResult{{Name: "Springfield", Region: "Illinois", Country: "USA"}}, nil
+}
+
+func (s *stubWeatherService) GetWeatherByCoordinates(ctx context.Context, lat, lon string) (*models.WeatherData, error) {
+	s.lastLat, s.lastLon = lat, lon
+	return &models.WeatherData{City: "London", Temperature: 14}, nil
+}
+
+func (s *stubWeatherService) GetWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
+	switch city {
+	case "London", "São Paulo":
+		return &models.WeatherData{City: city, Temperature: 15.5, Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}, nil
+	case "Offline":
+		return nil, fmt.Errorf("%w: connection refused", models.ErrUpstreamUnavailable)
+	}
+	return nil, fmt.Errorf("city %w: %s", models.ErrNotFound, city)
+}
+
+func (s *stubWeatherService) GetForecast(ctx context.Context, city string, days int) (*models.Forecast, error) {
+	s.lastDays = days
+	return &models.Forecast{City: city, Days: make([]models.ForecastDay, days)}, nil
+}
+
+// stubDatabaseService serves a fixed history
+type stubDatabaseService struct {
+	historyLimit int
+}
+
+func (s *stubDatabaseService) SaveWeatherData(ctx context.Context, data *models.WeatherData) error {
+	return nil
+}
+
+func (s *stubDatabaseService) GetWeatherHistory(ctx context.Context, limit int) ([]models.WeatherData, error) {
+	s.historyLimit = limit
+	return []models.WeatherData{{ID: 2, City: "Paris"}, {ID: 1, City: "London"}}, nil
+}
+
+func (s *stubDatabaseService) GetWeatherHistoryDefault(ctx context.Context) ([]models.WeatherData, error) {
+	s.historyLimit = -1
+	return []models.WeatherData{{ID: 2, City: "Paris"}}, nil
+}
+
+func (s *stubDatabaseService) StreamWeatherHistory(ctx context.Context, filter models.HistoryFilter, fn func(*models.WeatherData) error) error {
+	return nil
+}
+
+func (s *stubDatabaseService) Close() error {
+	return nil
+}
+
+// newAPIServer serves the real v1 handlers backed by stubs
+func newAPIServer(t *testing.T, weatherService *stubWeatherService, dbService *stubDatabaseService) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := handlers.NewWeatherHandler(weatherService, dbService)
+	v1 := r.Group("/api/v1")
+	v1.GET("/weather/:city", h.GetWeatherByCity)
+	v1.GET("/weather/coordinates/:lat/:lon", h.GetWeatherByCoordinates)
+	v1.GET("/forecast/:city", h.GetForecast)
+	v1.GET("/search", h.SearchCities)
+	v1.GET("/history", h.GetWeatherHistory)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(t *testing.T, baseURL string, opts Options) *Client {
+	if opts.RetryBaseDelay == 0 {
+		opts.RetryBaseDelay = time.Millisecond
+	}
+	c, err := New(baseURL, opts)
+	require.NoError(t, err)
+	return c
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		valid   bool
+	}{
+		{"https://weather.example.com", true},
+		{"http://localhost:8080/dashboard/", true},
+		{"weather.example.com", false},
+		{"ftp://weather.example.com", false},
+		{"://", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.baseURL, func(t *testing.T) {
+			_, err := New(tt.baseURL, Options{})
+			assert.Equal(t, tt.valid, err == nil)
+		})
+	}
+}
+
+func TestClient_AgainstAPI(t *testing.T) {
+	weatherService, dbService := &stubWeatherService{}, &stubDatabaseService{}
+	server := newAPIServer(t, weatherService, dbService)
+	c := newTestClient(t, server.URL, Options{})
+	ctx := context.Background()
+
+	t.Run("weather by city", func(t *testing.T) {
+		data, err := c.GetWeatherByCity(ctx, "são paulo")
+		require.NoError(t, err)
+		assert.Equal(t, "São Paulo", data.City)
+		assert.Equal(t, 15.5, data.Temperature)
+		assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), data.Timestamp)
+	})
+
+	t.Run("weather by coordinates", func(t *testing.T) {
+		data, err := c.GetWeatherByCoordinates(ctx, 51.5074, -0.1278)
+		require.NoError(t, err)
+		assert.Equal(t, "London", data.City)
+		assert.Equal(t, "51.5074", weatherService.lastLat)
+		assert.Equal(t, "-0.1278", weatherService.lastLon)
+	})
+
+	t.Run("forecast", func(t *testing.T) {
+		forecast, err := c.GetForecast(ctx, "New York", 5)
+		require.NoError(t, err)
+		assert.Equal(t, "New York", forecast.City)
+		assert.Len(t, forecast.Days, 5)
+		assert.Equal(t, 5, weatherService.lastDays)
+
+		_, err = c.GetForecast(ctx, "London", 0)
+		require.NoError(t, err)
+		assert.Equal(t, models.DefaultForecastDays, weatherService.lastDays)
+
+		_, err = c.GetForecast(ctx, "London", models.MaxForecastDays+1)
+		assert.ErrorIs(t, err, models.ErrInvalidInput)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		results, err := c.SearchCity(ctx, "spring field")
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "Springfield", results[0].Name)
+
+		results, err = c.SearchCity(ctx, "nowhere")
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("history", func(t *testing.T) {
+		history, err := c.History(ctx, 2)
+		require.NoError(t, err)
+		assert.Len(t, history, 2)
+		assert.Equal(t, 2, dbService.historyLimit)
+
+		history, err = c.History(ctx, 0)
+		require.NoError(t, err)
+		assert.Len(t, history, 1)
+		assert.Equal(t, -1, dbService.historyLimit)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := c.GetWeatherByCity(ctx, "Atlantis")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+
+		var apiErr *Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Equal(t, models.ErrorCodeNotFound, apiErr.Code)
+	})
+
+	t.Run("validation error details", func(t *testing.T) {
+		_, err := c.GetWeatherByCoordinates(ctx, 91, 0)
+		assert.ErrorIs(t, err, models.ErrInvalidInput)
+
+		var apiErr *Error
+		require.ErrorAs(t, err, &apiErr)
+		require.Len(t, apiErr.Details, 1)
+		assert.Equal(t, "lat", apiErr.Details[0].Field)
+
+		_, err = c.History(ctx, models.MaxHistoryLimit+1)
+		assert.ErrorIs(t, err, models.ErrInvalidInput)
+	})
+
+	t.Run("upstream unavailable after retries", func(t *testing.T) {
+		_, err := c.GetWeatherByCity(ctx, "Offline")
+		assert.ErrorIs(t, err, models.ErrUpstreamUnavailable)
+
+		var apiErr *Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	})
+}
+
+func TestClient_Retries(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []func(w http.ResponseWriter)
+		opts      Options
+		attempts  int32
+		wantErr   bool
+	}{
+		{
+			name: "retries until success",
+			responses: []func(w http.ResponseWriter){
+				respond(http.StatusServiceUnavailable, `{"error":"down","code":"upstream_unavailable"}`, ""),
+				respond(http.StatusBadGateway, `<html>bad gateway</html>`, ""),
+				respond(http.StatusOK, `{"city":"London"}`, ""),
+			},
+			attempts: 3,
+		},
+		{
+			name: "retries when rate limited",
+			responses: []func(w http.ResponseWriter){
+				respond(http.StatusTooManyRequests, `{"error":"slow down","code":"rate_limited"}`, "0"),
+				respond(http.StatusOK, `{"city":"London"}`, ""),
+			},
+			attempts: 2,
+		},
+		{
+			name: "gives up when Retry-After exceeds the maximum delay",
+			responses: []func(w http.ResponseWriter){
+				respond(http.StatusTooManyRequests, `{"error":"slow down","code":"rate_limited"}`, "60"),
+			},
+			attempts: 1,
+			wantErr:  true,
+		},
+		{
+			name: "quota exhaustion is not retried",
+			responses: []func(w http.ResponseWriter){
+				respond(http.StatusTooManyRequests, `{"error":"quota","code":"quota_exceeded"}`, ""),
+			},
+			attempts: 1,
+			wantErr:  true,
+		},
+		{
+			name: "client errors are not retried",
+			responses: []func(w http.ResponseWriter){
+				respond(http.StatusBadRequest, `{"error":"bad","code":"invalid_input"}`, ""),
+			},
+			attempts: 1,
+			wantErr:  true,
+		},
+		{
+			name: "stops after the maximum retries",
+			responses: []func(w http.ResponseWriter){
+				respond(http.StatusServiceUnavailable, `{"error":"down"}`, ""),
+			},
+			opts:     Options{MaxRetries: 3},
+			attempts: 4,
+			wantErr:  true,
+		},
+		{
+			name: "retries disabled",
+			responses: []func(w http.ResponseWriter){
+				respond(http.StatusServiceUnavailable, `{"error":"down"}`, ""),
+			},
+			opts:     Options{MaxRetries: -1},
+			attempts: 1,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(attempts.Add(1)) - 1
+				tt.responses[min(n, len(tt.responses)-1)](w)
+			}))
+			defer server.Close()
+
+			tt.opts.RetryMaxDelay = time.Second
+			c := newTestClient(t, server.URL, tt.opts)
+
+			data, err := c.GetWeatherByCity(context.Background(), "London")
+			assert.Equal(t, tt.attempts, attempts.Load())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "London", data.City)
+		})
+	}
+}
+
+func respond(status int, body, retryAfter string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}
+}
+
+func TestClient_ContextCancelStopsRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server.URL, Options{RetryBaseDelay: time.Minute, RetryMaxDelay: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.History(ctx, 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestClient_Headers(t *testing.T) {
+	var got http.Header
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		path = r.URL.EscapedPath()
+		fmt.Fprint(w, `{"city":"New York"}`)
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server.URL+"/dashboard/", Options{APIKey: "key-1", UserAgent: "alerts/1.0"})
+	ctx := logging.WithRequestID(context.Background(), "req-123")
+
+	_, err := c.GetWeatherByCity(ctx, "New York")
+	require.NoError(t, err)
+	assert.Equal(t, "/dashboard/api/v1/weather/New%20York", path)
+	assert.Equal(t, "key-1", got.Get(apiKeyHeader))
+	assert.Equal(t, "alerts/1.0", got.Get("User-Agent"))
+	assert.Equal(t, "req-123", got.Get(requestIDHeader))
+}
+
+func TestError(t *testing.T) {
+	err := error(&Error{StatusCode: http.StatusNotFound, Code: models.ErrorCodeNotFound, Message: "city not found: Atlantis"})
+	assert.Equal(t, "weather API: 404 not_found: city not found: Atlantis", err.Error())
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+	assert.False(t, errors.Is(err, models.ErrInvalidInput))
+
+	err = &Error{StatusCode: http.StatusBadGateway, Message: "Bad Gateway"}
+	assert.Equal(t, "weather API: 502 Bad Gateway", err.Error())
+	assert.Nil(t, errors.Unwrap(err))
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"weather-dashboard/internal/backoff"
+	"weather-dashboard/models"
+)
+
+// Error is an error response from the API
+type Error struct {
+	// StatusCode is the HTTP status
+	StatusCode int
+	// Code is the stable API error code, such as models.ErrorCodeNotFound,
+	// or "" if the response was not an API error body
+	Code string
+	// Message describes the problem
+	Message string
+	// Details lists the invalid fields of a validation error
+	Details []models.FieldError
+	// RetryAfter is the delay the API asked for before retrying, if any
+	RetryAfter time.Duration
+	// RequestID identifies the request in the API's logs
+	RequestID string
+}
+
+// sentinelErrors maps API error codes back to the service errors they were
+// derived from
+var sentinelErrors = map[string]error{
+	models.ErrorCodeInvalidInput:         models.ErrInvalidInput,
+	models.ErrorCodeNotFound:             models.ErrNotFound,
+	models.ErrorCodeQuotaExceeded:        models.ErrQuotaExceeded,
+	models.ErrorCodeUpstreamUnavailable:  models.ErrUpstreamUnavailable,
+	models.ErrorCodeUpstreamUnauthorized: models.ErrUnauthorized,
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("weather API: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("weather API: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Unwrap returns the models error matching the error code, so callers can
+// test errors with errors.Is(err, models.ErrNotFound)
+func (e *Error) Unwrap() error {
+	return sentinelErrors[e.Code]
+}
+
+// decodeError reads a non-200 response into an *Error. Bodies that are not
+// a models.APIError, such as a proxy's error page, keep the status text as
+// the message.
+func decodeError(resp *http.Response, body io.Reader) error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+		RetryAfter: backoff.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		RequestID:  resp.Header.Get(requestIDHeader),
+	}
+
+	var decoded models.APIError
+	if err := json.NewDecoder(body).Decode(&decoded); err == nil && decoded.Error != "" {
+		apiErr.Code = decoded.Code
+		apiErr.Message = decoded.Error
+		apiErr.Details = decoded.Details
+	}
+	return apiErr
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"weather-dashboard/config"
+	"weather-dashboard/models"
+	"weather-dashboard/services"
+)
+
+// commands maps subcommand names to their entry points. Without a known
+// subcommand the binary runs the server.
+var commands = map[string]func(ctx context.Context, args []string, stdout io.Writer) error{
+	"import":   runImport,
+	"get":      runGet,
+	"forecast": runForecast,
+	"history":  runHistory,
+	"search":   runSearch,
+	"db":       runDB,
+}
+
+// runImport imports observations from CSV or NDJSON files ("-" for stdin)
+// into the configured database and prints the import report as JSON
+func runImport(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "input format: csv or ndjson (default: from the file extension)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: weather-dashboard import [-format csv|ndjson] <file>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no input files")
+	}
+
+	cfg, err := config.LoadLocal()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	dbService, err := services.NewDatabaseService(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database service: %w", err)
+	}
+	defer dbService.Close()
+
+	importer := services.NewImporter(dbService)
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+
+	failed := 0
+	for _, path := range fs.Args() {
+		fileFormat := *format
+		if fileFormat == "" {
+			fileFormat = formatFromPath(path)
+		}
+
+		report, err := importFile(ctx, importer, path, fileFormat)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		failed += report.Failed
+
+		if err := encoder.Encode(struct {
+			File string `json:"file"`
+			*models.ImportReport
+		}{path, report}); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d lines were rejected", failed)
+	}
+	return nil
+}
+
+// importFile imports a single file, or stdin for "-"
+func importFile(ctx context.Context, importer *services.Importer, path, format string) (*models.ImportReport, error) {
+	if path == "-" {
+		return importer.Import(ctx, os.Stdin, format)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return importer.Import(ctx, file, format)
+}
+
+// formatFromPath guesses a file's format from its extension
+func formatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return models.FormatNDJSON
+	default:
+		return models.FormatCSV
+	}
+}
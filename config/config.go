@@ -1,81 +1,407 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds all application configuration
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Weather  WeatherConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Weather   WeatherConfig
+	RateLimit RateLimitConfig
+	Quota     QuotaConfig
+	Admin     AdminConfig
+	Health    HealthConfig
+	GraphQL   GraphQLConfig
+	GRPC      GRPCConfig
+	Log       LogConfig
+	Tracing   TracingConfig
+	Reload    ReloadConfig
+	Retention RetentionConfig
+
+	// File is the config file the settings were loaded from, if any
+	File string
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
-	Port string
-	Host string
+	Port              string
+	Host              string
+	ShutdownTimeout   time.Duration
+	ReadHeaderTimeout time.Duration
+	CORSOrigins       []string
+	// TrustedProxies are the IPs or CIDR ranges whose X-Forwarded-For
+	// header is believed when finding the client IP; none by default
+	TrustedProxies []string
 }
 
 // DatabaseConfig holds database-related configuration
 type DatabaseConfig struct {
-	Path string
+	Path         string
+	HistoryLimit int
 }
 
 // WeatherConfig holds weather API-related configuration
 type WeatherConfig struct {
-	APIKey     string
-	BaseURL    string
-	SearchURL  string
-	CurrentURL string
+	APIKey      string
+	APIKeys     []string
+	BaseURL     string
+	SearchURL   string
+	CurrentURL  string
+	ForecastURL string
+	Timeout     time.Duration
+	CacheTTL    time.Duration
+
+	// Maximum age of a stored reading served when the upstream fails;
+	// 0 disables stale serving
+	MaxStaleness time.Duration
+
+	// Retries of transient upstream failures
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+
+	// Circuit breaker; disabled when BreakerThreshold is 0
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
 }
 
-// Load loads configuration from environment variables
-func Load() (*Config, error) {
-	// Load .env file if it exists
-	if err := godotenv.Load(".env"); err != nil {
-		log.Printf("Warning: .env file not found, using system environment variables")
-	} else {
-		log.Println("Loaded .env file")
+// Keys returns every configured API key, primary key first, without
+// duplicates
+func (w WeatherConfig) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range append([]string{w.APIKey}, w.APIKeys...) {
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
 	}
+	return keys
+}
+
+// RateLimitConfig holds per-route-group rate limiting configuration
+type RateLimitConfig struct {
+	Enabled bool
+	General RateLimitRule
+	API     RateLimitRule
+	Batch   RateLimitRule
+	// APIKeys are the client keys accepted in X-API-Key. Each gets its own
+	// buckets; other callers are limited by IP.
+	APIKeys []string
+}
+
+// RateLimitRule describes a token bucket: Rate tokens are added per second
+// up to a maximum of Burst tokens
+type RateLimitRule struct {
+	Rate  float64
+	Burst int
+}
+
+// Quota modes applied once the upstream budget is exhausted
+const (
+	QuotaModeRefuse = "refuse"
+	QuotaModeCache  = "cache"
+)
+
+// QuotaConfig holds upstream call budget configuration.
+// A limit of zero means unlimited.
+type QuotaConfig struct {
+	DailyLimit   int
+	MonthlyLimit int
+	Mode         string
+}
+
+// AdminConfig holds configuration for admin endpoints
+type AdminConfig struct {
+	Token string
+}
+
+// LogConfig holds logging configuration
+type LogConfig struct {
+	Level string
+}
 
-	config := &Config{
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Exporter    string
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
+}
+
+// ReloadConfig holds configuration hot reload settings
+type ReloadConfig struct {
+	WatchInterval time.Duration
+}
+
+// RetentionConfig holds the raw observation retention and downsampling
+// policy. Raw rows older than RawDays are rolled up into hourly and daily
+// aggregates and deleted; hourly aggregates are kept for HourlyDays. Zero
+// days keeps data forever.
+type RetentionConfig struct {
+	RawDays        int
+	HourlyDays     int
+	Interval       time.Duration
+	VacuumInterval time.Duration
+}
+
+// HealthConfig holds readiness check configuration
+type HealthConfig struct {
+	CheckUpstream bool
+}
+
+// GraphQLConfig holds the limits applied to GraphQL queries before they run
+type GraphQLConfig struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// GRPCConfig holds the gRPC server configuration. An empty port disables
+// the server.
+type GRPCConfig struct {
+	Port string
+	// StreamInterval is the shortest time between polls of a StreamUpdates
+	// call, and the interval used when the client asks for none
+	StreamInterval time.Duration
+	// MaxStreams caps the StreamUpdates calls open at once
+	MaxStreams int
+	// MaxStreamCities caps the cities a single StreamUpdates call watches
+	MaxStreamCities int
+}
+
+// Default returns the built-in configuration defaults
+func Default() *Config {
+	return &Config{
 		Server: ServerConfig{
-			Port: getEnv("PORT", "8080"),
-			Host: getEnv("HOST", "localhost"),
+			Port:              "8080",
+			Host:              "localhost",
+			ShutdownTimeout:   15 * time.Second,
+			ReadHeaderTimeout: 10 * time.Second,
 		},
 		Database: DatabaseConfig{
-			Path: getEnv("DB_PATH", "./weather.db"),
+			Path:         "./weather.db",
+			HistoryLimit: 3,
 		},
 		Weather: WeatherConfig{
-			APIKey:     getEnv("WEATHERAPI_KEY", ""),
-			BaseURL:    "http://api.weatherapi.com/v1",
-			SearchURL:  "http://api.weatherapi.com/v1/search.json",
-			CurrentURL: "http://api.weatherapi.com/v1/current.json",
+			BaseURL:          "http://api.weatherapi.com/v1",
+			Timeout:          10 * time.Second,
+			CacheTTL:         time.Hour,
+			MaxStaleness:     6 * time.Hour,
+			MaxRetries:       2,
+			RetryBaseDelay:   250 * time.Millisecond,
+			RetryMaxDelay:    5 * time.Second,
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			General: RateLimitRule{Rate: 30, Burst: 50},
+			API:     RateLimitRule{Rate: 10, Burst: 20},
+			Batch:   RateLimitRule{Rate: 1, Burst: 5},
+		},
+		Quota: QuotaConfig{
+			Mode: QuotaModeCache,
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:      8,
+			MaxComplexity: 500,
 		},
+		GRPC: GRPCConfig{
+			StreamInterval:  5 * time.Minute,
+			MaxStreams:      50,
+			MaxStreamCities: 10,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1.0,
+		},
+		Retention: RetentionConfig{
+			HourlyDays:     365,
+			Interval:       time.Hour,
+			VacuumInterval: 7 * 24 * time.Hour,
+		},
+	}
+}
+
+// Load loads configuration from the optional CONFIG_FILE and environment
+// variables
+func Load() (*Config, error) {
+	return Parse(nil)
+}
+
+// LoadLocal loads configuration like Load for CLI commands. A WeatherAPI
+// key is not required, since not every command calls the upstream, and a
+// missing .env file is only logged at debug level.
+func LoadLocal() (*Config, error) {
+	return parse(nil, parseOptions{quiet: true})
+}
+
+// parseOptions adjusts Parse for callers other than the server
+type parseOptions struct {
+	// requireWeatherKey rejects configurations without a WeatherAPI key
+	requireWeatherKey bool
+	// quiet logs .env discovery at debug level
+	quiet bool
+	// reload reads .env afresh instead of loading it into the process
+	// environment, which still holds the values loaded at startup
+	reload bool
+}
+
+// Parse loads configuration in layers, each overriding the previous one:
+// built-in defaults, a YAML or TOML config file (-config flag or CONFIG_FILE),
+// environment variables and finally command-line flags. All problems found
+// while loading and validating are reported together.
+func Parse(args []string) (*Config, error) {
+	return parse(args, parseOptions{requireWeatherKey: true})
+}
+
+func parse(args []string, opts parseOptions) (*Config, error) {
+	// Load .env file if it exists
+	missingLevel, loadedLevel := slog.LevelWarn, slog.LevelInfo
+	if opts.quiet {
+		missingLevel, loadedLevel = slog.LevelDebug, slog.LevelDebug
+	}
+	getenv, err := loadEnv(opts.reload)
+	if err != nil {
+		slog.Log(context.Background(), missingLevel, ".env file not found, using system environment variables")
+	} else {
+		slog.Log(context.Background(), loadedLevel, "loaded .env file")
+	}
+
+	fs, configFile, flagValues := newFlagSet()
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config := Default()
+	var problems []error
+
+	path := *configFile
+	if path == "" {
+		path = getenv("CONFIG_FILE")
+	}
+	config.File = path
+	if path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, config.apply(values, "config file")...)
+	}
+
+	problems = append(problems, config.apply(envValues(getenv), "environment")...)
+
+	set := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if value, ok := flagValues[f.Name]; ok {
+			set[f.Name] = *value
+		}
+	})
+	problems = append(problems, config.apply(set, "flag")...)
+
+	config.deriveDefaults()
+
+	if err := config.validate(opts.requireWeatherKey); err != nil {
+		problems = append(problems, err)
 	}
 
-	// Validate required configuration
-	if config.Weather.APIKey == "" {
-		return nil, fmt.Errorf("WEATHERAPI_KEY is required")
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(problems...))
 	}
 
 	return config, nil
 }
 
-// getEnv gets environment variable with fallback
-func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// startupEnv is the process environment as it was before .env was loaded
+// at startup
+var startupEnv map[string]string
+
+// loadEnv loads the .env file and returns a lookup for environment
+// variables. At startup .env fills in variables missing from the process
+// environment. godotenv.Load never overrides a variable that is already set,
+// so by the time of a reload every .env value looks like part of the
+// environment; a reload therefore reads .env again and lays the environment
+// captured at startup over it, so edits to .env take effect.
+func loadEnv(reload bool) (func(string) string, error) {
+	if !reload {
+		startupEnv = environ()
+		return os.Getenv, godotenv.Load(".env")
+	}
+
+	values, err := godotenv.Read(".env")
+	if values == nil {
+		values = make(map[string]string, len(startupEnv))
+	}
+	for key, value := range startupEnv {
+		values[key] = value
+	}
+	return func(key string) string { return values[key] }, err
+}
+
+// environ returns the process environment as a map
+func environ() map[string]string {
+	env := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			env[key] = value
+		}
 	}
-	return fallback
+	return env
+}
+
+// deriveDefaults fills settings whose defaults depend on other settings
+func (c *Config) deriveDefaults() {
+	base := strings.TrimSuffix(c.Weather.BaseURL, "/")
+	if c.Weather.SearchURL == "" {
+		c.Weather.SearchURL = base + "/search.json"
+	}
+	if c.Weather.CurrentURL == "" {
+		c.Weather.CurrentURL = base + "/current.json"
+	}
+	if c.Weather.ForecastURL == "" {
+		c.Weather.ForecastURL = base + "/forecast.json"
+	}
+}
+
+// Secrets returns the values of every secret setting, for redaction
+func (c *Config) Secrets() []string {
+	var secrets []string
+	for _, s := range settings {
+		if !s.secret {
+			continue
+		}
+		for _, value := range strings.Split(s.get(c), ",") {
+			if value != "" {
+				secrets = append(secrets, value)
+			}
+		}
+	}
+	return secrets
 }
 
 // GetServerAddress returns the full server address
 func (c *Config) GetServerAddress() string {
 	return fmt.Sprintf("%s:%s", c.Server.Host, c.Server.Port)
 }
+
+// GetGRPCAddress returns the gRPC server address, on the same host as the
+// HTTP server
+func (c *Config) GetGRPCAddress() string {
+	return fmt.Sprintf("%s:%s", c.Server.Host, c.GRPC.Port)
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"bytes"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -51,6 +55,48 @@ func TestLoad(t *testing.T) {
 				assert.Equal(t, "8080", cfg.Server.Port)
 				assert.Equal(t, "localhost", cfg.Server.Host)
 				assert.Equal(t, "./weather.db", cfg.Database.Path)
+				assert.Equal(t, 15*time.Second, cfg.Server.ShutdownTimeout)
+				assert.True(t, cfg.RateLimit.Enabled)
+				assert.Equal(t, 10.0, cfg.RateLimit.API.Rate)
+				assert.Equal(t, 20, cfg.RateLimit.API.Burst)
+			},
+		},
+		{
+			name: "rate limit overrides",
+			envVars: map[string]string{
+				"WEATHERAPI_KEY":      "test-api-key",
+				"RATE_LIMIT_ENABLED":  "false",
+				"RATE_LIMIT_API_RATE": "2.5",
+			},
+			expectError: false,
+			checkConfig: func(t *testing.T, cfg *Config) {
+				assert.False(t, cfg.RateLimit.Enabled)
+				assert.Equal(t, 2.5, cfg.RateLimit.API.Rate)
+				assert.Equal(t, 5, cfg.RateLimit.Batch.Burst)
+			},
+		},
+		{
+			name: "unparseable values are reported",
+			envVars: map[string]string{
+				"WEATHERAPI_KEY":         "test-api-key",
+				"RATE_LIMIT_BATCH_BURST": "not-a-number",
+			},
+			expectError: true,
+		},
+		{
+			name: "provider URLs derive from base URL",
+			envVars: map[string]string{
+				"WEATHERAPI_KEY":      "test-api-key",
+				"WEATHERAPI_BASE_URL": "https://weather.internal/v1/",
+				"WEATHERAPI_TIMEOUT":  "3s",
+				"CORS_ORIGINS":        "https://a.example.com, https://b.example.com",
+			},
+			expectError: false,
+			checkConfig: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "https://weather.internal/v1/search.json", cfg.Weather.SearchURL)
+				assert.Equal(t, "https://weather.internal/v1/current.json", cfg.Weather.CurrentURL)
+				assert.Equal(t, 3*time.Second, cfg.Weather.Timeout)
+				assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.Server.CORSOrigins)
 			},
 		},
 	}
@@ -85,6 +131,193 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestLoadLocal(t *testing.T) {
+	t.Setenv("DB_PATH", "/tmp/local.db")
+
+	cfg, err := LoadLocal()
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/local.db", cfg.Database.Path)
+	assert.Empty(t, cfg.Weather.Keys())
+
+	// Everything else is still validated
+	t.Setenv("HISTORY_LIMIT", "0")
+	_, err = LoadLocal()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database.history_limit")
+	assert.NotContains(t, err.Error(), "WEATHERAPI_KEY")
+}
+
+func TestLoadLocal_QuietEnvFile(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+	t.Setenv("WEATHERAPI_KEY", "test-api-key")
+
+	// CLI commands only log the missing .env file at debug level
+	_, err := LoadLocal()
+	require.NoError(t, err)
+	assert.Empty(t, logs.String())
+
+	_, err = Load()
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), ".env file not found")
+}
+
+func TestParse_Layers(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte(`
+server:
+  port: 7070
+  cors_origins:
+    - https://app.example.com
+database:
+  history_limit: 10
+weather:
+  api_key: file-key
+  timeout: 5s
+quota:
+  daily_limit: 500
+`), 0o600))
+
+	tomlPath := filepath.Join(dir, "config.toml")
+	require.NoError(t, os.WriteFile(tomlPath, []byte(`
+[server]
+port = "6060"
+
+[weather]
+api_key = "toml-key"
+
+[rate_limit.api]
+burst = 40
+`), 0o600))
+
+	t.Run("yaml file", func(t *testing.T) {
+		cfg, err := Parse([]string{"-config", yamlPath})
+		require.NoError(t, err)
+		assert.Equal(t, "7070", cfg.Server.Port)
+		assert.Equal(t, []string{"https://app.example.com"}, cfg.Server.CORSOrigins)
+		assert.Equal(t, 10, cfg.Database.HistoryLimit)
+		assert.Equal(t, "file-key", cfg.Weather.APIKey)
+		assert.Equal(t, 5*time.Second, cfg.Weather.Timeout)
+		assert.Equal(t, 500, cfg.Quota.DailyLimit)
+	})
+
+	t.Run("toml file from environment", func(t *testing.T) {
+		os.Setenv("CONFIG_FILE", tomlPath)
+		defer os.Unsetenv("CONFIG_FILE")
+
+		cfg, err := Parse(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "6060", cfg.Server.Port)
+		assert.Equal(t, "toml-key", cfg.Weather.APIKey)
+		assert.Equal(t, 40, cfg.RateLimit.API.Burst)
+	})
+
+	t.Run("env overrides file and flags override env", func(t *testing.T) {
+		os.Setenv("PORT", "9090")
+		os.Setenv("WEATHERAPI_KEY", "env-key")
+		defer os.Unsetenv("PORT")
+		defer os.Unsetenv("WEATHERAPI_KEY")
+
+		cfg, err := Parse([]string{"-config", yamlPath, "-server.port", "5050"})
+		require.NoError(t, err)
+		assert.Equal(t, "5050", cfg.Server.Port)
+		assert.Equal(t, "env-key", cfg.Weather.APIKey)
+		assert.Equal(t, 10, cfg.Database.HistoryLimit)
+	})
+
+	t.Run("all problems reported at once", func(t *testing.T) {
+		badPath := filepath.Join(dir, "bad.yaml")
+		require.NoError(t, os.WriteFile(badPath, []byte(`
+server:
+  port: not-a-port
+  colour: blue
+weather:
+  timeout: soon
+`), 0o600))
+
+		_, err := Parse([]string{"-config", badPath, "-quota.mode", "panic"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown setting server.colour")
+		assert.Contains(t, err.Error(), `weather.timeout: invalid duration "soon"`)
+		assert.Contains(t, err.Error(), "server.port must be between 1 and 65535")
+		assert.Contains(t, err.Error(), "quota.mode must be")
+		assert.Contains(t, err.Error(), "WEATHERAPI_KEY is required")
+	})
+
+	t.Run("unsupported file format", func(t *testing.T) {
+		_, err := Parse([]string{"-config", filepath.Join(dir, "config.ini")})
+		assert.Error(t, err)
+	})
+}
+
+func TestParse_Secrets(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "weatherapi_key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("file-key\n"), 0o600))
+	keysFile := filepath.Join(dir, "weatherapi_keys")
+	require.NoError(t, os.WriteFile(keysFile, []byte("second-key\nthird-key\n"), 0o600))
+
+	t.Run("environment file", func(t *testing.T) {
+		os.Setenv("WEATHERAPI_KEY_FILE", keyFile)
+		os.Setenv("WEATHERAPI_KEYS_FILE", keysFile)
+		defer os.Unsetenv("WEATHERAPI_KEY_FILE")
+		defer os.Unsetenv("WEATHERAPI_KEYS_FILE")
+
+		cfg, err := Parse(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "file-key", cfg.Weather.APIKey)
+		assert.Equal(t, []string{"file-key", "second-key", "third-key"}, cfg.Weather.Keys())
+	})
+
+	t.Run("config file and flag", func(t *testing.T) {
+		configPath := filepath.Join(dir, "config.yaml")
+		require.NoError(t, os.WriteFile(configPath, []byte("weather:\n  api_key_file: "+keyFile+"\n"), 0o600))
+
+		cfg, err := Parse([]string{"-config", configPath, "-admin.token_file", keyFile})
+		require.NoError(t, err)
+		assert.Equal(t, "file-key", cfg.Weather.APIKey)
+		assert.Equal(t, "file-key", cfg.Admin.Token)
+	})
+
+	t.Run("comma separated keys", func(t *testing.T) {
+		os.Setenv("WEATHERAPI_KEYS", "a-key, b-key")
+		defer os.Unsetenv("WEATHERAPI_KEYS")
+
+		cfg, err := Parse(nil)
+		require.NoError(t, err)
+		assert.Empty(t, cfg.Weather.APIKey)
+		assert.Equal(t, []string{"a-key", "b-key"}, cfg.Weather.Keys())
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		os.Setenv("WEATHERAPI_KEY_FILE", filepath.Join(dir, "missing"))
+		defer os.Unsetenv("WEATHERAPI_KEY_FILE")
+
+		_, err := Parse(nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "WEATHERAPI_KEY_FILE: failed to read secret file")
+	})
+
+	t.Run("file variant only for secrets", func(t *testing.T) {
+		_, err := Parse([]string{"-weather.api_key", "k", "-log.level_file", keyFile})
+		assert.Error(t, err)
+	})
+}
+
+func TestConfig_Secrets(t *testing.T) {
+	cfg := Default()
+	cfg.Weather.APIKey = "primary"
+	cfg.Weather.APIKeys = []string{"primary", "backup"}
+	cfg.Admin.Token = "admin-token"
+
+	assert.Equal(t, []string{"primary", "backup"}, cfg.Weather.Keys())
+	assert.ElementsMatch(t, []string{"primary", "primary", "backup", "admin-token"}, cfg.Secrets())
+}
+
 func TestGetServerAddress(t *testing.T) {
 	cfg := &Config{
 		Server: ServerConfig{
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"weather-dashboard/models"
+)
+
+// reloadable lists the settings that can change without a restart. A reload
+// changing any other setting is rejected as a whole. There are no alert rules
+// to reload: the server only exports metrics, and alerting on them is left to
+// Prometheus, whose rules are reloaded there.
+var reloadable = map[string]bool{
+	"weather.api_key":          true,
+	"weather.api_keys":         true,
+	"weather.cache_ttl":        true,
+	"weather.max_staleness":    true,
+	"weather.max_retries":      true,
+	"weather.retry_base_delay": true,
+	"weather.retry_max_delay":  true,
+	"rate_limit.general.rate":  true,
+	"rate_limit.general.burst": true,
+	"rate_limit.api.rate":      true,
+	"rate_limit.api.burst":     true,
+	"rate_limit.batch.rate":    true,
+	"rate_limit.batch.burst":   true,
+	"rate_limit.api_keys":      true,
+	"log.level":                true,
+}
+
+// Reloader re-reads the configuration using the original command-line
+// arguments and applies reloadable settings to registered listeners
+type Reloader struct {
+	args []string
+
+	mu          sync.Mutex
+	current     *Config
+	version     int
+	loadedAt    time.Time
+	lastAttempt time.Time
+	lastErr     error
+	listeners   []func(*Config)
+	now         func() time.Time
+}
+
+// NewReloader creates a reloader whose first version is cfg
+func NewReloader(cfg *Config, args []string) *Reloader {
+	return &Reloader{
+		args:     args,
+		current:  cfg,
+		version:  1,
+		loadedAt: time.Now(),
+		now:      time.Now,
+	}
+}
+
+// OnReload registers fn to be called with the new configuration after each
+// successful reload
+func (r *Reloader) OnReload(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+// Current returns the active configuration
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Reload loads the configuration again and, if it is valid and only
+// reloadable settings changed, makes it the active version. It reports
+// whether anything changed.
+func (r *Reloader) Reload() (bool, error) {
+	next, err := parse(r.args, parseOptions{requireWeatherKey: true, reload: true})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastAttempt = r.now()
+	r.lastErr = err
+	if err != nil {
+		return false, err
+	}
+
+	changed := changedSettings(r.current, next)
+	var restart []string
+	for _, key := range changed {
+		if !reloadable[key] {
+			restart = append(restart, key)
+		}
+	}
+	if len(restart) > 0 {
+		r.lastErr = fmt.Errorf("settings require a restart: %s", strings.Join(restart, ", "))
+		return false, r.lastErr
+	}
+	if len(changed) == 0 {
+		return false, nil
+	}
+
+	for _, fn := range r.listeners {
+		fn(next)
+	}
+	r.current = next
+	r.version++
+	r.loadedAt = r.lastAttempt
+
+	return true, nil
+}
+
+// Version reports the active configuration version and the outcome of the
+// last reload attempt
+func (r *Reloader) Version() models.ConfigVersion {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version := models.ConfigVersion{
+		Version:  r.version,
+		Checksum: checksum(r.current),
+		Source:   r.current.File,
+		LoadedAt: r.loadedAt,
+	}
+	if !r.lastAttempt.IsZero() {
+		lastAttempt := r.lastAttempt
+		version.LastReloadAt = &lastAttempt
+	}
+	if r.lastErr != nil {
+		version.LastReloadError = r.lastErr.Error()
+	}
+
+	return version
+}
+
+// Watch reloads the configuration whenever a signal arrives on signals or,
+// if an interval is configured, the config file's modification time changes.
+// It blocks until ctx is cancelled.
+func (r *Reloader) Watch(ctx context.Context, signals <-chan os.Signal) {
+	cfg := r.Current()
+
+	var tick <-chan time.Time
+	if cfg.File != "" && cfg.Reload.WatchInterval > 0 {
+		ticker := time.NewTicker(cfg.Reload.WatchInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	modTime := fileModTime(cfg.File)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signals:
+			slog.Info("reloading configuration", "signal", sig.String())
+		case <-tick:
+			current := fileModTime(cfg.File)
+			if current.Equal(modTime) {
+				continue
+			}
+			modTime = current
+			slog.Info("reloading configuration", "file", cfg.File)
+		}
+
+		changed, err := r.Reload()
+		switch {
+		case err != nil:
+			slog.Error("configuration reload rejected", "error", err)
+		case changed:
+			slog.Info("configuration reloaded", "version", r.Version().Version)
+		default:
+			slog.Info("configuration unchanged")
+		}
+	}
+}
+
+// changedSettings returns the keys of settings that differ between a and b
+func changedSettings(a, b *Config) []string {
+	var changed []string
+	for _, s := range settings {
+		if s.get(a) != s.get(b) {
+			changed = append(changed, s.key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// checksum fingerprints the effective settings so operators can tell
+// whether two instances run the same configuration. Secrets count only by
+// whether they are set, so the fingerprint reveals nothing about their values.
+func checksum(c *Config) string {
+	hash := sha256.New()
+	for _, s := range settings {
+		value := s.get(c)
+		if s.secret {
+			value = strconv.FormatBool(value != "")
+		}
+		fmt.Fprintf(hash, "%s=%s\n", s.key, value)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// fileModTime returns the modification time of path, or the zero time if
+// it cannot be read
+func fileModTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestReloader_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, `
+server:
+  port: 8080
+weather:
+  api_key: first-key
+log:
+  level: info
+`)
+
+	args := []string{"-config", path}
+	cfg, err := Parse(args)
+	require.NoError(t, err)
+
+	reloader := NewReloader(cfg, args)
+	var applied []*Config
+	reloader.OnReload(func(next *Config) { applied = append(applied, next) })
+
+	initial := reloader.Version()
+	assert.Equal(t, 1, initial.Version)
+	assert.Equal(t, path, initial.Source)
+	assert.Len(t, initial.Checksum, 12)
+	assert.Nil(t, initial.LastReloadAt)
+
+	t.Run("unchanged", func(t *testing.T) {
+		changed, err := reloader.Reload()
+		require.NoError(t, err)
+		assert.False(t, changed)
+		assert.Empty(t, applied)
+		assert.Equal(t, 1, reloader.Version().Version)
+	})
+
+	t.Run("reloadable settings applied", func(t *testing.T) {
+		writeConfig(t, path, `
+server:
+  port: 8080
+weather:
+  api_key: second-key
+  cache_ttl: 5m
+rate_limit:
+  api:
+    burst: 99
+log:
+  level: debug
+`)
+		changed, err := reloader.Reload()
+		require.NoError(t, err)
+		assert.True(t, changed)
+		require.Len(t, applied, 1)
+		assert.Equal(t, "second-key", applied[0].Weather.APIKey)
+		assert.Equal(t, 5*time.Minute, applied[0].Weather.CacheTTL)
+		assert.Equal(t, 99, applied[0].RateLimit.API.Burst)
+		assert.Equal(t, "debug", applied[0].Log.Level)
+		assert.Same(t, applied[0], reloader.Current())
+
+		version := reloader.Version()
+		assert.Equal(t, 2, version.Version)
+		assert.NotEqual(t, initial.Checksum, version.Checksum)
+		assert.NotNil(t, version.LastReloadAt)
+		assert.Empty(t, version.LastReloadError)
+	})
+
+	t.Run("restart required", func(t *testing.T) {
+		writeConfig(t, path, `
+server:
+  port: 9090
+weather:
+  api_key: third-key
+`)
+		changed, err := reloader.Reload()
+		require.Error(t, err)
+		assert.False(t, changed)
+		assert.Contains(t, err.Error(), "server.port")
+		assert.Len(t, applied, 1)
+		assert.Equal(t, "second-key", reloader.Current().Weather.APIKey)
+
+		version := reloader.Version()
+		assert.Equal(t, 2, version.Version)
+		assert.Contains(t, version.LastReloadError, "settings require a restart: server.port")
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		writeConfig(t, path, `
+weather:
+  api_key: second-key
+  cache_ttl: soon
+`)
+		_, err := reloader.Reload()
+		require.Error(t, err)
+		assert.Equal(t, 2, reloader.Version().Version)
+		assert.Contains(t, reloader.Version().LastReloadError, "weather.cache_ttl")
+	})
+}
+
+func TestReloader_ReloadEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	// Start without the variables .env provides; loading it sets them
+	t.Setenv("WEATHERAPI_KEY", "")
+	os.Unsetenv("WEATHERAPI_KEY")
+	t.Setenv("LOG_LEVEL", "warn")
+
+	envPath := filepath.Join(dir, ".env")
+	writeConfig(t, envPath, "WEATHERAPI_KEY=first-key\nLOG_LEVEL=info\n")
+	cfg, err := Parse(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "first-key", cfg.Weather.APIKey)
+	assert.Equal(t, "warn", cfg.Log.Level, "the environment overrides .env")
+
+	reloader := NewReloader(cfg, nil)
+	writeConfig(t, envPath, "WEATHERAPI_KEY=second-key\nLOG_LEVEL=debug\n")
+
+	changed, err := reloader.Reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "second-key", reloader.Current().Weather.APIKey)
+	assert.Equal(t, "warn", reloader.Current().Log.Level, "the environment overrides .env")
+}
+
+func TestReloader_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeConfig(t, path, `
+[weather]
+api_key = "first-key"
+`)
+
+	args := []string{"-config", path}
+	cfg, err := Parse(args)
+	require.NoError(t, err)
+
+	reloader := NewReloader(cfg, args)
+	reloaded := make(chan *Config, 1)
+	reloader.OnReload(func(next *Config) { reloaded <- next })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	go func() {
+		reloader.Watch(ctx, signals)
+		close(done)
+	}()
+
+	writeConfig(t, path, `
+[weather]
+api_key = "second-key"
+`)
+	signals <- syscall.SIGHUP
+
+	select {
+	case next := <-reloaded:
+		assert.Equal(t, "second-key", next.Weather.APIKey)
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration was not reloaded")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestChecksum_IgnoresSecretValues(t *testing.T) {
+	cfg := Default()
+	cfg.Weather.APIKey = "first-key"
+	cfg.Admin.Token = "first-token"
+	initial := checksum(cfg)
+
+	cfg.Weather.APIKey = "second-key"
+	cfg.Admin.Token = "second-token"
+	assert.Equal(t, initial, checksum(cfg), "changing a secret value changed the checksum")
+
+	cfg.Admin.Token = ""
+	assert.NotEqual(t, initial, checksum(cfg), "removing a secret kept the checksum")
+
+	cfg.Admin.Token = "second-token"
+	cfg.Log.Level = "debug"
+	assert.NotEqual(t, initial, checksum(cfg))
+}
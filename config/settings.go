@@ -0,0 +1,339 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// setting describes a single tunable. The key is used in config files
+// (as nested sections) and as the command-line flag name. Secret settings
+// can also be read from a file named by <key>_file or <ENV>_FILE.
+type setting struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	set    func(c *Config, value string) error
+	get    func(c *Config) string
+}
+
+// fileSuffix marks a key whose value is the path of a file holding a secret
+const fileSuffix = "_file"
+
+// settings lists every configurable value
+var settings = []setting{
+	stringSetting("server.host", "HOST", "address to bind the HTTP server to", func(c *Config) *string { return &c.Server.Host }),
+	stringSetting("server.port", "PORT", "port to bind the HTTP server to", func(c *Config) *string { return &c.Server.Port }),
+	durationSetting("server.shutdown_timeout", "SHUTDOWN_TIMEOUT", "time allowed for in-flight requests on shutdown", func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout }),
+	durationSetting("server.read_header_timeout", "READ_HEADER_TIMEOUT", "time allowed to read request headers", func(c *Config) *time.Duration { return &c.Server.ReadHeaderTimeout }),
+	listSetting("server.cors_origins", "CORS_ORIGINS", "comma-separated origins allowed by CORS (* for any)", func(c *Config) *[]string { return &c.Server.CORSOrigins }),
+	listSetting("server.trusted_proxies", "TRUSTED_PROXIES", "comma-separated proxy IPs or CIDRs whose X-Forwarded-For is trusted (empty = none)", func(c *Config) *[]string { return &c.Server.TrustedProxies }),
+
+	stringSetting("database.path", "DB_PATH", "SQLite database path", func(c *Config) *string { return &c.Database.Path }),
+	intSetting("database.history_limit", "HISTORY_LIMIT", "number of entries returned by the history endpoint", func(c *Config) *int { return &c.Database.HistoryLimit }),
+
+	intSetting("retention.raw_days", "RETENTION_RAW_DAYS", "days raw observations are kept before being rolled up into aggregates (0 = forever)", func(c *Config) *int { return &c.Retention.RawDays }),
+	intSetting("retention.hourly_days", "RETENTION_HOURLY_DAYS", "days hourly aggregates are kept (0 = forever)", func(c *Config) *int { return &c.Retention.HourlyDays }),
+	durationSetting("retention.interval", "RETENTION_INTERVAL", "how often the retention job runs (0 = never)", func(c *Config) *time.Duration { return &c.Retention.Interval }),
+	durationSetting("retention.vacuum_interval", "RETENTION_VACUUM_INTERVAL", "minimum time between VACUUMs of the database (0 = never)", func(c *Config) *time.Duration { return &c.Retention.VacuumInterval }),
+
+	secret(stringSetting("weather.api_key", "WEATHERAPI_KEY", "WeatherAPI.com API key", func(c *Config) *string { return &c.Weather.APIKey })),
+	secret(listSetting("weather.api_keys", "WEATHERAPI_KEYS", "additional WeatherAPI.com API keys used in rotation", func(c *Config) *[]string { return &c.Weather.APIKeys })),
+	stringSetting("weather.base_url", "WEATHERAPI_BASE_URL", "WeatherAPI base URL", func(c *Config) *string { return &c.Weather.BaseURL }),
+	stringSetting("weather.search_url", "WEATHERAPI_SEARCH_URL", "WeatherAPI search URL (default: base URL + /search.json)", func(c *Config) *string { return &c.Weather.SearchURL }),
+	stringSetting("weather.current_url", "WEATHERAPI_CURRENT_URL", "WeatherAPI current weather URL (default: base URL + /current.json)", func(c *Config) *string { return &c.Weather.CurrentURL }),
+	stringSetting("weather.forecast_url", "WEATHERAPI_FORECAST_URL", "WeatherAPI forecast URL (default: base URL + /forecast.json)", func(c *Config) *string { return &c.Weather.ForecastURL }),
+	durationSetting("weather.timeout", "WEATHERAPI_TIMEOUT", "timeout for upstream requests", func(c *Config) *time.Duration { return &c.Weather.Timeout }),
+	durationSetting("weather.cache_ttl", "CACHE_TTL", "maximum age of stored readings served from cache (0 = never serve stored readings)", func(c *Config) *time.Duration { return &c.Weather.CacheTTL }),
+	durationSetting("weather.max_staleness", "MAX_STALENESS", "maximum age of a stored reading served when the upstream fails (0 = disabled)", func(c *Config) *time.Duration { return &c.Weather.MaxStaleness }),
+	intSetting("weather.max_retries", "WEATHERAPI_MAX_RETRIES", "retries of transient upstream failures (5xx, 429, timeouts)", func(c *Config) *int { return &c.Weather.MaxRetries }),
+	durationSetting("weather.retry_base_delay", "WEATHERAPI_RETRY_BASE_DELAY", "backoff before the first retry, doubled for each further retry", func(c *Config) *time.Duration { return &c.Weather.RetryBaseDelay }),
+	durationSetting("weather.retry_max_delay", "WEATHERAPI_RETRY_MAX_DELAY", "longest wait between retries, including Retry-After", func(c *Config) *time.Duration { return &c.Weather.RetryMaxDelay }),
+	intSetting("weather.breaker_threshold", "WEATHERAPI_BREAKER_THRESHOLD", "consecutive upstream failures that open the circuit breaker (0 = disabled)", func(c *Config) *int { return &c.Weather.BreakerThreshold }),
+	durationSetting("weather.breaker_cooldown", "WEATHERAPI_BREAKER_COOLDOWN", "how long the circuit breaker stays open before a probe call", func(c *Config) *time.Duration { return &c.Weather.BreakerCooldown }),
+
+	boolSetting("rate_limit.enabled", "RATE_LIMIT_ENABLED", "enable in-process rate limiting", func(c *Config) *bool { return &c.RateLimit.Enabled }),
+	floatSetting("rate_limit.general.rate", "RATE_LIMIT_GENERAL_RATE", "requests per second for pages and static files", func(c *Config) *float64 { return &c.RateLimit.General.Rate }),
+	intSetting("rate_limit.general.burst", "RATE_LIMIT_GENERAL_BURST", "burst size for pages and static files", func(c *Config) *int { return &c.RateLimit.General.Burst }),
+	floatSetting("rate_limit.api.rate", "RATE_LIMIT_API_RATE", "requests per second for API routes", func(c *Config) *float64 { return &c.RateLimit.API.Rate }),
+	intSetting("rate_limit.api.burst", "RATE_LIMIT_API_BURST", "burst size for API routes", func(c *Config) *int { return &c.RateLimit.API.Burst }),
+	floatSetting("rate_limit.batch.rate", "RATE_LIMIT_BATCH_RATE", "requests per second for batch lookups", func(c *Config) *float64 { return &c.RateLimit.Batch.Rate }),
+	intSetting("rate_limit.batch.burst", "RATE_LIMIT_BATCH_BURST", "burst size for batch lookups", func(c *Config) *int { return &c.RateLimit.Batch.Burst }),
+	secret(listSetting("rate_limit.api_keys", "RATE_LIMIT_API_KEYS", "client API keys rate limited per key instead of per IP", func(c *Config) *[]string { return &c.RateLimit.APIKeys })),
+
+	intSetting("quota.daily_limit", "QUOTA_DAILY_LIMIT", "upstream calls allowed per UTC day (0 = unlimited)", func(c *Config) *int { return &c.Quota.DailyLimit }),
+	intSetting("quota.monthly_limit", "QUOTA_MONTHLY_LIMIT", "upstream calls allowed per UTC month (0 = unlimited)", func(c *Config) *int { return &c.Quota.MonthlyLimit }),
+	stringSetting("quota.mode", "QUOTA_MODE", "behaviour once the quota is exhausted (refuse or cache)", func(c *Config) *string { return &c.Quota.Mode }),
+
+	secret(stringSetting("admin.token", "ADMIN_TOKEN", "bearer token for admin endpoints", func(c *Config) *string { return &c.Admin.Token })),
+	boolSetting("health.check_upstream", "HEALTH_CHECK_UPSTREAM", "include upstream reachability in readiness", func(c *Config) *bool { return &c.Health.CheckUpstream }),
+	intSetting("graphql.max_depth", "GRAPHQL_MAX_DEPTH", "deepest field nesting allowed in a GraphQL query", func(c *Config) *int { return &c.GraphQL.MaxDepth }),
+	intSetting("graphql.max_complexity", "GRAPHQL_MAX_COMPLEXITY", "highest cost allowed for a GraphQL query (upstream lookups cost more than plain fields)", func(c *Config) *int { return &c.GraphQL.MaxComplexity }),
+	stringSetting("grpc.port", "GRPC_PORT", "port to bind the gRPC server to, such as 9090 (empty = disabled)", func(c *Config) *string { return &c.GRPC.Port }),
+	durationSetting("grpc.stream_interval", "GRPC_STREAM_INTERVAL", "shortest time between polls of a StreamUpdates call", func(c *Config) *time.Duration { return &c.GRPC.StreamInterval }),
+	intSetting("grpc.max_streams", "GRPC_MAX_STREAMS", "most StreamUpdates calls open at once", func(c *Config) *int { return &c.GRPC.MaxStreams }),
+	intSetting("grpc.max_stream_cities", "GRPC_MAX_STREAM_CITIES", "most cities a single StreamUpdates call can watch", func(c *Config) *int { return &c.GRPC.MaxStreamCities }),
+	stringSetting("log.level", "LOG_LEVEL", "log level (debug, info, warn, error)", func(c *Config) *string { return &c.Log.Level }),
+
+	stringSetting("tracing.exporter", "TRACING_EXPORTER", "trace exporter (none, stdout, otlp)", func(c *Config) *string { return &c.Tracing.Exporter }),
+	stringSetting("tracing.endpoint", "TRACING_ENDPOINT", "OTLP/HTTP collector host:port", func(c *Config) *string { return &c.Tracing.Endpoint }),
+	boolSetting("tracing.insecure", "TRACING_INSECURE", "send OTLP traces over plain HTTP", func(c *Config) *bool { return &c.Tracing.Insecure }),
+	floatSetting("tracing.sample_ratio", "TRACING_SAMPLE_RATIO", "fraction of new traces to sample", func(c *Config) *float64 { return &c.Tracing.SampleRatio }),
+
+	durationSetting("reload.watch_interval", "CONFIG_WATCH_INTERVAL", "how often to check the config file for changes (0 = only on SIGHUP)", func(c *Config) *time.Duration { return &c.Reload.WatchInterval }),
+}
+
+// secret marks a setting as sensitive: it can be read from a file and its
+// value is redacted from logs and client errors
+func secret(s setting) setting {
+	s.secret = true
+	return s
+}
+
+func stringSetting(key, env, usage string, field func(*Config) *string) setting {
+	return setting{key: key, env: env, usage: usage, set: func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}, get: func(c *Config) string {
+		return *field(c)
+	}}
+}
+
+func intSetting(key, env, usage string, field func(*Config) *int) setting {
+	return setting{key: key, env: env, usage: usage, set: func(c *Config, value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}, get: func(c *Config) string {
+		return strconv.Itoa(*field(c))
+	}}
+}
+
+func floatSetting(key, env, usage string, field func(*Config) *float64) setting {
+	return setting{key: key, env: env, usage: usage, set: func(c *Config, value string) error {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}, get: func(c *Config) string {
+		return strconv.FormatFloat(*field(c), 'g', -1, 64)
+	}}
+}
+
+func boolSetting(key, env, usage string, field func(*Config) *bool) setting {
+	return setting{key: key, env: env, usage: usage, set: func(c *Config, value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}, get: func(c *Config) string {
+		return strconv.FormatBool(*field(c))
+	}}
+}
+
+func durationSetting(key, env, usage string, field func(*Config) *time.Duration) setting {
+	return setting{key: key, env: env, usage: usage, set: func(c *Config, value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}, get: func(c *Config) string {
+		return field(c).String()
+	}}
+}
+
+func listSetting(key, env, usage string, field func(*Config) *[]string) setting {
+	return setting{key: key, env: env, usage: usage, set: func(c *Config, value string) error {
+		var items []string
+		for _, item := range strings.FieldsFunc(value, isListSeparator) {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*field(c) = items
+		return nil
+	}, get: func(c *Config) string {
+		return strings.Join(*field(c), ",")
+	}}
+}
+
+// isListSeparator splits list values on commas and newlines
+func isListSeparator(r rune) bool {
+	return r == ',' || r == '\n'
+}
+
+// apply sets every value keyed by setting key, returning a problem for each
+// unknown key or unparseable value
+func (c *Config) apply(values map[string]string, source string) []error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []error
+	for _, key := range keys {
+		value := values[key]
+		s, ok := lookupSetting(key)
+		name := s.describe(source)
+		if !ok {
+			s, ok = lookupSetting(strings.TrimSuffix(key, fileSuffix))
+			if !ok || !s.secret || !strings.HasSuffix(key, fileSuffix) {
+				problems = append(problems, fmt.Errorf("%s: unknown setting %s", source, key))
+				continue
+			}
+
+			name = s.describeFile(source)
+			secret, err := readSecretFile(value)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("%s: %s: %w", source, name, err))
+				continue
+			}
+			value = secret
+		}
+		if err := s.set(c, value); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %s: %w", source, name, err))
+		}
+	}
+
+	return problems
+}
+
+// describe names the setting the way it was specified in source
+func (s setting) describe(source string) string {
+	switch source {
+	case "environment":
+		return s.env
+	case "flag":
+		return "-" + s.key
+	default:
+		return s.key
+	}
+}
+
+// describeFile names the setting's file variant the way it was specified
+// in source
+func (s setting) describeFile(source string) string {
+	if source == "environment" {
+		return s.env + "_FILE"
+	}
+	return s.describe(source) + fileSuffix
+}
+
+// readSecretFile reads a secret from path, ignoring surrounding whitespace
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// lookupSetting finds a setting by key
+func lookupSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// envValues returns the settings present in the environment, keyed by setting key
+func envValues(getenv func(string) string) map[string]string {
+	values := make(map[string]string)
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			values[s.key] = value
+		}
+		if path := getenv(s.env + "_FILE"); s.secret && path != "" {
+			values[s.key+fileSuffix] = path
+		}
+	}
+	return values
+}
+
+// newFlagSet creates a flag for every setting plus -config. Flags are
+// registered as strings so they are parsed and validated like other layers.
+func newFlagSet() (*flag.FlagSet, *string, map[string]*string) {
+	fs := flag.NewFlagSet("weather-dashboard", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML or TOML config file (env CONFIG_FILE)")
+
+	values := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		values[s.key] = fs.String(s.key, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+		if s.secret {
+			values[s.key+fileSuffix] = fs.String(s.key+fileSuffix, "", fmt.Sprintf("file containing the %s (env %s_FILE)", s.usage, s.env))
+		}
+	}
+
+	return fs, configFile, values
+}
+
+// readConfigFile reads a YAML or TOML file, chosen by extension, into
+// setting keys
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("unsupported config file format: %s (use .yaml, .yml or .toml)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten("", tree, values)
+	return values, nil
+}
+
+// flatten converts nested sections into dotted setting keys. Lists are
+// joined with commas.
+func flatten(prefix string, node interface{}, values map[string]string) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flatten(key, child, values)
+		}
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		values[prefix] = strings.Join(items, ",")
+	case nil:
+		values[prefix] = ""
+	default:
+		values[prefix] = fmt.Sprint(v)
+	}
+}
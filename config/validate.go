@@ -0,0 +1,131 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"weather-dashboard/models"
+)
+
+// Validate checks the configuration and reports every problem found
+func (c *Config) Validate() error {
+	return c.validate(true)
+}
+
+// validate checks the configuration, skipping the WeatherAPI key check for
+// commands that never call the upstream
+func (c *Config) validate(requireWeatherKey bool) error {
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port <= 65535, "server.port must be between 1 and 65535, got %q", c.Server.Port)
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "server.read_header_timeout must be positive")
+	for _, origin := range c.Server.CORSOrigins {
+		check(origin == "*" || isHTTPURL(origin), "server.cors_origins: invalid origin %q", origin)
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		check(isIPOrCIDR(proxy), "server.trusted_proxies: invalid IP or CIDR %q", proxy)
+	}
+
+	check(c.Database.Path != "", "database.path is required")
+	check(c.Database.HistoryLimit > 0, "database.history_limit must be positive")
+
+	check(c.Retention.RawDays >= 0, "retention.raw_days must not be negative")
+	check(c.Retention.HourlyDays >= 0, "retention.hourly_days must not be negative")
+	check(c.Retention.HourlyDays == 0 || c.Retention.RawDays == 0 || c.Retention.HourlyDays >= c.Retention.RawDays, "retention.hourly_days must not be less than retention.raw_days")
+	check(c.Retention.Interval >= 0, "retention.interval must not be negative")
+	check(c.Retention.VacuumInterval >= 0, "retention.vacuum_interval must not be negative")
+
+	check(!requireWeatherKey || len(c.Weather.Keys()) > 0, "WEATHERAPI_KEY is required (or WEATHERAPI_KEYS, or either with a _FILE suffix)")
+	check(isHTTPURL(c.Weather.BaseURL), "weather.base_url must be an http(s) URL, got %q", c.Weather.BaseURL)
+	check(isHTTPURL(c.Weather.SearchURL), "weather.search_url must be an http(s) URL, got %q", c.Weather.SearchURL)
+	check(isHTTPURL(c.Weather.CurrentURL), "weather.current_url must be an http(s) URL, got %q", c.Weather.CurrentURL)
+	check(isHTTPURL(c.Weather.ForecastURL), "weather.forecast_url must be an http(s) URL, got %q", c.Weather.ForecastURL)
+	check(c.Weather.Timeout > 0, "weather.timeout must be positive")
+	check(c.Weather.CacheTTL >= 0, "weather.cache_ttl must not be negative")
+	check(c.Weather.MaxStaleness >= 0, "weather.max_staleness must not be negative")
+	check(c.Weather.MaxRetries >= 0, "weather.max_retries must not be negative")
+	check(c.Weather.RetryBaseDelay >= 0, "weather.retry_base_delay must not be negative")
+	check(c.Weather.RetryMaxDelay >= c.Weather.RetryBaseDelay, "weather.retry_max_delay must not be less than weather.retry_base_delay")
+	check(c.Weather.BreakerThreshold >= 0, "weather.breaker_threshold must not be negative")
+	check(c.Weather.BreakerThreshold == 0 || c.Weather.BreakerCooldown > 0, "weather.breaker_cooldown must be positive")
+
+	if c.RateLimit.Enabled {
+		rules := []struct {
+			name string
+			rule RateLimitRule
+		}{
+			{"general", c.RateLimit.General},
+			{"api", c.RateLimit.API},
+			{"batch", c.RateLimit.Batch},
+		}
+		for _, r := range rules {
+			check(r.rule.Rate > 0, "rate_limit.%s.rate must be positive", r.name)
+			check(r.rule.Burst >= 1, "rate_limit.%s.burst must be at least 1", r.name)
+		}
+	}
+
+	check(c.Quota.DailyLimit >= 0, "quota.daily_limit must not be negative")
+	check(c.Quota.MonthlyLimit >= 0, "quota.monthly_limit must not be negative")
+	check(c.Quota.Mode == QuotaModeRefuse || c.Quota.Mode == QuotaModeCache,
+		"quota.mode must be %q or %q, got %q", QuotaModeRefuse, QuotaModeCache, c.Quota.Mode)
+
+	if c.GRPC.Port != "" {
+		grpcPort, err := strconv.Atoi(c.GRPC.Port)
+		check(err == nil && grpcPort > 0 && grpcPort <= 65535, "grpc.port must be between 1 and 65535, got %q", c.GRPC.Port)
+		check(c.GRPC.Port != c.Server.Port, "grpc.port must differ from server.port")
+	}
+	check(c.GRPC.StreamInterval > 0, "grpc.stream_interval must be positive")
+	check(c.GRPC.MaxStreams >= 1, "grpc.max_streams must be at least 1")
+	check(c.GRPC.MaxStreamCities >= 1 && c.GRPC.MaxStreamCities <= models.MaxBatchSize,
+		"grpc.max_stream_cities must be between 1 and %d", models.MaxBatchSize)
+
+	check(c.GraphQL.MaxDepth > 0, "graphql.max_depth must be positive")
+	check(c.GraphQL.MaxComplexity > 0, "graphql.max_complexity must be positive")
+
+	check(oneOf(strings.ToLower(c.Log.Level), "debug", "info", "warn", "error"),
+		"log.level must be debug, info, warn or error, got %q", c.Log.Level)
+
+	check(oneOf(c.Tracing.Exporter, "none", "stdout", "otlp"),
+		"tracing.exporter must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+
+	check(c.Reload.WatchInterval >= 0, "reload.watch_interval must not be negative")
+
+	return errors.Join(problems...)
+}
+
+// isHTTPURL reports whether raw is an absolute http or https URL
+func isHTTPURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// isIPOrCIDR reports whether raw is an IP address or a CIDR range
+func isIPOrCIDR(raw string) bool {
+	if _, err := netip.ParsePrefix(raw); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(raw)
+	return err == nil
+}
+
+// oneOf reports whether value is one of the allowed values
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		cfg := Default()
+		cfg.Weather.APIKey = "test-key"
+		cfg.deriveDefaults()
+		return cfg
+	}
+
+	tests := []struct {
+		name     string
+		mutate   func(*Config)
+		problems []string
+	}{
+		{
+			name:   "defaults with API key are valid",
+			mutate: func(c *Config) {},
+		},
+		{
+			name: "multiple problems",
+			mutate: func(c *Config) {
+				c.Server.Port = "70000"
+				c.Weather.BaseURL = "ftp://example.com"
+				c.RateLimit.API.Burst = 0
+				c.Tracing.SampleRatio = 2
+			},
+			problems: []string{
+				"server.port must be between 1 and 65535",
+				"weather.base_url must be an http(s) URL",
+				"rate_limit.api.burst must be at least 1",
+				"tracing.sample_ratio must be between 0 and 1",
+			},
+		},
+		{
+			name: "rate limits ignored when disabled",
+			mutate: func(c *Config) {
+				c.RateLimit.Enabled = false
+				c.RateLimit.API.Rate = 0
+			},
+		},
+		{
+			name: "hourly aggregates expire before raw rows",
+			mutate: func(c *Config) {
+				c.Retention.RawDays = 30
+				c.Retention.HourlyDays = 7
+			},
+			problems: []string{"retention.hourly_days must not be less than retention.raw_days"},
+		},
+		{
+			name: "invalid CORS origin",
+			mutate: func(c *Config) {
+				c.Server.CORSOrigins = []string{"*", "app.example.com"}
+			},
+			problems: []string{`server.cors_origins: invalid origin "app.example.com"`},
+		},
+		{
+			name: "trusted proxies",
+			mutate: func(c *Config) {
+				c.Server.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.10", "::1", "proxy.internal"}
+			},
+			problems: []string{`server.trusted_proxies: invalid IP or CIDR "proxy.internal"`},
+		},
+		{
+			name: "GraphQL limits",
+			mutate: func(c *Config) {
+				c.GraphQL.MaxDepth = 0
+				c.GraphQL.MaxComplexity = -1
+			},
+			problems: []string{"graphql.max_depth must be positive", "graphql.max_complexity must be positive"},
+		},
+		{
+			name: "gRPC enabled",
+			mutate: func(c *Config) {
+				c.GRPC.Port = "9090"
+			},
+		},
+		{
+			name: "gRPC port clashes with the HTTP port",
+			mutate: func(c *Config) {
+				c.GRPC.Port = c.Server.Port
+				c.GRPC.StreamInterval = 0
+			},
+			problems: []string{"grpc.port must differ from server.port", "grpc.stream_interval must be positive"},
+		},
+		{
+			name: "gRPC stream limits out of range",
+			mutate: func(c *Config) {
+				c.GRPC.MaxStreams = 0
+				c.GRPC.MaxStreamCities = 26
+			},
+			problems: []string{"grpc.max_streams must be at least 1", "grpc.max_stream_cities must be between 1 and 25"},
+		},
+		{
+			name: "unknown log level and exporter",
+			mutate: func(c *Config) {
+				c.Log.Level = "loud"
+				c.Tracing.Exporter = "zipkin"
+			},
+			problems: []string{"log.level must be", "tracing.exporter must be"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.mutate(cfg)
+
+			err := cfg.Validate()
+			if len(tt.problems) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.Error(t, err)
+			for _, problem := range tt.problems {
+				assert.Contains(t, err.Error(), problem)
+			}
+		})
+	}
+}
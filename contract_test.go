@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"weather-dashboard/config"
+	"weather-dashboard/handlers"
+	"weather-dashboard/middleware"
+	"weather-dashboard/models"
+	"weather-dashboard/services"
+)
+
+const contractAdminToken = "contract-admin-token"
+
+func init() {
+	// Exports and imports are plain text or binary as far as the contract
+	// is concerned
+	for _, contentType := range []string{"text/html", "text/css", "text/javascript", "application/x-ndjson", "application/jsonl", "application/vnd.apache.parquet"} {
+		openapi3filter.RegisterBodyDecoder(contentType, openapi3filter.FileBodyDecoder)
+	}
+}
+
+// loadOpenAPISpec parses and validates the served OpenAPI document
+func loadOpenAPISpec(t *testing.T) *openapi3.T {
+	t.Helper()
+	loader := openapi3.NewLoader()
+	spec, err := loader.LoadFromData(handlers.OpenAPISpec())
+	require.NoError(t, err)
+	require.NoError(t, spec.Validate(loader.Context))
+	return spec
+}
+
+// newContractRouter wires the real handlers and services to a fake WeatherAPI
+// and a temporary database, the same way run does. Each option adjusts the
+// configuration before anything is built.
+func newContractRouter(t *testing.T, options ...func(*config.Config)) *gin.Engine {
+	t.Helper()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/search.json":
+			if strings.HasPrefix(r.URL.Query().Get("q"), "Atlantis") {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`[{"name":"London","region":"City of London, Greater London","country":"United Kingdom","lat":51.52,"lon":-0.11}]`))
+		case "/current.json":
+			w.Write([]byte(`{"location":{"name":"London","region":"City of London, Greater London","country":"United Kingdom","lat":51.52,"lon":-0.11},
+				"current":{"temp_c":15.5,"humidity":65,"condition":{"text":"Partly cloudy","icon":"//cdn.weatherapi.com/weather/64x64/day/116.png","code":1003}}}`))
+		case "/forecast.json":
+			w.Write([]byte(`{"location":{"name":"London","region":"City of London, Greater London","country":"United Kingdom"},
+				"forecast":{"forecastday":[{"date":"2024-03-01","day":{"maxtemp_c":12.5,"mintemp_c":4.1,"avgtemp_c":8.3,"avghumidity":71,"daily_chance_of_rain":80,
+				"condition":{"text":"Light rain","icon":"//cdn.weatherapi.com/weather/64x64/day/296.png","code":1183}}}]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := config.Default()
+	cfg.Database.Path = filepath.Join(t.TempDir(), "contract.db")
+	cfg.Weather.APIKey = "test-key"
+	cfg.Weather.SearchURL = upstream.URL + "/search.json"
+	cfg.Weather.CurrentURL = upstream.URL + "/current.json"
+	cfg.Weather.ForecastURL = upstream.URL + "/forecast.json"
+	cfg.Weather.MaxRetries = 0
+	cfg.Admin.Token = contractAdminToken
+	cfg.RateLimit.Enabled = false
+	for _, option := range options {
+		option(cfg)
+	}
+
+	dbService, err := services.NewDatabaseService(cfg.Database.Path)
+	require.NoError(t, err)
+	t.Cleanup(func() { dbService.Close() })
+
+	quotaTracker := services.NewQuotaTracker(services.ProviderWeatherAPI, cfg.Quota, dbService)
+	weatherService := services.NewWeatherService(&cfg.Weather)
+	weatherService.SetQuota(quotaTracker, dbService)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.LoadHTMLGlob("templates/*")
+	setupRoutes(r,
+		apiHandlers{
+			weather: handlers.NewWeatherHandler(weatherService, dbService),
+			admin:   handlers.NewAdminHandler(quotaTracker, config.NewReloader(cfg, nil)),
+			imports: handlers.NewImportHandler(services.NewImporter(dbService)),
+			graphql: handlers.NewGraphQLHandler(weatherService, dbService, handlers.GraphQLLimits{
+				MaxDepth:      cfg.GraphQL.MaxDepth,
+				MaxComplexity: cfg.GraphQL.MaxComplexity,
+			}),
+		},
+		handlers.NewHealthHandler(map[string]handlers.HealthCheckerInterface{"database": dbService}),
+		middleware.NewRateLimiters(cfg.RateLimit),
+		cfg)
+	return r
+}
+
+// apiDocsPaths are the API docs routes, documented at their permanent
+// unversioned paths and aliased under /api/v1
+var apiDocsPaths = []string{"/openapi.json", "/docs"}
+
+// specPath converts a gin route path to OpenAPI syntax. Unversioned API
+// routes are aliases, documented by their v1 path, except the API docs.
+func specPath(path string) string {
+	for _, docsPath := range apiDocsPaths {
+		if path == apiDocsPrefix+docsPath || path == apiV1Prefix+docsPath {
+			return apiDocsPrefix + docsPath
+		}
+	}
+	if !strings.HasPrefix(path, apiV1Prefix+"/") && strings.HasPrefix(path, legacyAPIPrefix+"/") {
+		path = legacyAPISuccessor(path)
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func TestOpenAPI_RoutesMatchSpec(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+	r := newContractRouter(t)
+
+	registered := make(map[string]bool)
+	for _, route := range r.Routes() {
+		key := route.Method + " " + specPath(route.Path)
+		registered[key] = true
+
+		pathItem := spec.Paths.Find(specPath(route.Path))
+		if assert.NotNil(t, pathItem, "route %s is not documented", key) {
+			assert.NotNil(t, pathItem.GetOperation(route.Method), "route %s is not documented", key)
+		}
+	}
+
+	for path, pathItem := range spec.Paths.Map() {
+		for method := range pathItem.Operations() {
+			assert.True(t, registered[method+" "+path], "documented operation %s %s has no route", method, path)
+		}
+	}
+}
+
+func TestOpenAPI_SchemasMatchModels(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+
+	schemaModels := map[string]interface{}{
+		"WeatherData":          models.WeatherData{},
+		"APIError":             models.APIError{},
+		"FieldError":           models.FieldError{},
+		"SearchResult":         models.WeatherAPISearchResult{},
+		"Forecast":             models.Forecast{},
+		"ForecastDay":          models.ForecastDay{},
+		"BatchWeatherItem":     models.BatchWeatherItem{},
+		"BatchWeatherRequest":  models.BatchWeatherRequest{},
+		"BatchWeatherResult":   models.BatchWeatherResult{},
+		"BatchWeatherResponse": models.BatchWeatherResponse{},
+		"ImportReport":         models.ImportReport{},
+		"ImportLineError":      models.ImportLineError{},
+		"UpstreamUsage":        models.UpstreamUsage{},
+		"ConfigVersion":        models.ConfigVersion{},
+		"HealthReport":         models.HealthReport{},
+		"ComponentHealth":      models.ComponentHealth{},
+		"GraphQLRequest":       models.GraphQLRequest{},
+		"GraphQLResponse":      models.GraphQLResponse{},
+		"GraphQLError":         models.GraphQLError{},
+		"GraphQLErrorLocation": models.GraphQLErrorLocation{},
+	}
+
+	for name := range spec.Components.Schemas {
+		assert.Contains(t, schemaModels, name, "schema %s has no model", name)
+	}
+
+	for name, model := range schemaModels {
+		t.Run(name, func(t *testing.T) {
+			ref := spec.Components.Schemas[name]
+			require.NotNil(t, ref, "model has no schema")
+
+			var fields, required []string
+			modelType := reflect.TypeOf(model)
+			for i := 0; i < modelType.NumField(); i++ {
+				tag := modelType.Field(i).Tag.Get("json")
+				field, options, _ := strings.Cut(tag, ",")
+				if field == "" || field == "-" {
+					continue
+				}
+				fields = append(fields, field)
+				if options != "omitempty" {
+					required = append(required, field)
+				}
+			}
+
+			var properties []string
+			for property := range ref.Value.Properties {
+				properties = append(properties, property)
+			}
+			specRequired := append([]string(nil), ref.Value.Required...)
+
+			sort.Strings(fields)
+			sort.Strings(required)
+			sort.Strings(properties)
+			sort.Strings(specRequired)
+			assert.Equal(t, fields, properties, "properties differ from the JSON fields")
+			assert.Equal(t, required, specRequired, "required properties differ from the fields without omitempty")
+		})
+	}
+}
+
+func TestOpenAPI_ResponsesMatchSpec(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+	specRouter, err := gorillamux.NewRouter(spec)
+	require.NoError(t, err)
+	r := newContractRouter(t)
+
+	admin := http.Header{"Authorization": {"Bearer " + contractAdminToken}}
+	tests := []struct {
+		method string
+		path   string
+		header http.Header
+		body   string
+		status int
+	}{
+		{"GET", "/healthz", nil, "", http.StatusOK},
+		{"GET", "/readyz", nil, "", http.StatusOK},
+		{"GET", "/metrics", nil, "", http.StatusOK},
+		{"GET", "/", nil, "", http.StatusOK},
+		{"GET", "/static/style.css", nil, "", http.StatusOK},
+		{"HEAD", "/static/script.js", nil, "", http.StatusOK},
+		{"GET", "/api/openapi.json", nil, "", http.StatusOK},
+		{"GET", "/api/docs", nil, "", http.StatusOK},
+		{"GET", "/api/v1/weather/London", nil, "", http.StatusOK},
+		{"GET", "/api/v1/weather/Atlantis", nil, "", http.StatusNotFound},
+		{"GET", "/api/v1/weather/London1", nil, "", http.StatusBadRequest},
+		{"POST", "/api/v1/weather/batch", http.Header{"Content-Type": {"application/json"}}, `{"items":[{"city":"London"},{"lat":51.5,"lon":-0.1},{"city":"Atlantis"}]}`, http.StatusOK},
+		{"POST", "/api/v1/weather/batch", http.Header{"Content-Type": {"application/json"}}, `{"items":[]}`, http.StatusBadRequest},
+		{"GET", "/api/v1/weather/coordinates/51.5074/-0.1278", nil, "", http.StatusOK},
+		{"GET", "/api/v1/weather/coordinates/91/0", nil, "", http.StatusBadRequest},
+		{"GET", "/api/v1/forecast/London?days=1", nil, "", http.StatusOK},
+		{"GET", "/api/v1/forecast/Atlantis", nil, "", http.StatusNotFound},
+		{"GET", "/api/v1/search?q=Lon", nil, "", http.StatusOK},
+		{"GET", "/api/v1/search?q=Atlantis", nil, "", http.StatusOK},
+		{"GET", "/api/v1/history", nil, "", http.StatusOK},
+		{"GET", "/api/v1/history?limit=5", nil, "", http.StatusOK},
+		{"GET", "/api/v1/history/export?format=csv&city=London", nil, "", http.StatusOK},
+		{"GET", "/api/v1/history/export?format=ndjson&from=2024-01-01", nil, "", http.StatusOK},
+		{"GET", "/api/v1/history/export?format=parquet", nil, "", http.StatusOK},
+		{"POST", "/api/v1/history/import", http.Header{"Content-Type": {"text/csv"}, "Authorization": admin["Authorization"]},
+			"city,timestamp,temperature,humidity,description\nParis,2024-03-01T12:00:00Z,18,55,Sunny\nRome,,20,50,Sunny\n", http.StatusOK},
+		{"POST", "/api/v1/history/import", http.Header{"Content-Type": {"application/x-ndjson"}}, "{}\n", http.StatusUnauthorized},
+		{"GET", "/api/v1/admin/usage", admin, "", http.StatusOK},
+		{"GET", "/api/v1/admin/config", admin, "", http.StatusOK},
+		{"GET", "/api/v1/admin/config", nil, "", http.StatusUnauthorized},
+		{"POST", "/graphql", http.Header{"Content-Type": {"application/json"}},
+			`{"query":"{ cities(names: [\"London\", \"Atlantis\"]) { name current { city temperature } forecast(days: 1) { days { date } } } history(limit: 2) { id } }"}`, http.StatusOK},
+		{"POST", "/graphql", http.Header{"Content-Type": {"application/json"}}, `{"query":"{ current(city: \"London\") { wind } }"}`, http.StatusBadRequest},
+	}
+
+	covered := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+
+			// Validate a fresh copy of the request, since serving consumed
+			// the body
+			specReq := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			specReq.Header = req.Header
+			route, pathParams, err := specRouter.FindRoute(specReq)
+			require.NoError(t, err)
+			covered[route.Method+" "+route.Path] = true
+
+			ctx := context.Background()
+			requestInput := &openapi3filter.RequestValidationInput{
+				Request:    specReq,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+			}
+			if tt.status < http.StatusBadRequest {
+				require.NoError(t, openapi3filter.ValidateRequest(ctx, requestInput), "request does not match the spec")
+			}
+
+			responseInput := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: requestInput,
+				Status:                 w.Code,
+				Header:                 w.Header(),
+				Body:                   io.NopCloser(bytes.NewReader(w.Body.Bytes())),
+				Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+			}
+			assert.NoError(t, openapi3filter.ValidateResponse(ctx, responseInput), "response does not match the spec")
+		})
+	}
+
+	for path, pathItem := range spec.Paths.Map() {
+		for method := range pathItem.Operations() {
+			assert.True(t, covered[method+" "+path], "no contract case for %s %s", method, path)
+		}
+	}
+}
+
+func TestOpenAPI_ServedSpecIsValidJSON(t *testing.T) {
+	r := newContractRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, json.Valid(w.Body.Bytes()))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/docs", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `url: "/api/openapi.json"`)
+	assert.NotContains(t, w.Body.String(), "https://", "docs page loads third-party assets")
+
+	// The vendored Swagger UI assets it links to are served
+	page := w.Body.String()
+	for _, asset := range []string{"/static/swagger-ui/swagger-ui.css", "/static/swagger-ui/swagger-ui-bundle.js"} {
+		assert.Contains(t, page, asset)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", asset, nil))
+		assert.Equal(t, http.StatusOK, w.Code, asset)
+	}
+}
+
+func TestAPIDocsPaths(t *testing.T) {
+	r := newContractRouter(t)
+
+	for _, docsPath := range apiDocsPaths {
+		t.Run(docsPath, func(t *testing.T) {
+			docs := httptest.NewRecorder()
+			r.ServeHTTP(docs, httptest.NewRequest("GET", apiDocsPrefix+docsPath, nil))
+			alias := httptest.NewRecorder()
+			r.ServeHTTP(alias, httptest.NewRequest("GET", apiV1Prefix+docsPath, nil))
+
+			require.Equal(t, http.StatusOK, docs.Code)
+			assert.Equal(t, docs.Body.String(), alias.Body.String())
+
+			// The docs are permanent, not deprecated aliases of v1
+			assert.Empty(t, docs.Header().Get("Deprecation"))
+			assert.Empty(t, docs.Header().Get("Sunset"))
+		})
+	}
+}
+
+func TestLegacyAPIAliases(t *testing.T) {
+	r := newContractRouter(t)
+
+	tests := []struct {
+		path      string
+		successor string
+	}{
+		{"/api/weather/London", "/api/v1/weather/London"},
+		{"/api/history?limit=1", "/api/v1/history?limit=1"},
+		{"/api/admin/config", "/api/v1/admin/config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			legacy := httptest.NewRecorder()
+			r.ServeHTTP(legacy, httptest.NewRequest("GET", tt.path, nil))
+			current := httptest.NewRecorder()
+			r.ServeHTTP(current, httptest.NewRequest("GET", tt.successor, nil))
+
+			assert.Equal(t, current.Code, legacy.Code)
+			assert.Equal(t, "@"+strconv.FormatInt(legacyAPIDeprecated.Unix(), 10), legacy.Header().Get("Deprecation"))
+			assert.Equal(t, legacyAPISunset.Format(http.TimeFormat), legacy.Header().Get("Sunset"))
+			assert.Equal(t, "<"+strings.Split(tt.successor, "?")[0]+`>; rel="successor-version"`, legacy.Header().Get("Link"))
+
+			assert.Empty(t, current.Header().Get("Deprecation"))
+			assert.Empty(t, current.Header().Get("Sunset"))
+		})
+	}
+}
+
+func TestGraphQLSharesBatchRateLimit(t *testing.T) {
+	r := newContractRouter(t, func(cfg *config.Config) {
+		cfg.RateLimit.Enabled = true
+		cfg.RateLimit.Batch = config.RateLimitRule{Rate: 0.001, Burst: 1}
+	})
+
+	post := func(path, body string) int {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	query := `{"query":"{ history(limit: 1) { id } }"}`
+	assert.Equal(t, http.StatusOK, post("/graphql", query))
+	assert.Equal(t, http.StatusTooManyRequests, post("/graphql", query))
+	assert.Equal(t, http.StatusTooManyRequests, post("/api/v1/weather/batch", `{"items":[{"city":"London"}]}`))
+}
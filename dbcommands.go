@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"weather-dashboard/config"
+	"weather-dashboard/services"
+)
+
+// pruneBatchSize is the number of observations deleted per transaction by
+// db prune
+const pruneBatchSize = 1000
+
+// dbCommand is a database maintenance command. It receives the database
+// named by the configuration, already opened and migrated.
+type dbCommand struct {
+	usage string
+	run   func(ctx context.Context, db *services.DatabaseService, fs *flag.FlagSet, args []string, stdout io.Writer) error
+}
+
+// dbCommands maps the subcommands of db to their implementations
+var dbCommands = map[string]dbCommand{
+	"migrate":         {"", runDBMigrate},
+	"backup":          {"<file>", runDBBackup},
+	"restore":         {"<file>", runDBRestore},
+	"stats":           {"[-json]", runDBStats},
+	"prune":           {"-before <time>", runDBPrune},
+	"integrity-check": {"", runDBIntegrityCheck},
+}
+
+// runDB runs a database maintenance command against the database at the
+// configured path
+func runDB(ctx context.Context, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: weather-dashboard db <%s> [flags]", strings.Join(dbCommandNames(), "|"))
+	}
+	command, ok := dbCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q (use %s)", args[0], strings.Join(dbCommandNames(), ", "))
+	}
+
+	fs := flag.NewFlagSet("db "+args[0], flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), strings.TrimSpace("usage: weather-dashboard db "+args[0]+" "+command.usage))
+		fs.PrintDefaults()
+	}
+
+	cfg, err := config.LoadLocal()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	dbService, err := services.NewDatabaseService(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database service: %w", err)
+	}
+	defer dbService.Close()
+
+	return command.run(ctx, dbService, fs, args[1:], stdout)
+}
+
+// dbCommandNames returns the db subcommands in alphabetical order
+func dbCommandNames() []string {
+	names := make([]string, 0, len(dbCommands))
+	for name := range dbCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseFileArg parses a command's flags and its single file argument
+func parseFileArg(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", fmt.Errorf("expected exactly one file")
+	}
+	return fs.Arg(0), nil
+}
+
+// runDBMigrate reports the schema version. Opening the database has already
+// applied any pending migrations.
+func runDBMigrate(ctx context.Context, db *services.DatabaseService, fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	if _, err := parseLookup(fs, args, false); err != nil {
+		return err
+	}
+
+	version, err := db.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "schema is at version %d\n", version)
+	return nil
+}
+
+// runDBBackup writes an online backup of the database to a new file
+func runDBBackup(ctx context.Context, db *services.DatabaseService, fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	path, err := parseFileArg(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Backup(ctx, path); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "backed up to %s\n", path)
+	return nil
+}
+
+// runDBRestore replaces the database with a backup
+func runDBRestore(ctx context.Context, db *services.DatabaseService, fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	path, err := parseFileArg(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Restore(ctx, path); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "restored from %s\n", path)
+	return nil
+}
+
+// runDBStats prints row counts, the database size and the time span of the
+// stored observations
+func runDBStats(ctx context.Context, db *services.DatabaseService, fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	if _, err := parseLookup(fs, args, false); err != nil {
+		return err
+	}
+
+	stats, err := db.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(stdout, stats)
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Local().Format(time.DateTime)
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Schema version:\t%d\n", stats.SchemaVersion)
+	fmt.Fprintf(tw, "Size:\t%s (%s free)\n", formatBytes(stats.SizeBytes), formatBytes(stats.FreeBytes))
+	fmt.Fprintf(tw, "Oldest observation:\t%s\n", formatTime(stats.Oldest))
+	fmt.Fprintf(tw, "Newest observation:\t%s\n", formatTime(stats.Newest))
+	tables := make([]string, 0, len(stats.Rows))
+	for table := range stats.Rows {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Fprintf(tw, "Rows in %s:\t%d\n", table, stats.Rows[table])
+	}
+	return tw.Flush()
+}
+
+// formatBytes formats a size with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// runDBPrune deletes observations and aggregates older than -before
+func runDBPrune(ctx context.Context, db *services.DatabaseService, fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	before := fs.String("before", "", "delete data older than this RFC 3339 timestamp, YYYY-MM-DD date (UTC) or age such as 90d or 720h")
+	if _, err := parseLookup(fs, args, false); err != nil {
+		return err
+	}
+	if *before == "" {
+		fs.Usage()
+		return fmt.Errorf("-before is required")
+	}
+	cutoff, err := parseCutoff(*before, time.Now())
+	if err != nil {
+		return err
+	}
+
+	report, err := db.PruneBefore(ctx, cutoff, pruneBatchSize)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "deleted %d observations, %d hourly and %d daily aggregates before %s\n",
+		report.Observations, report.Hourly, report.Daily, cutoff.Format(time.RFC3339))
+	return nil
+}
+
+// parseCutoff parses an RFC 3339 timestamp, a date or an age relative to now
+func parseCutoff(value string, now time.Time) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	if parsed, err := time.Parse(time.DateOnly, value); err == nil {
+		return parsed, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if age, err := time.ParseDuration(value); err == nil && age >= 0 {
+		return now.Add(-age), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid -before %q: use an RFC 3339 timestamp, a YYYY-MM-DD date or an age such as 90d", value)
+}
+
+// runDBIntegrityCheck runs SQLite's integrity check and fails if it finds
+// problems
+func runDBIntegrityCheck(ctx context.Context, db *services.DatabaseService, fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	if _, err := parseLookup(fs, args, false); err != nil {
+		return err
+	}
+
+	problems, err := db.IntegrityCheck(ctx)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Fprintln(stdout, problem)
+		}
+		return fmt.Errorf("integrity check found %d problems", len(problems))
+	}
+	fmt.Fprintln(stdout, "ok")
+	return nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"weather-dashboard/models"
+	"weather-dashboard/services"
+)
+
+// setupDBCommands points the configuration at a fresh database without a
+// WeatherAPI key, which the db commands must not need
+func setupDBCommands(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "weather.db")
+	t.Setenv("DB_PATH", path)
+	t.Setenv("WEATHERAPI_KEY", "")
+	t.Setenv("WEATHERAPI_KEYS", "")
+	return path
+}
+
+func TestRunDB(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		expectedError string
+		expected      string
+	}{
+		{name: "no command", expectedError: "usage: weather-dashboard db <backup|integrity-check|migrate|prune|restore|stats>"},
+		{name: "unknown command", args: []string{"vacuum"}, expectedError: `unknown command "vacuum"`},
+		{name: "migrate", args: []string{"migrate"}, expected: "schema is at version"},
+		{name: "migrate rejects arguments", args: []string{"migrate", "extra"}, expectedError: "unexpected arguments: extra"},
+		{name: "integrity check", args: []string{"integrity-check"}, expected: "ok\n"},
+		{name: "stats", args: []string{"stats"}, expected: "Rows in weather_data:"},
+		{name: "prune requires before", args: []string{"prune"}, expectedError: "-before is required"},
+		{name: "prune", args: []string{"prune", "-before", "30d"}, expected: "deleted 0 observations, 0 hourly and 0 daily aggregates"},
+		{name: "backup requires a file", args: []string{"backup"}, expectedError: "expected exactly one file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupDBCommands(t)
+
+			var stdout bytes.Buffer
+			err := runDB(context.Background(), tt.args, &stdout)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, stdout.String(), tt.expected)
+		})
+	}
+}
+
+func TestRunDB_StatsJSON(t *testing.T) {
+	path := setupDBCommands(t)
+	saveObservations(t, path, "London")
+
+	var stdout bytes.Buffer
+	require.NoError(t, runDB(context.Background(), []string{"stats", "-json"}, &stdout))
+
+	var stats models.DatabaseStats
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &stats))
+	assert.Equal(t, int64(1), stats.Rows["weather_data"])
+	assert.NotNil(t, stats.Newest)
+}
+
+func TestRunDB_BackupRestore(t *testing.T) {
+	path := setupDBCommands(t)
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	ctx := context.Background()
+
+	saveObservations(t, path, "London")
+
+	var stdout bytes.Buffer
+	require.NoError(t, runDB(ctx, []string{"backup", backup}, &stdout))
+	assert.Equal(t, "backed up to "+backup+"\n", stdout.String())
+
+	// Changes made after the backup are undone by the restore
+	saveObservations(t, path, "Paris")
+
+	stdout.Reset()
+	require.NoError(t, runDB(ctx, []string{"restore", backup}, &stdout))
+	assert.Equal(t, "restored from "+backup+"\n", stdout.String())
+
+	db, err := services.NewDatabaseService(path)
+	require.NoError(t, err)
+	defer db.Close()
+	history, err := db.GetWeatherHistory(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "London", history[0].City)
+}
+
+// saveObservations stores one observation per city in the database at path
+func saveObservations(t *testing.T, path string, cities ...string) {
+	db, err := services.NewDatabaseService(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, city := range cities {
+		require.NoError(t, db.SaveWeatherData(context.Background(), &models.WeatherData{
+			City:        city,
+			Country:     "UK",
+			Temperature: 15,
+			Timestamp:   time.Now(),
+		}))
+	}
+}
+
+func TestParseCutoff(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		value         string
+		expected      time.Time
+		expectedError bool
+	}{
+		{name: "RFC 3339", value: "2026-01-02T03:04:05Z", expected: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{name: "RFC 3339 with offset", value: "2026-01-02T03:04:05+02:00", expected: time.Date(2026, 1, 2, 1, 4, 5, 0, time.UTC)},
+		{name: "date", value: "2026-01-02", expected: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "days", value: "90d", expected: now.AddDate(0, 0, -90)},
+		{name: "zero days", value: "0d", expected: now},
+		{name: "duration", value: "36h", expected: now.Add(-36 * time.Hour)},
+		{name: "negative days", value: "-5d", expectedError: true},
+		{name: "negative duration", value: "-1h", expectedError: true},
+		{name: "fractional days", value: "1.5d", expectedError: true},
+		{name: "garbage", value: "last week", expectedError: true},
+		{name: "empty", value: "", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cutoff, err := parseCutoff(tt.value, now)
+			if tt.expectedError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid -before")
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(cutoff), "expected %s, got %s", tt.expected, cutoff)
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{1024 * 1024, "1.0 MiB"},
+		{5*1024*1024*1024 + 512*1024*1024, "5.5 GiB"},
+		{1 << 60, "1.0 EiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatBytes(tt.bytes))
+		})
+	}
+}
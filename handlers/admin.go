@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"weather-dashboard/models"
+)
+
+type UsageReporterInterface interface {
+	Usage(ctx context.Context) (*models.UpstreamUsage, error)
+}
+
+type ConfigVersionInterface interface {
+	Version() models.ConfigVersion
+}
+
+// AdminHandler handles administrative HTTP requests
+type AdminHandler struct {
+	usageReporter UsageReporterInterface
+	configVersion ConfigVersionInterface
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(usageReporter UsageReporterInterface, configVersion ConfigVersionInterface) *AdminHandler {
+	return &AdminHandler{
+		usageReporter: usageReporter,
+		configVersion: configVersion,
+	}
+}
+
+// GetUsage handles GET /api/v1/admin/usage
+func (h *AdminHandler) GetUsage(c *gin.Context) {
+	usage, err := h.usageReporter.Usage(c.Request.Context())
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to fetch upstream usage", "error", err)
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// GetConfigVersion handles GET /api/v1/admin/config
+func (h *AdminHandler) GetConfigVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, h.configVersion.Version())
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"weather-dashboard/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockUsageReporter struct {
+	usage *models.UpstreamUsage
+	err   error
+}
+
+func (m *MockUsageReporter) Usage(ctx context.Context) (*models.UpstreamUsage, error) {
+	return m.usage, m.err
+}
+
+type MockConfigVersion struct {
+	version models.ConfigVersion
+}
+
+func (m *MockConfigVersion) Version() models.ConfigVersion {
+	return m.version
+}
+
+func TestAdminHandler_GetUsage(t *testing.T) {
+	tests := []struct {
+		name           string
+		reporter       *MockUsageReporter
+		expectedStatus int
+	}{
+		{
+			name: "usage reported",
+			reporter: &MockUsageReporter{usage: &models.UpstreamUsage{
+				Provider:     "weatherapi",
+				DailyCalls:   42,
+				DailyLimit:   100,
+				MonthlyCalls: 420,
+			}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "store error",
+			reporter:       &MockUsageReporter{err: assert.AnError},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAdminHandler(tt.reporter, &MockConfigVersion{})
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.GET("/api/admin/usage", handler.GetUsage)
+
+			req, err := http.NewRequest("GET", "/api/admin/usage", nil)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var response models.UpstreamUsage
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "weatherapi", response.Provider)
+				assert.Equal(t, 42, response.DailyCalls)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_GetConfigVersion(t *testing.T) {
+	loadedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	handler := NewAdminHandler(&MockUsageReporter{}, &MockConfigVersion{version: models.ConfigVersion{
+		Version:         3,
+		Checksum:        "0123456789ab",
+		Source:          "config.yaml",
+		LoadedAt:        loadedAt,
+		LastReloadError: "settings require a restart: server.port",
+	}})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/admin/config", handler.GetConfigVersion)
+
+	req, err := http.NewRequest("GET", "/api/admin/config", nil)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.ConfigVersion
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 3, response.Version)
+	assert.Equal(t, "0123456789ab", response.Checksum)
+	assert.True(t, loadedAt.Equal(response.LoadedAt))
+	assert.Contains(t, response.LastReloadError, "server.port")
+}
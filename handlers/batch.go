@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"weather-dashboard/models"
+)
+
+// GetWeatherBatch handles POST /api/v1/weather/batch
+func (h *WeatherHandler) GetWeatherBatch(c *gin.Context) {
+	var req models.BatchWeatherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidInput(c, "invalid request body: "+err.Error())
+		return
+	}
+
+	if len(req.Items) == 0 {
+		respondInvalidInput(c, "items must not be empty")
+		return
+	}
+
+	if len(req.Items) > models.MaxBatchSize {
+		respondInvalidInput(c, fmt.Sprintf("too many items: %d (max %d)", len(req.Items), models.MaxBatchSize))
+		return
+	}
+
+	results := h.fetchBatch(c.Request.Context(), req.Items, models.BatchWorkers)
+
+	response := models.BatchWeatherResponse{Results: results}
+	for _, result := range results {
+		if result.Error != "" {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// fetchBatch looks up every item using a fixed pool of workers.
+// Results are returned in the same order as the items. Items not yet
+// started when ctx is cancelled are reported as failed.
+func (h *WeatherHandler) fetchBatch(ctx context.Context, items []models.BatchWeatherItem, workers int) []models.BatchWeatherResult {
+	results := make([]models.BatchWeatherResult, len(items))
+	jobs := make(chan int)
+
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					results[i] = models.BatchWeatherResult{Index: i, Error: err.Error(), Code: models.ErrorCodeInternal}
+					continue
+				}
+				results[i] = h.fetchBatchItem(ctx, i, items[i])
+			}
+		}()
+	}
+
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// fetchBatchItem performs a single lookup and saves successful results
+func (h *WeatherHandler) fetchBatchItem(ctx context.Context, index int, item models.BatchWeatherItem) models.BatchWeatherResult {
+	result := models.BatchWeatherResult{Index: index}
+
+	item, problems := validateBatchItem(item)
+	if len(problems) > 0 {
+		result.Query = item.City
+		result.Error = "invalid item: " + describeProblems(problems)
+		result.Code = models.ErrorCodeInvalidInput
+		return result
+	}
+
+	var (
+		weatherData *models.WeatherData
+		err         error
+	)
+
+	switch {
+	case item.City != "":
+		result.Query = item.City
+		weatherData, err = h.weatherService.GetWeatherByCity(ctx, item.City)
+	case item.Lat != nil && item.Lon != nil:
+		lat := fmt.Sprintf("%f", *item.Lat)
+		lon := fmt.Sprintf("%f", *item.Lon)
+		result.Query = lat + "," + lon
+		weatherData, err = h.weatherService.GetWeatherByCoordinates(ctx, lat, lon)
+	default:
+		result.Error = "either city or both lat and lon are required"
+		result.Code = models.ErrorCodeInvalidInput
+		return result
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to fetch weather for batch item", "index", index, "query", result.Query, "error", err)
+		result.Error = errorMessage(err)
+		_, result.Code = errorStatus(err)
+		return result
+	}
+
+	saveWeatherData(ctx, h.dbService, weatherData)
+	result.Data = weatherData
+	return result
+}
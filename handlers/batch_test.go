@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"weather-dashboard/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cityWeatherService returns data for known cities and an error otherwise
+type cityWeatherService struct {
+	MockWeatherService
+	cities map[string]*models.WeatherData
+	calls  int32
+}
+
+func (m *cityWeatherService) GetWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
+	atomic.AddInt32(&m.calls, 1)
+	if data, ok := m.cities[strings.ToLower(city)]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("city %w: %s", models.ErrNotFound, city)
+}
+
+func (m *cityWeatherService) GetWeatherByCoordinates(ctx context.Context, lat, lon string) (*models.WeatherData, error) {
+	atomic.AddInt32(&m.calls, 1)
+	return &models.WeatherData{City: lat + "," + lon}, nil
+}
+
+func postBatch(t *testing.T, r *gin.Engine, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/api/weather/batch", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestWeatherHandler_GetWeatherBatch(t *testing.T) {
+	weatherService := &cityWeatherService{
+		cities: map[string]*models.WeatherData{
+			"london": {City: "London", Temperature: 15.5},
+			"paris":  {City: "Paris", Temperature: 18.2},
+		},
+	}
+	handler := NewWeatherHandler(weatherService, &MockDatabaseService{})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/weather/batch", handler.GetWeatherBatch)
+
+	t.Run("mixed results keep request order", func(t *testing.T) {
+		body := `{"items":[{"city":"london"},{"city":"atlantis"},{"lat":51.5,"lon":-0.12},{"city":"paris"},{}]}`
+		w := postBatch(t, r, body)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.BatchWeatherResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Results, 5)
+		assert.Equal(t, 3, response.Succeeded)
+		assert.Equal(t, 2, response.Failed)
+
+		for i, result := range response.Results {
+			assert.Equal(t, i, result.Index)
+		}
+
+		assert.Equal(t, "London", response.Results[0].Data.City)
+		assert.Contains(t, response.Results[1].Error, "city not found")
+		assert.Equal(t, models.ErrorCodeNotFound, response.Results[1].Code)
+		assert.Nil(t, response.Results[1].Data)
+		assert.Equal(t, "51.500000,-0.120000", response.Results[2].Query)
+		assert.Equal(t, "Paris", response.Results[3].Data.City)
+		assert.Equal(t, "either city or both lat and lon are required", response.Results[4].Error)
+		assert.Equal(t, models.ErrorCodeInvalidInput, response.Results[4].Code)
+	})
+
+	t.Run("empty items", func(t *testing.T) {
+		w := postBatch(t, r, `{"items":[]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		w := postBatch(t, r, `not json`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("too many items", func(t *testing.T) {
+		items := make([]string, models.MaxBatchSize+1)
+		for i := range items {
+			items[i] = `{"city":"london"}`
+		}
+		w := postBatch(t, r, `{"items":[`+strings.Join(items, ",")+`]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response models.APIError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Contains(t, response.Error, "too many items")
+		assert.Equal(t, models.ErrorCodeInvalidInput, response.Code)
+	})
+}
+
+func TestWeatherHandler_fetchBatch(t *testing.T) {
+	weatherService := &cityWeatherService{
+		cities: map[string]*models.WeatherData{"london": {City: "London"}},
+	}
+	handler := NewWeatherHandler(weatherService, &MockDatabaseService{})
+
+	items := make([]models.BatchWeatherItem, 12)
+	for i := range items {
+		items[i] = models.BatchWeatherItem{City: "london"}
+	}
+
+	results := handler.fetchBatch(context.Background(), items, 4)
+	require.Len(t, results, len(items))
+	assert.Equal(t, int32(len(items)), atomic.LoadInt32(&weatherService.calls))
+	for _, result := range results {
+		assert.Empty(t, result.Error)
+		assert.Equal(t, "London", result.Data.City)
+	}
+}
+
+func TestWeatherHandler_fetchBatchCancelled(t *testing.T) {
+	weatherService := &cityWeatherService{
+		cities: map[string]*models.WeatherData{"london": {City: "London"}},
+	}
+	handler := NewWeatherHandler(weatherService, &MockDatabaseService{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := handler.fetchBatch(ctx, []models.BatchWeatherItem{{City: "london"}, {City: "london"}}, 2)
+	require.Len(t, results, 2)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&weatherService.calls))
+	for i, result := range results {
+		assert.Equal(t, i, result.Index)
+		assert.Equal(t, context.Canceled.Error(), result.Error)
+	}
+}
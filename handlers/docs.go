@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec is the OpenAPI 3 document describing every route. The
+// contract test in package main fails when it drifts from the handlers.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// swaggerUIAssets is where the vendored Swagger UI release (see NOTICE
+// there) is served from, so the docs page loads no third-party scripts
+const swaggerUIAssets = "/static/swagger-ui"
+
+// apiDocsPage renders the spec with Swagger UI
+const apiDocsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Weather Dashboard API</title>
+  <link rel="stylesheet" href="` + swaggerUIAssets + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="` + swaggerUIAssets + `/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/api/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// OpenAPISpec returns the OpenAPI document served by ServeOpenAPI
+func OpenAPISpec() []byte {
+	return openAPISpec
+}
+
+// ServeOpenAPI handles GET /api/openapi.json
+func ServeOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openAPISpec)
+}
+
+// ServeAPIDocs handles GET /api/docs with an interactive Swagger UI page
+func ServeAPIDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(apiDocsPage))
+}
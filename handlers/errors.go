@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"weather-dashboard/logging"
+	"weather-dashboard/models"
+)
+
+// errorMappings maps service errors to HTTP statuses and error codes.
+// The first match wins.
+var errorMappings = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{models.ErrInvalidInput, http.StatusBadRequest, models.ErrorCodeInvalidInput},
+	{models.ErrNotFound, http.StatusNotFound, models.ErrorCodeNotFound},
+	{models.ErrQuotaExceeded, http.StatusTooManyRequests, models.ErrorCodeQuotaExceeded},
+	{models.ErrUnauthorized, http.StatusServiceUnavailable, models.ErrorCodeUpstreamUnauthorized},
+	{models.ErrUpstreamUnavailable, http.StatusBadGateway, models.ErrorCodeUpstreamUnavailable},
+}
+
+// errorStatus maps a service error to an HTTP status code and error code
+func errorStatus(err error) (int, string) {
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return mapping.status, mapping.code
+		}
+	}
+	return http.StatusInternalServerError, models.ErrorCodeInternal
+}
+
+// errorMessage returns the client-facing message for err with any secrets
+// such as API keys redacted
+func errorMessage(err error) string {
+	return logging.Redact(err.Error())
+}
+
+// respondError writes err as a JSON error response
+func respondError(c *gin.Context, err error) {
+	status, code := errorStatus(err)
+	c.JSON(status, models.APIError{Error: errorMessage(err), Code: code})
+}
+
+// respondInvalidInput writes a 400 response with the given message
+func respondInvalidInput(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, models.APIError{Error: message, Code: models.ErrorCodeInvalidInput})
+}
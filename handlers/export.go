@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/parquet-go/parquet-go"
+
+	"weather-dashboard/models"
+)
+
+// exportFlushRows is how many rows are buffered before they are flushed to
+// the client (and, for Parquet, closed into a row group)
+const exportFlushRows = 1000
+
+// exportContentTypes maps export formats to response content types
+var exportContentTypes = map[string]string{
+	models.FormatCSV:     "text/csv; charset=utf-8",
+	models.FormatNDJSON:  "application/x-ndjson",
+	models.FormatParquet: "application/vnd.apache.parquet",
+}
+
+// exportWriter encodes observations in one export format
+type exportWriter interface {
+	Write(data *models.WeatherData) error
+	// Flush writes buffered rows to the underlying writer
+	Flush() error
+	// Close flushes remaining rows and writes any trailer
+	Close() error
+}
+
+// newExportWriter creates an encoder for format writing to w
+func newExportWriter(format string, w io.Writer) exportWriter {
+	switch format {
+	case models.FormatNDJSON:
+		return &ndjsonExportWriter{encoder: json.NewEncoder(w)}
+	case models.FormatParquet:
+		return &parquetExportWriter{writer: parquet.NewGenericWriter[parquetRow](w, parquet.MaxRowsPerRowGroup(exportFlushRows))}
+	default:
+		return &csvExportWriter{writer: csv.NewWriter(w)}
+	}
+}
+
+// csvExportWriter writes a header row followed by one row per observation
+type csvExportWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (e *csvExportWriter) Write(data *models.WeatherData) error {
+	if !e.headerWritten {
+		if err := e.writer.Write(models.HistoryCSVColumns); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+
+	return e.writer.Write([]string{
+		strconv.Itoa(data.ID),
+		data.City,
+		data.Country,
+		data.State,
+		strconv.FormatFloat(data.Temperature, 'f', -1, 64),
+		data.Description,
+		strconv.Itoa(data.Humidity),
+		data.Icon,
+		strconv.Itoa(data.ConditionCode),
+		data.Timestamp.UTC().Format(time.RFC3339),
+	})
+}
+
+func (e *csvExportWriter) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvExportWriter) Close() error {
+	if !e.headerWritten {
+		if err := e.writer.Write(models.HistoryCSVColumns); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+	return e.Flush()
+}
+
+// ndjsonExportWriter writes one JSON object per line
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExportWriter) Write(data *models.WeatherData) error {
+	return e.encoder.Encode(data)
+}
+
+func (e *ndjsonExportWriter) Flush() error { return nil }
+
+func (e *ndjsonExportWriter) Close() error { return nil }
+
+// parquetRow is the Parquet schema of an exported observation
+type parquetRow struct {
+	ID            int64     `parquet:"id"`
+	City          string    `parquet:"city,dict"`
+	Country       string    `parquet:"country,dict"`
+	State         string    `parquet:"state,dict"`
+	Temperature   float64   `parquet:"temperature"`
+	Description   string    `parquet:"description,dict"`
+	Humidity      int32     `parquet:"humidity"`
+	Icon          string    `parquet:"icon,dict"`
+	ConditionCode int32     `parquet:"condition_code"`
+	Timestamp     time.Time `parquet:"timestamp,timestamp(millisecond)"`
+}
+
+// parquetExportWriter writes a Parquet file with a row group per
+// exportFlushRows observations
+type parquetExportWriter struct {
+	writer *parquet.GenericWriter[parquetRow]
+}
+
+func (e *parquetExportWriter) Write(data *models.WeatherData) error {
+	_, err := e.writer.Write([]parquetRow{{
+		ID:            int64(data.ID),
+		City:          data.City,
+		Country:       data.Country,
+		State:         data.State,
+		Temperature:   data.Temperature,
+		Description:   data.Description,
+		Humidity:      int32(data.Humidity),
+		Icon:          data.Icon,
+		ConditionCode: int32(data.ConditionCode),
+		Timestamp:     data.Timestamp.UTC(),
+	}})
+	return err
+}
+
+// Flush is a no-op: row groups are closed by the writer itself once they
+// hold exportFlushRows rows
+func (e *parquetExportWriter) Flush() error { return nil }
+
+func (e *parquetExportWriter) Close() error {
+	return e.writer.Close()
+}
+
+// parseHistoryFilter reads the city, from, to and limit query parameters.
+// Times are RFC 3339 timestamps or YYYY-MM-DD dates (UTC midnight).
+func parseHistoryFilter(c *gin.Context) (models.HistoryFilter, []models.FieldError) {
+	var filter models.HistoryFilter
+	var problems []models.FieldError
+
+	if city := c.Query("city"); city != "" {
+		var cityProblems []models.FieldError
+		filter.City, cityProblems = validateCity("city", city)
+		problems = append(problems, cityProblems...)
+	}
+
+	for _, param := range []struct {
+		field string
+		dest  *time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	} {
+		value := strings.TrimSpace(c.Query(param.field))
+		if value == "" {
+			continue
+		}
+		parsed, err := parseFilterTime(value)
+		if err != nil {
+			problems = append(problems, models.FieldError{Field: param.field, Message: "must be an RFC 3339 timestamp or a YYYY-MM-DD date"})
+			continue
+		}
+		*param.dest = parsed
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		problems = append(problems, models.FieldError{Field: "to", Message: "must be after from"})
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			problems = append(problems, models.FieldError{Field: "limit", Message: "must be a non-negative integer"})
+		}
+		filter.Limit = limit
+	}
+
+	return filter, problems
+}
+
+// parseFilterTime parses an RFC 3339 timestamp or a date
+func parseFilterTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// ExportWeatherHistory handles GET /api/v1/history/export. Rows are streamed
+// from the database as they are encoded, so exports of any size use
+// constant memory.
+func (h *WeatherHandler) ExportWeatherHistory(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", models.FormatCSV))
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		respondValidationError(c, []models.FieldError{{Field: "format", Message: "must be one of csv, ndjson, parquet"}})
+		return
+	}
+
+	filter, problems := parseHistoryFilter(c)
+	if len(problems) > 0 {
+		respondValidationError(c, problems)
+		return
+	}
+
+	ctx := c.Request.Context()
+	writer := newExportWriter(format, c.Writer)
+	started := false
+	rows := 0
+
+	err := h.dbService.StreamWeatherHistory(ctx, filter, func(data *models.WeatherData) error {
+		if !started {
+			startExport(c, format, contentType)
+			started = true
+		}
+		if err := writer.Write(data); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to export weather history", "format", format, "rows", rows, "error", err)
+		if !started {
+			respondError(c, err)
+			return
+		}
+		abortExport()
+	}
+
+	if !started {
+		startExport(c, format, contentType)
+	}
+	if err := writer.Close(); err != nil {
+		slog.ErrorContext(ctx, "failed to finish weather history export", "format", format, "rows", rows, "error", err)
+		abortExport()
+	}
+	c.Writer.Flush()
+}
+
+// abortExport fails an export whose 200 status may already be sent. A normal
+// return would end the body cleanly and hide the truncation, so the
+// connection is reset instead and the client sees an incomplete response.
+func abortExport() {
+	panic(http.ErrAbortHandler)
+}
+
+// startExport writes the response headers of an export download
+func startExport(c *gin.Context, format, contentType string) {
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="weather-history.`+format+`"`)
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"weather-dashboard/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportTestData() []models.WeatherData {
+	return []models.WeatherData{
+		{ID: 1, City: "London", Country: "United Kingdom", Temperature: 15.5, Description: "Partly cloudy", Humidity: 65, ConditionCode: 1003, Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{ID: 2, City: "Paris", Country: "France", Temperature: 18.2, Description: "Sunny", Humidity: 55, ConditionCode: 1000, Timestamp: time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)},
+		{ID: 3, City: "London", Country: "United Kingdom", Temperature: 11, Description: "Light rain", Humidity: 80, ConditionCode: 1183, Timestamp: time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC)},
+	}
+}
+
+func setupExportRouter(dbService *MockDatabaseService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/history/export", NewWeatherHandler(&MockWeatherService{}, dbService).ExportWeatherHistory)
+	return r
+}
+
+func TestWeatherHandler_ExportWeatherHistory(t *testing.T) {
+	r := setupExportRouter(&MockDatabaseService{historyData: exportTestData()})
+
+	t.Run("csv", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/history/export?city=london", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "weather-history.csv")
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, models.HistoryCSVColumns, records[0])
+		assert.Equal(t, []string{"1", "London", "United Kingdom", "", "15.5", "Partly cloudy", "65", "", "1003", "2024-03-01T12:00:00Z"}, records[1])
+		assert.Equal(t, "3", records[2][0])
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/history/export?format=ndjson&from=2024-03-02", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+		var cities []string
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var data models.WeatherData
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &data))
+			cities = append(cities, data.City)
+		}
+		assert.Equal(t, []string{"Paris", "London"}, cities)
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/history/export?format=parquet", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/vnd.apache.parquet", w.Header().Get("Content-Type"))
+
+		body := w.Body.Bytes()
+		rows, err := parquet.Read[parquetRow](bytes.NewReader(body), int64(len(body)))
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, "Paris", rows[1].City)
+		assert.Equal(t, 18.2, rows[1].Temperature)
+		assert.True(t, rows[1].Timestamp.Equal(time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("empty csv has a header", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/history/export?city=Berlin", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{models.HistoryCSVColumns}, records)
+	})
+}
+
+func TestWeatherHandler_ExportWeatherHistoryErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		dbError        error
+		expectedStatus int
+		expectedFields []string
+	}{
+		{name: "unknown format", query: "format=xlsx", expectedStatus: http.StatusBadRequest, expectedFields: []string{"format"}},
+		{name: "invalid filters", query: "from=yesterday&limit=-1", expectedStatus: http.StatusBadRequest, expectedFields: []string{"from", "limit"}},
+		{name: "empty range", query: "from=2024-03-02&to=2024-03-01", expectedStatus: http.StatusBadRequest, expectedFields: []string{"to"}},
+		{name: "database error", query: "format=ndjson", dbError: errors.New("database error"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := setupExportRouter(&MockDatabaseService{historyData: exportTestData(), historyError: tt.dbError})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/history/export?"+tt.query, nil))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+			assert.Empty(t, w.Header().Get("Content-Disposition"))
+
+			var response models.APIError
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			var fields []string
+			for _, detail := range response.Details {
+				fields = append(fields, detail.Field)
+			}
+			assert.Equal(t, tt.expectedFields, fields)
+		})
+	}
+}
+
+// failingStreamDatabase fails a stream after a number of rows
+type failingStreamDatabase struct {
+	MockDatabaseService
+	failAfter int
+}
+
+func (m *failingStreamDatabase) StreamWeatherHistory(ctx context.Context, filter models.HistoryFilter, fn func(*models.WeatherData) error) error {
+	for i := 0; i < m.failAfter; i++ {
+		data := models.WeatherData{ID: i + 1, City: "London", Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+		if err := fn(&data); err != nil {
+			return err
+		}
+	}
+	return errors.New("database error")
+}
+
+func TestWeatherHandler_ExportWeatherHistoryFailsMidStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	dbService := &failingStreamDatabase{failAfter: exportFlushRows + 10}
+	r.GET("/api/history/export", NewWeatherHandler(&MockWeatherService{}, dbService).ExportWeatherHistory)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	for _, format := range []string{models.FormatCSV, models.FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			resp, err := http.Get(server.URL + "/api/history/export?format=" + format)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			// The first rows were flushed with a 200, so the failure must
+			// show up as a broken body rather than a clean end
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			assert.NotEmpty(t, body)
+		})
+	}
+}
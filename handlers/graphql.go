@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	"weather-dashboard/models"
+	"weather-dashboard/utils"
+)
+
+// graphQLDefaultHistoryLimit is the number of observations returned by the
+// history field without a limit argument
+const graphQLDefaultHistoryLimit = 10
+
+// GraphQLHandler serves a GraphQL schema over the weather and database
+// services, so clients can fetch current weather, forecasts, search results
+// and history for several cities in one request
+type GraphQLHandler struct {
+	weatherService WeatherServiceInterface
+	dbService      DatabaseServiceInterface
+	limits         GraphQLLimits
+	schema         graphql.Schema
+}
+
+// NewGraphQLHandler creates a new GraphQL handler
+func NewGraphQLHandler(weatherService WeatherServiceInterface, dbService DatabaseServiceInterface, limits GraphQLLimits) *GraphQLHandler {
+	h := &GraphQLHandler{
+		weatherService: weatherService,
+		dbService:      dbService,
+		limits:         limits,
+	}
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: h.queryType()})
+	if err != nil {
+		// The schema is fixed at compile time, so this is a programming error
+		panic(fmt.Sprintf("invalid GraphQL schema: %v", err))
+	}
+	h.schema = schema
+	return h
+}
+
+// Query handles POST /graphql. Documents that cannot run, because they are
+// malformed, invalid or exceed the limits, get a 400 response; everything
+// else gets a 200 response with any field errors listed alongside the data.
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxGraphQLBytes)
+
+	var req models.GraphQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondGraphQLError(c, models.ErrorCodeInvalidInput, "invalid request body: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		respondGraphQLError(c, models.ErrorCodeInvalidInput, "query is required")
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.GraphQLResponse{
+			Errors: graphQLErrors(models.ErrorCodeInvalidQuery, gqlerrors.FormatError(err)),
+		})
+		return
+	}
+
+	if validation := graphql.ValidateDocument(&h.schema, doc, nil); !validation.IsValid {
+		c.JSON(http.StatusBadRequest, models.GraphQLResponse{
+			Errors: graphQLErrors(models.ErrorCodeInvalidQuery, validation.Errors...),
+		})
+		return
+	}
+
+	operation, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		respondGraphQLError(c, models.ErrorCodeInvalidQuery, err.Error())
+		return
+	}
+
+	if err := h.limits.check(doc, operation, req.Variables); err != nil {
+		respondGraphQLError(c, models.ErrorCodeQueryTooComplex, err.Error())
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withGraphQLLoaders(c.Request.Context(), h),
+	})
+
+	c.JSON(http.StatusOK, models.GraphQLResponse{
+		Data:   result.Data,
+		Errors: graphQLErrors(models.ErrorCodeInternal, result.Errors...),
+	})
+}
+
+// selectOperation returns the operation of doc named by operationName, which
+// may be omitted when doc has a single operation
+func selectOperation(doc *ast.Document, operationName string) (*ast.OperationDefinition, error) {
+	var operations []*ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		if operation, ok := definition.(*ast.OperationDefinition); ok {
+			if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+				operations = append(operations, operation)
+			}
+		}
+	}
+
+	switch {
+	case len(operations) == 1:
+		return operations[0], nil
+	case operationName != "":
+		return nil, fmt.Errorf("unknown operation %q", operationName)
+	case len(operations) == 0:
+		return nil, errors.New("document has no operation")
+	default:
+		return nil, errors.New("operationName is required when the document has several operations")
+	}
+}
+
+// respondGraphQLError writes a 400 response with a single GraphQL error
+func respondGraphQLError(c *gin.Context, code, message string) {
+	c.JSON(http.StatusBadRequest, models.GraphQLResponse{
+		Errors: []models.GraphQLError{{Message: message, Extensions: map[string]interface{}{"code": code}}},
+	})
+}
+
+// graphQLErrors converts GraphQL errors for the response. Errors raised by
+// resolvers already carry a code; the others get defaultCode.
+func graphQLErrors(defaultCode string, errs ...gqlerrors.FormattedError) []models.GraphQLError {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	converted := make([]models.GraphQLError, len(errs))
+	for i, err := range errs {
+		converted[i] = models.GraphQLError{
+			Message:    err.Message,
+			Path:       err.Path,
+			Extensions: err.Extensions,
+		}
+		if converted[i].Extensions == nil {
+			converted[i].Extensions = map[string]interface{}{"code": defaultCode}
+		}
+		for _, location := range err.Locations {
+			converted[i].Locations = append(converted[i].Locations, models.GraphQLErrorLocation{Line: location.Line, Column: location.Column})
+		}
+	}
+	return converted
+}
+
+// graphQLFieldError is a resolver error exposing the same message and code
+// as the REST API would for err
+type graphQLFieldError struct {
+	err error
+}
+
+func (e graphQLFieldError) Error() string {
+	return errorMessage(e.err)
+}
+
+func (e graphQLFieldError) Extensions() map[string]interface{} {
+	_, code := errorStatus(e.err)
+	return map[string]interface{}{"code": code}
+}
+
+// invalidArguments returns the error for resolver arguments failing validation
+func invalidArguments(problems []models.FieldError) error {
+	return graphQLFieldError{fmt.Errorf("%w: %s", models.ErrInvalidInput, describeProblems(problems))}
+}
+
+// deferred adapts a loader result for the executor, which resolves it only
+// after every field of the current level has queued its keys. Errors are
+// raised by panicking, because graphql-go keeps the code of errors it
+// recovers but drops it from errors returned by deferred resolvers.
+func deferred[V any](result func() (V, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		value, err := result()
+		if err != nil {
+			panic(graphQLFieldError{err})
+		}
+		return value, nil
+	}
+}
+
+// forecastKey identifies a forecast lookup
+type forecastKey struct {
+	city string
+	days int
+}
+
+// coordinatesKey identifies a lookup by coordinates in canonical form
+type coordinatesKey struct {
+	lat, lon string
+}
+
+// graphQLLoaders batches the upstream lookups of a single request
+type graphQLLoaders struct {
+	current     *batchLoader[string, *models.WeatherData]
+	coordinates *batchLoader[coordinatesKey, *models.WeatherData]
+	forecast    *batchLoader[forecastKey, *models.Forecast]
+	search      *batchLoader[string, []models.WeatherAPISearchResult]
+}
+
+type graphQLLoadersKey struct{}
+
+// withGraphQLLoaders returns ctx carrying fresh loaders for one request
+func withGraphQLLoaders(ctx context.Context, h *GraphQLHandler) context.Context {
+	loaders := &graphQLLoaders{
+		current: newBatchLoader(ctx, models.BatchWorkers, func(ctx context.Context, city string) (*models.WeatherData, error) {
+			weatherData, err := h.weatherService.GetWeatherByCity(ctx, city)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to fetch weather", "city", city, "error", err)
+				return nil, err
+			}
+			saveWeatherData(ctx, h.dbService, weatherData)
+			return weatherData, nil
+		}),
+		coordinates: newBatchLoader(ctx, models.BatchWorkers, func(ctx context.Context, key coordinatesKey) (*models.WeatherData, error) {
+			weatherData, err := h.weatherService.GetWeatherByCoordinates(ctx, key.lat, key.lon)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to fetch weather by coordinates", "lat", key.lat, "lon", key.lon, "error", err)
+				return nil, err
+			}
+			saveWeatherData(ctx, h.dbService, weatherData)
+			return weatherData, nil
+		}),
+		forecast: newBatchLoader(ctx, models.BatchWorkers, func(ctx context.Context, key forecastKey) (*models.Forecast, error) {
+			forecast, err := h.weatherService.GetForecast(ctx, key.city, key.days)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to fetch forecast", "city", key.city, "error", err)
+			}
+			return forecast, err
+		}),
+		search: newBatchLoader(ctx, models.BatchWorkers, func(ctx context.Context, query string) ([]models.WeatherAPISearchResult, error) {
+			results, err := h.weatherService.SearchCity(ctx, query)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to search cities", "query", query, "error", err)
+				return nil, err
+			}
+			if results == nil {
+				results = []models.WeatherAPISearchResult{}
+			}
+			return results, nil
+		}),
+	}
+	return context.WithValue(ctx, graphQLLoadersKey{}, loaders)
+}
+
+// loadersFrom returns the loaders of the request being resolved
+func loadersFrom(ctx context.Context) *graphQLLoaders {
+	return ctx.Value(graphQLLoadersKey{}).(*graphQLLoaders)
+}
+
+// graphQLCity is the source of City objects
+type graphQLCity struct {
+	Name string `json:"name"`
+}
+
+// queryType builds the root query type and the types it returns
+func (h *GraphQLHandler) queryType() *graphql.Object {
+	weatherType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Weather",
+		Description: "Weather observed at a location",
+		Fields: graphql.Fields{
+			"id":             &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "ID of the stored observation, 0 if not stored"},
+			"city":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"country":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"state":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"temperature":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Description: "Temperature in °C"},
+			"description":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"humidity":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Relative humidity in percent"},
+			"icon":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"condition_code": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"timestamp":      &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"cached":         &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "Served from the database instead of the weather provider"},
+			"stale":          &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "Served from the database because the weather provider is unavailable"},
+			"age_seconds":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Age of a cached or stale reading"},
+		},
+	})
+
+	forecastDayType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ForecastDay",
+		Fields: graphql.Fields{
+			"date":            &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "Local date, YYYY-MM-DD"},
+			"min_temperature": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"max_temperature": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"avg_temperature": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"humidity":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"chance_of_rain":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"description":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"icon":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"condition_code":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	forecastType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Forecast",
+		Description: "Daily forecast for a location",
+		Fields: graphql.Fields{
+			"city":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"country": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"state":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"days":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(forecastDayType)))},
+		},
+	})
+
+	locationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Location",
+		Description: "A city matching a search",
+		Fields: graphql.Fields{
+			"name":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"region":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"country": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"lat":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"lon":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+
+	daysArg := &graphql.ArgumentConfig{
+		Type:         graphql.Int,
+		DefaultValue: models.DefaultForecastDays,
+		Description:  fmt.Sprintf("Number of days, 1 to %d", models.MaxForecastDays),
+	}
+
+	cityType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "City",
+		Description: "A city with its current weather and forecast, looked up on demand",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"current": &graphql.Field{
+				Type: weatherType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return deferred(loadersFrom(p.Context).current.load(p.Source.(graphQLCity).Name)), nil
+				},
+			},
+			"forecast": &graphql.Field{
+				Type: forecastType,
+				Args: graphql.FieldConfigArgument{"days": daysArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveForecast(p, p.Source.(graphQLCity).Name)
+				},
+			},
+		},
+	})
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"current": &graphql.Field{
+				Type:        weatherType,
+				Description: "Current weather for a city",
+				Args: graphql.FieldConfigArgument{
+					"city": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					city, problems := validateCity("city", p.Args["city"].(string))
+					if len(problems) > 0 {
+						return nil, invalidArguments(problems)
+					}
+					return deferred(loadersFrom(p.Context).current.load(city)), nil
+				},
+			},
+			"current_at": &graphql.Field{
+				Type:        weatherType,
+				Description: "Current weather at a latitude and longitude",
+				Args: graphql.FieldConfigArgument{
+					"lat": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)},
+					"lon": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					lat, lon := p.Args["lat"].(float64), p.Args["lon"].(float64)
+					var problems []models.FieldError
+					if err := utils.CheckCoordinateRange(lat, utils.MaxLatitude); err != nil {
+						problems = append(problems, models.FieldError{Field: "lat", Message: err.Error()})
+					}
+					if err := utils.CheckCoordinateRange(lon, utils.MaxLongitude); err != nil {
+						problems = append(problems, models.FieldError{Field: "lon", Message: err.Error()})
+					}
+					if len(problems) > 0 {
+						return nil, invalidArguments(problems)
+					}
+					key := coordinatesKey{lat: utils.FormatCoordinate(lat), lon: utils.FormatCoordinate(lon)}
+					return deferred(loadersFrom(p.Context).coordinates.load(key)), nil
+				},
+			},
+			"forecast": &graphql.Field{
+				Type:        forecastType,
+				Description: "Daily forecast for a city",
+				Args: graphql.FieldConfigArgument{
+					"city": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"days": daysArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					city, problems := validateCity("city", p.Args["city"].(string))
+					if len(problems) > 0 {
+						return nil, invalidArguments(problems)
+					}
+					return resolveForecast(p, city)
+				},
+			},
+			"search": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(locationType))),
+				Description: "Cities matching a name",
+				Args: graphql.FieldConfigArgument{
+					"query": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					query, problems := validateCity("query", p.Args["query"].(string))
+					if len(problems) > 0 {
+						return nil, invalidArguments(problems)
+					}
+					return deferred(loadersFrom(p.Context).search.load(query)), nil
+				},
+			},
+			"history": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(weatherType))),
+				Description: "Most recent stored observations, newest first",
+				Args: graphql.FieldConfigArgument{
+					"limit": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: graphQLDefaultHistoryLimit,
+						Description:  fmt.Sprintf("Number of observations, 1 to %d", models.MaxHistoryLimit),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit, ok := p.Args["limit"].(int)
+					if !ok {
+						limit = graphQLDefaultHistoryLimit
+					}
+					if problems := validateIntRange("limit", limit, 1, models.MaxHistoryLimit); len(problems) > 0 {
+						return nil, invalidArguments(problems)
+					}
+					history, err := h.dbService.GetWeatherHistory(p.Context, limit)
+					if err != nil {
+						slog.ErrorContext(p.Context, "failed to fetch weather history", "error", err)
+						return nil, graphQLFieldError{err}
+					}
+					return history, nil
+				},
+			},
+			"cities": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(cityType))),
+				Description: fmt.Sprintf("Several cities at once, such as a user's favorites, at most %d. Their lookups are made concurrently.", models.MaxBatchSize),
+				Args: graphql.FieldConfigArgument{
+					"names": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					names, _ := p.Args["names"].([]interface{})
+					if len(names) > models.MaxBatchSize {
+						return nil, invalidArguments([]models.FieldError{{Field: "names", Message: fmt.Sprintf("must not list more than %d cities", models.MaxBatchSize)}})
+					}
+
+					cities := make([]graphQLCity, len(names))
+					var problems []models.FieldError
+					for i, name := range names {
+						city, cityProblems := validateCity(fmt.Sprintf("names[%d]", i), name.(string))
+						problems = append(problems, cityProblems...)
+						cities[i] = graphQLCity{Name: city}
+					}
+					if len(problems) > 0 {
+						return nil, invalidArguments(problems)
+					}
+					return cities, nil
+				},
+			},
+		},
+	})
+}
+
+// resolveForecast queues a forecast lookup for city using the days argument
+func resolveForecast(p graphql.ResolveParams, city string) (interface{}, error) {
+	days, ok := p.Args["days"].(int)
+	if !ok {
+		days = models.DefaultForecastDays
+	}
+	if problems := validateIntRange("days", days, 1, models.MaxForecastDays); len(problems) > 0 {
+		return nil, invalidArguments(problems)
+	}
+	return deferred(loadersFrom(p.Context).forecast.load(forecastKey{city: city, days: days})), nil
+}
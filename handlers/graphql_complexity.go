@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// graphQLUpstreamCost is the cost of a field that calls the weather provider.
+// Every other field costs 1.
+const graphQLUpstreamCost = 10
+
+// graphQLFieldCosts lists the fields resolved with an upstream call
+var graphQLFieldCosts = map[string]int{
+	"current":    graphQLUpstreamCost,
+	"current_at": graphQLUpstreamCost,
+	"forecast":   graphQLUpstreamCost,
+	"search":     graphQLUpstreamCost,
+}
+
+// graphQLListSizes maps list fields to the argument giving the number of
+// items they return, and the size assumed when it is omitted. The cost of
+// their selections is multiplied by that size.
+var graphQLListSizes = map[string]struct {
+	arg string
+	def int
+}{
+	"cities":  {arg: "names"},
+	"history": {arg: "limit", def: graphQLDefaultHistoryLimit},
+}
+
+// GraphQLLimits bounds the queries accepted by the GraphQL endpoint
+type GraphQLLimits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// check measures operation, an operation of doc, and reports the first limit
+// it exceeds. The document must already be valid.
+func (l GraphQLLimits) check(doc *ast.Document, operation *ast.OperationDefinition, variables map[string]interface{}) error {
+	depth, complexity := l.measure(doc, operation, variables)
+	if depth > l.MaxDepth {
+		return fmt.Errorf("query depth exceeds the limit of %d", l.MaxDepth)
+	}
+	if complexity > l.MaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, l.MaxComplexity)
+	}
+	return nil
+}
+
+// measure returns the depth and complexity of operation. Once either
+// exceeds its limit the walk stops, and the values returned are only known
+// to be over the limit.
+func (l GraphQLLimits) measure(doc *ast.Document, operation *ast.OperationDefinition, variables map[string]interface{}) (depth, complexity int) {
+	m := &queryMeasure{
+		limits:    l,
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+		defaults:  make(map[string]ast.Value),
+		measured:  make(map[fragmentDepth]fragmentMeasure),
+	}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			m.fragments[fragment.Name.Value] = fragment
+		}
+	}
+	for _, definition := range operation.VariableDefinitions {
+		if definition.DefaultValue != nil {
+			m.defaults[definition.Variable.Name.Value] = definition.DefaultValue
+		}
+	}
+
+	return m.selectionSet(operation.SelectionSet, 0)
+}
+
+// queryMeasure walks a GraphQL document computing its depth and complexity
+type queryMeasure struct {
+	limits    GraphQLLimits
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	defaults  map[string]ast.Value
+	measured  map[fragmentDepth]fragmentMeasure
+}
+
+// fragmentDepth identifies a fragment spread at a given depth
+type fragmentDepth struct {
+	name  string
+	depth int
+}
+
+// fragmentMeasure holds the measure of a fragment spread
+type fragmentMeasure struct {
+	maxDepth, cost int
+}
+
+// selectionSet returns the deepest field level and the total cost of set,
+// whose fields are at depth+1. Fragments count as if written inline.
+// Introspection fields are free, as they never reach the database or the
+// weather provider. The walk stops as soon as a limit is exceeded.
+func (m *queryMeasure) selectionSet(set *ast.SelectionSet, depth int) (maxDepth, cost int) {
+	maxDepth = depth
+	if set == nil {
+		return maxDepth, 0
+	}
+
+	for _, selection := range set.Selections {
+		if maxDepth > m.limits.MaxDepth || cost > m.limits.MaxComplexity {
+			break
+		}
+
+		var childDepth, childCost int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			name := selection.Name.Value
+			if strings.HasPrefix(name, "__") {
+				continue
+			}
+			fieldCost, ok := graphQLFieldCosts[name]
+			if !ok {
+				fieldCost = 1
+			}
+			childDepth, childCost = m.selectionSet(selection.SelectionSet, depth+1)
+			childCost = fieldCost + m.listSize(selection)*childCost
+		case *ast.FragmentSpread:
+			childDepth, childCost = m.fragment(selection.Name.Value, depth)
+		case *ast.InlineFragment:
+			childDepth, childCost = m.selectionSet(selection.SelectionSet, depth)
+		}
+
+		maxDepth = max(maxDepth, childDepth)
+		cost += childCost
+	}
+	return maxDepth, cost
+}
+
+// fragment measures the named fragment spread at depth. Results are
+// remembered, so fragments spread many times are walked once per depth.
+func (m *queryMeasure) fragment(name string, depth int) (maxDepth, cost int) {
+	key := fragmentDepth{name: name, depth: depth}
+	if measured, ok := m.measured[key]; ok {
+		return measured.maxDepth, measured.cost
+	}
+
+	maxDepth = depth
+	if fragment := m.fragments[name]; fragment != nil {
+		maxDepth, cost = m.selectionSet(fragment.SelectionSet, depth)
+	}
+	m.measured[key] = fragmentMeasure{maxDepth: maxDepth, cost: cost}
+	return maxDepth, cost
+}
+
+// listSize returns the number of items field is expected to return, or 1
+// for fields that are not lists
+func (m *queryMeasure) listSize(field *ast.Field) int {
+	size, ok := graphQLListSizes[field.Name.Value]
+	if !ok {
+		return 1
+	}
+
+	for _, argument := range field.Arguments {
+		if argument.Name.Value == size.arg {
+			if n, ok := m.count(argument.Value); ok {
+				return max(n, 0)
+			}
+		}
+	}
+	return size.def
+}
+
+// count returns the length of a list argument or the value of an integer
+// one, looking variables up in the request and then in their defaults. A
+// single value given for a list counts as a list of one.
+func (m *queryMeasure) count(value ast.Value) (int, bool) {
+	switch value := value.(type) {
+	case *ast.ListValue:
+		return len(value.Values), true
+	case *ast.IntValue:
+		n, err := strconv.Atoi(value.Value)
+		return n, err == nil
+	case *ast.Variable:
+		v, ok := m.variables[value.Name.Value]
+		if !ok {
+			if def := m.defaults[value.Name.Value]; def != nil {
+				return m.count(def)
+			}
+		}
+		switch v := v.(type) {
+		case nil:
+			return 0, false
+		case []interface{}:
+			return len(v), true
+		case float64:
+			return int(v), true
+		case int:
+			return v, true
+		}
+	}
+	return 1, true
+}
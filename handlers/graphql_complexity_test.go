@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQLLimits_measure(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		variables  map[string]interface{}
+		depth      int
+		complexity int
+	}{
+		{
+			name:       "upstream field",
+			query:      `{ current(city: "London") { city temperature } }`,
+			depth:      2,
+			complexity: 12,
+		},
+		{
+			name:       "list of cities multiplies its selections",
+			query:      `{ cities(names: ["London", "Paris"]) { name current { temperature } forecast(days: 2) { days { date } } } }`,
+			depth:      4,
+			complexity: 1 + 2*(1+11+12),
+		},
+		{
+			name:       "single name counts as a list of one",
+			query:      `{ cities(names: "London") { name } }`,
+			depth:      2,
+			complexity: 2,
+		},
+		{
+			name:       "list size from a variable",
+			query:      `query($names: [String!]!) { cities(names: $names) { name } }`,
+			variables:  map[string]interface{}{"names": []interface{}{"London", "Paris", "Rome"}},
+			depth:      2,
+			complexity: 4,
+		},
+		{
+			name:       "limit from a variable default",
+			query:      `query($n: Int = 50) { history(limit: $n) { id city } }`,
+			depth:      2,
+			complexity: 101,
+		},
+		{
+			name:       "default history limit",
+			query:      `{ history { id } }`,
+			depth:      2,
+			complexity: 1 + graphQLDefaultHistoryLimit,
+		},
+		{
+			name: "fragments count as inline",
+			query: `{ ...Current ... on Query { search(query: "Lon") { name } } }
+				fragment Current on Query { current(city: "London") { ...Fields } }
+				fragment Fields on Weather { city humidity }`,
+			depth:      2,
+			complexity: 12 + 11,
+		},
+		{
+			name:       "introspection is free",
+			query:      `{ __typename __schema { types { name fields { name type { name ofType { name } } } } } }`,
+			depth:      0,
+			complexity: 0,
+		},
+	}
+
+	limits := GraphQLLimits{MaxDepth: 100, MaxComplexity: 10000}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			require.NoError(t, err)
+			operation, err := selectOperation(doc, "")
+			require.NoError(t, err)
+
+			depth, complexity := limits.measure(doc, operation, tt.variables)
+			assert.Equal(t, tt.depth, depth)
+			assert.Equal(t, tt.complexity, complexity)
+		})
+	}
+}
+
+func TestGraphQLLimits_checkFragmentExpansion(t *testing.T) {
+	// Each fragment spreads the next twice: 2^40 fields in all
+	query := `{ ...F0 }`
+	for i := 0; i < 40; i++ {
+		query += fmt.Sprintf(" fragment F%d on Query { ...F%d ...F%d }", i, i+1, i+1)
+	}
+	query += ` fragment F40 on Query { __typename }`
+
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	require.NoError(t, err)
+	operation, err := selectOperation(doc, "")
+	require.NoError(t, err)
+
+	limits := GraphQLLimits{MaxDepth: 10, MaxComplexity: 100}
+	assert.NoError(t, limits.check(doc, operation, nil))
+
+	doc, err = parser.Parse(parser.ParseParams{Source: strings.Replace(query, "__typename", "history(limit: 1) { id }", 1)})
+	require.NoError(t, err)
+	operation, err = selectOperation(doc, "")
+	require.NoError(t, err)
+	assert.ErrorContains(t, limits.check(doc, operation, nil), "exceeds the limit of 100")
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errLoadPending is returned for a result read while another call is still
+// fetching its batch
+var errLoadPending = errors.New("batched lookup has not completed")
+
+// batchLoader collects the keys requested while one level of a GraphQL
+// query resolves and fetches them together, once, when the first of their
+// results is needed. Duplicate keys share a single fetch. The executor
+// resolves every field of a level before completing any of the deferred
+// results, so a list of cities turns into one round of concurrent upstream
+// calls instead of a call per field, one after another.
+type batchLoader[K comparable, V any] struct {
+	ctx     context.Context
+	fetch   func(ctx context.Context, key K) (V, error)
+	workers int
+
+	mu      sync.Mutex
+	pending []K
+	results map[K]*loadResult[V]
+}
+
+// loadResult holds the outcome of fetching one key
+type loadResult[V any] struct {
+	value V
+	err   error
+}
+
+// newBatchLoader creates a loader fetching at most workers keys at a time
+func newBatchLoader[K comparable, V any](ctx context.Context, workers int, fetch func(ctx context.Context, key K) (V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{
+		ctx:     ctx,
+		fetch:   fetch,
+		workers: workers,
+		results: make(map[K]*loadResult[V]),
+	}
+}
+
+// load queues key and returns a function waiting for its result
+func (l *batchLoader[K, V]) load(key K) func() (V, error) {
+	l.mu.Lock()
+	if _, ok := l.results[key]; !ok {
+		l.results[key] = nil
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.dispatch()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		result := l.results[key]
+		if result == nil {
+			var zero V
+			return zero, errLoadPending
+		}
+		return result.value, result.err
+	}
+}
+
+// dispatch fetches every queued key using a fixed pool of workers
+func (l *batchLoader[K, V]) dispatch() {
+	l.mu.Lock()
+	keys := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	if len(keys) == 0 {
+		return
+	}
+
+	results := make([]loadResult[V], len(keys))
+	jobs := make(chan int)
+	workers := l.workers
+	if workers > len(keys) {
+		workers = len(keys)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := l.ctx.Err(); err != nil {
+					results[i].err = err
+					continue
+				}
+				results[i].value, results[i].err = l.fetch(l.ctx, keys[i])
+			}
+		}()
+	}
+
+	for i := range keys {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	l.mu.Lock()
+	for i, key := range keys {
+		l.results[key] = &results[i]
+	}
+	l.mu.Unlock()
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchLoader(t *testing.T) {
+	t.Run("duplicate keys fetched once", func(t *testing.T) {
+		calls := 0
+		loader := newBatchLoader(context.Background(), 2, func(ctx context.Context, key string) (int, error) {
+			calls++
+			return len(key), nil
+		})
+
+		first, second := loader.load("London"), loader.load("London")
+		value, err := first()
+		require.NoError(t, err)
+		assert.Equal(t, 6, value)
+		value, err = second()
+		require.NoError(t, err)
+		assert.Equal(t, 6, value)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("result read while its batch is fetched", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		loader := newBatchLoader(context.Background(), 1, func(ctx context.Context, key string) (int, error) {
+			close(started)
+			<-release
+			return len(key), nil
+		})
+
+		first, second := loader.load("Paris"), loader.load("Paris")
+		done := make(chan int)
+		go func() {
+			value, _ := first()
+			done <- value
+		}()
+		<-started
+
+		_, err := second()
+		assert.ErrorIs(t, err, errLoadPending)
+
+		close(release)
+		assert.Equal(t, 5, <-done)
+	})
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"weather-dashboard/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingWeatherService records upstream lookups and how many ran at once
+type countingWeatherService struct {
+	MockWeatherService
+	cities map[string]*models.WeatherData
+
+	mu          sync.Mutex
+	calls       []string
+	inFlight    int
+	maxInFlight int
+}
+
+func (m *countingWeatherService) track(call string) func() {
+	m.mu.Lock()
+	m.calls = append(m.calls, call)
+	m.inFlight++
+	m.maxInFlight = max(m.maxInFlight, m.inFlight)
+	m.mu.Unlock()
+
+	// Give the other lookups of the batch time to start
+	time.Sleep(20 * time.Millisecond)
+
+	return func() {
+		m.mu.Lock()
+		m.inFlight--
+		m.mu.Unlock()
+	}
+}
+
+func (m *countingWeatherService) GetWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
+	defer m.track("current " + city)()
+	if data, ok := m.cities[strings.ToLower(city)]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("city %w: %s", models.ErrNotFound, city)
+}
+
+func (m *countingWeatherService) GetForecast(ctx context.Context, city string, days int) (*models.Forecast, error) {
+	defer m.track(fmt.Sprintf("forecast %s %d", city, days))()
+	return m.MockWeatherService.GetForecast(ctx, city, days)
+}
+
+func postGraphQL(t *testing.T, r *gin.Engine, body string) (*httptest.ResponseRecorder, models.GraphQLResponse) {
+	req, err := http.NewRequest("POST", "/graphql", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var response models.GraphQLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), w.Body.String())
+	return w, response
+}
+
+// graphQLRequest encodes a GraphQL request body
+func graphQLRequest(query string, variables map[string]interface{}) string {
+	body, _ := json.Marshal(models.GraphQLRequest{Query: query, Variables: variables})
+	return string(body)
+}
+
+func setupGraphQLRouter(handler *GraphQLHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/graphql", handler.Query)
+	return r
+}
+
+func TestGraphQLHandler_Query(t *testing.T) {
+	weatherService := &countingWeatherService{
+		cities: map[string]*models.WeatherData{
+			"london": {City: "London", Country: "United Kingdom", Temperature: 15.5, Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		},
+	}
+	dbService := &MockDatabaseService{
+		historyData: []models.WeatherData{{ID: 7, City: "Paris", Temperature: 18.2}},
+	}
+	r := setupGraphQLRouter(NewGraphQLHandler(weatherService, dbService, GraphQLLimits{MaxDepth: 4, MaxComplexity: 100}))
+
+	tests := []struct {
+		name      string
+		body      string
+		status    int
+		data      string
+		errorCode string
+		errorPath []interface{}
+	}{
+		{
+			name:   "current weather",
+			body:   graphQLRequest(`{ current(city: " london ") { city temperature timestamp } }`, nil),
+			status: http.StatusOK,
+			data:   `{"current":{"city":"London","temperature":15.5,"timestamp":"2024-03-01T12:00:00Z"}}`,
+		},
+		{
+			name:   "forecast with days",
+			body:   graphQLRequest(`query($days: Int) { forecast(city: "London", days: $days) { city days { date } } }`, map[string]interface{}{"days": 2}),
+			status: http.StatusOK,
+			data:   `{"forecast":{"city":"London","days":[{"date":"2024-03-01"},{"date":"2024-03-02"}]}}`,
+		},
+		{
+			name:   "history with limit",
+			body:   graphQLRequest(`{ history(limit: 5) { id city } }`, nil),
+			status: http.StatusOK,
+			data:   `{"history":[{"id":7,"city":"Paris"}]}`,
+		},
+		{
+			name:      "unknown city fails only its field",
+			body:      graphQLRequest(`{ cities(names: ["London", "Atlantis"]) { name current { city } } }`, nil),
+			status:    http.StatusOK,
+			data:      `{"cities":[{"name":"London","current":{"city":"London"}},{"name":"Atlantis","current":null}]}`,
+			errorCode: models.ErrorCodeNotFound,
+			errorPath: []interface{}{"cities", float64(1), "current"},
+		},
+		{
+			name:      "invalid argument",
+			body:      graphQLRequest(`{ forecast(city: "London", days: 15) { city } }`, nil),
+			status:    http.StatusOK,
+			data:      `{"forecast":null}`,
+			errorCode: models.ErrorCodeInvalidInput,
+			errorPath: []interface{}{"forecast"},
+		},
+		{
+			name:      "syntax error",
+			body:      graphQLRequest(`{ current(city: "London") { city }`, nil),
+			status:    http.StatusBadRequest,
+			errorCode: models.ErrorCodeInvalidQuery,
+		},
+		{
+			name:      "unknown field",
+			body:      graphQLRequest(`{ current(city: "London") { wind } }`, nil),
+			status:    http.StatusBadRequest,
+			errorCode: models.ErrorCodeInvalidQuery,
+		},
+		{
+			name:      "unknown operation",
+			body:      `{"query":"query A { history { id } }","operationName":"B"}`,
+			status:    http.StatusBadRequest,
+			errorCode: models.ErrorCodeInvalidQuery,
+		},
+		{
+			name:      "too complex",
+			body:      graphQLRequest(`{ cities(names: ["A", "B", "C", "D", "E"]) { current { city } forecast { city } } }`, nil),
+			status:    http.StatusBadRequest,
+			errorCode: models.ErrorCodeQueryTooComplex,
+		},
+		{
+			name:   "nested to the depth limit",
+			body:   graphQLRequest(`{ cities(names: ["London"]) { forecast { days { date } } } }`, nil),
+			status: http.StatusOK,
+			data:   `{"cities":[{"forecast":{"days":[{"date":"2024-03-01"},{"date":"2024-03-02"},{"date":"2024-03-03"}]}}]}`,
+		},
+		{
+			name:      "missing query",
+			body:      `{"variables":{}}`,
+			status:    http.StatusBadRequest,
+			errorCode: models.ErrorCodeInvalidInput,
+		},
+		{
+			name:      "malformed body",
+			body:      `{"query":`,
+			status:    http.StatusBadRequest,
+			errorCode: models.ErrorCodeInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, response := postGraphQL(t, r, tt.body)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+
+			if tt.data != "" {
+				data, err := json.Marshal(response.Data)
+				require.NoError(t, err)
+				assert.JSONEq(t, tt.data, string(data))
+			} else {
+				assert.Nil(t, response.Data)
+			}
+
+			if tt.errorCode == "" {
+				assert.Empty(t, response.Errors)
+				return
+			}
+			require.Len(t, response.Errors, 1)
+			assert.Equal(t, tt.errorCode, response.Errors[0].Extensions["code"])
+			assert.Equal(t, tt.errorPath, response.Errors[0].Path)
+		})
+	}
+
+	assert.Equal(t, 5, dbService.historyLimit)
+}
+
+func TestGraphQLHandler_QueryDepthLimit(t *testing.T) {
+	r := setupGraphQLRouter(NewGraphQLHandler(&countingWeatherService{}, &MockDatabaseService{}, GraphQLLimits{MaxDepth: 3, MaxComplexity: 100}))
+
+	w, response := postGraphQL(t, r, graphQLRequest(`{ cities(names: ["London"]) { forecast { days { date } } } }`, nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, models.ErrorCodeQueryTooComplex, response.Errors[0].Extensions["code"])
+	assert.Contains(t, response.Errors[0].Message, "depth")
+}
+
+func TestGraphQLHandler_Batching(t *testing.T) {
+	weatherService := &countingWeatherService{
+		cities: map[string]*models.WeatherData{
+			"london": {City: "London"},
+			"paris":  {City: "Paris"},
+			"rome":   {City: "Rome"},
+		},
+	}
+	r := setupGraphQLRouter(NewGraphQLHandler(weatherService, &MockDatabaseService{}, GraphQLLimits{MaxDepth: 8, MaxComplexity: 500}))
+
+	query := `{
+		favorites: cities(names: ["London", "Paris", "Rome", "london"]) {
+			current { city }
+			forecast(days: 1) { city }
+		}
+		paris: current(city: "Paris") { temperature }
+	}`
+	w, response := postGraphQL(t, r, graphQLRequest(query, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, response.Errors)
+
+	// Duplicate cities are looked up once, and each level's lookups run
+	// concurrently
+	assert.ElementsMatch(t, []string{
+		"current London", "current Paris", "current Rome",
+		"forecast London 1", "forecast Paris 1", "forecast Rome 1",
+	}, weatherService.calls)
+	assert.Greater(t, weatherService.maxInFlight, 1)
+
+	favorites := response.Data.(map[string]interface{})["favorites"].([]interface{})
+	require.Len(t, favorites, 4)
+	assert.Equal(t, "London", favorites[3].(map[string]interface{})["current"].(map[string]interface{})["city"])
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"weather-dashboard/models"
+)
+
+// readyCheckTimeout bounds the time spent checking all components
+const readyCheckTimeout = 5 * time.Second
+
+type HealthCheckerInterface interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthHandler handles liveness and readiness probes
+type HealthHandler struct {
+	checkers map[string]HealthCheckerInterface
+}
+
+// NewHealthHandler creates a new health handler. Each checker is reported
+// as a named component by the readiness endpoint.
+func NewHealthHandler(checkers map[string]HealthCheckerInterface) *HealthHandler {
+	return &HealthHandler{
+		checkers: checkers,
+	}
+}
+
+// Healthz handles GET /healthz
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, models.HealthReport{Status: models.HealthStatusOK})
+}
+
+// Readyz handles GET /readyz
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := models.HealthReport{
+		Status:     models.HealthStatusOK,
+		Components: make(map[string]models.ComponentHealth, len(h.checkers)),
+	}
+
+	names := make([]string, 0, len(h.checkers))
+	for name := range h.checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readyCheckTimeout)
+	defer cancel()
+
+	for _, name := range names {
+		start := time.Now()
+		err := h.checkers[name].Ping(ctx)
+
+		component := models.ComponentHealth{
+			Status:    models.HealthStatusOK,
+			LatencyMs: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			component.Status = models.HealthStatusDown
+			component.Error = errorMessage(err)
+			report.Status = models.HealthStatusDegraded
+		}
+		report.Components[name] = component
+	}
+
+	status := http.StatusOK
+	if report.Status != models.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
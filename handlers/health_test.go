@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"weather-dashboard/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockHealthChecker struct {
+	err error
+}
+
+func (m *MockHealthChecker) Ping(ctx context.Context) error {
+	return m.err
+}
+
+func TestHealthHandler_Healthz(t *testing.T) {
+	handler := NewHealthHandler(map[string]HealthCheckerInterface{
+		"database": &MockHealthChecker{err: assert.AnError},
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/healthz", handler.Healthz)
+
+	req, err := http.NewRequest("GET", "/healthz", nil)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Liveness does not depend on components
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestHealthHandler_Readyz(t *testing.T) {
+	tests := []struct {
+		name           string
+		checkers       map[string]HealthCheckerInterface
+		expectedStatus int
+		expectedReport string
+		downComponents []string
+	}{
+		{
+			name: "all components healthy",
+			checkers: map[string]HealthCheckerInterface{
+				"database":   &MockHealthChecker{},
+				"weatherapi": &MockHealthChecker{},
+			},
+			expectedStatus: http.StatusOK,
+			expectedReport: models.HealthStatusOK,
+		},
+		{
+			name: "database down",
+			checkers: map[string]HealthCheckerInterface{
+				"database":   &MockHealthChecker{err: assert.AnError},
+				"weatherapi": &MockHealthChecker{},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReport: models.HealthStatusDegraded,
+			downComponents: []string{"database"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHealthHandler(tt.checkers)
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.GET("/readyz", handler.Readyz)
+
+			req, err := http.NewRequest("GET", "/readyz", nil)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var report models.HealthReport
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Equal(t, tt.expectedReport, report.Status)
+			assert.Len(t, report.Components, len(tt.checkers))
+
+			for _, name := range tt.downComponents {
+				assert.Equal(t, models.HealthStatusDown, report.Components[name].Status)
+				assert.Equal(t, assert.AnError.Error(), report.Components[name].Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"weather-dashboard/models"
+)
+
+type HistoryImporterInterface interface {
+	Import(ctx context.Context, r io.Reader, format string) (*models.ImportReport, error)
+}
+
+// importContentTypes maps request content types to import formats
+var importContentTypes = map[string]string{
+	"text/csv":             models.FormatCSV,
+	"application/x-ndjson": models.FormatNDJSON,
+	"application/jsonl":    models.FormatNDJSON,
+}
+
+// ImportHandler handles bulk imports of observations
+type ImportHandler struct {
+	importer HistoryImporterInterface
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(importer HistoryImporterInterface) *ImportHandler {
+	return &ImportHandler{importer: importer}
+}
+
+// ImportWeatherHistory handles POST /api/v1/history/import. The format is taken
+// from the format query parameter or else the Content-Type header. Invalid
+// lines are listed in the report; the valid ones are still imported.
+func (h *ImportHandler) ImportWeatherHistory(c *gin.Context) {
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		format = importContentTypes[mediaType]
+	}
+	if format != models.FormatCSV && format != models.FormatNDJSON {
+		respondValidationError(c, []models.FieldError{{Field: "format", Message: "must be csv or ndjson (or send Content-Type text/csv or application/x-ndjson)"}})
+		return
+	}
+
+	ctx := c.Request.Context()
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxImportBytes)
+	report, err := h.importer.Import(ctx, body, format)
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, models.APIError{
+			Error: fmt.Sprintf("import body exceeds %d bytes; nothing after the first %d records was imported", tooLarge.Limit, report.Total),
+			Code:  models.ErrorCodeInvalidInput,
+		})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to import weather history", "format", format, "imported", report.Imported, "error", err)
+		respondError(c, err)
+		return
+	}
+
+	slog.InfoContext(ctx, "imported weather history", "format", format,
+		"total", report.Total, "imported", report.Imported, "duplicates", report.Duplicates, "failed", report.Failed)
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"weather-dashboard/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockImporter records the format and body of the last import
+type MockImporter struct {
+	format string
+	body   string
+	report *models.ImportReport
+	err    error
+}
+
+func (m *MockImporter) Import(ctx context.Context, r io.Reader, format string) (*models.ImportReport, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return &models.ImportReport{}, err
+	}
+	m.format, m.body = format, string(body)
+	if m.report == nil {
+		return &models.ImportReport{}, m.err
+	}
+	return m.report, m.err
+}
+
+func TestImportHandler_ImportWeatherHistory(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		contentType    string
+		body           string
+		importer       *MockImporter
+		expectedStatus int
+		expectedFormat string
+		expectedCode   string
+	}{
+		{
+			name:           "csv by content type",
+			contentType:    "text/csv; charset=utf-8",
+			body:           "city,timestamp\n",
+			importer:       &MockImporter{report: &models.ImportReport{Total: 1, Imported: 1}},
+			expectedStatus: http.StatusOK,
+			expectedFormat: models.FormatCSV,
+		},
+		{
+			name:           "ndjson by query",
+			query:          "?format=ndjson",
+			contentType:    "application/octet-stream",
+			body:           "{}\n",
+			importer:       &MockImporter{report: &models.ImportReport{Total: 1, Failed: 1}},
+			expectedStatus: http.StatusOK,
+			expectedFormat: models.FormatNDJSON,
+		},
+		{
+			name:           "unknown format",
+			contentType:    "application/xml",
+			importer:       &MockImporter{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   models.ErrorCodeInvalidInput,
+		},
+		{
+			name:           "invalid input",
+			query:          "?format=csv",
+			importer:       &MockImporter{err: models.ErrInvalidInput},
+			expectedStatus: http.StatusBadRequest,
+			expectedFormat: models.FormatCSV,
+			expectedCode:   models.ErrorCodeInvalidInput,
+		},
+		{
+			name:           "store error",
+			query:          "?format=csv",
+			importer:       &MockImporter{err: errors.New("disk full")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedFormat: models.FormatCSV,
+			expectedCode:   models.ErrorCodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.POST("/api/history/import", NewImportHandler(tt.importer).ImportWeatherHistory)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/history/import"+tt.query, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedFormat, tt.importer.format)
+			if tt.expectedCode != "" {
+				var response models.APIError
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectedCode, response.Code)
+				return
+			}
+
+			assert.Equal(t, tt.body, tt.importer.body)
+			var report models.ImportReport
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Equal(t, *tt.importer.report, report)
+		})
+	}
+}
+
+// importerFunc adapts a function to HistoryImporterInterface
+type importerFunc func(ctx context.Context, r io.Reader, format string) (*models.ImportReport, error)
+
+func (f importerFunc) Import(ctx context.Context, r io.Reader, format string) (*models.ImportReport, error) {
+	return f(ctx, r, format)
+}
+
+// zeroReader is an endless stream of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestImportHandler_BodyTooLarge(t *testing.T) {
+	importer := importerFunc(func(ctx context.Context, r io.Reader, format string) (*models.ImportReport, error) {
+		_, err := io.Copy(io.Discard, r)
+		return &models.ImportReport{Total: 3, Imported: 3}, err
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/history/import", NewImportHandler(importer).ImportWeatherHistory)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/history/import?format=csv", io.LimitReader(zeroReader{}, models.MaxImportBytes+1))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	var response models.APIError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, models.ErrorCodeInvalidInput, response.Code)
+	assert.Contains(t, response.Error, "nothing after the first 3 records was imported")
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"weather-dashboard/models"
+	"weather-dashboard/utils"
+)
+
+// validateCity checks and normalizes a city name
+func validateCity(field, city string) (string, []models.FieldError) {
+	if err := utils.ValidateCityName(city); err != nil {
+		return "", []models.FieldError{{Field: field, Message: err.Error()}}
+	}
+	return utils.SanitizeCityName(city), nil
+}
+
+// validateCoordinates checks latitude and longitude path parameters and
+// returns them in canonical form
+func validateCoordinates(lat, lon string) (string, string, []models.FieldError) {
+	var problems []models.FieldError
+
+	parsedLat, err := utils.ParseLatitude(strings.TrimSpace(lat))
+	if err != nil {
+		problems = append(problems, models.FieldError{Field: "lat", Message: err.Error()})
+	}
+	parsedLon, err := utils.ParseLongitude(strings.TrimSpace(lon))
+	if err != nil {
+		problems = append(problems, models.FieldError{Field: "lon", Message: err.Error()})
+	}
+
+	if len(problems) > 0 {
+		return "", "", problems
+	}
+	return utils.FormatCoordinate(parsedLat), utils.FormatCoordinate(parsedLon), nil
+}
+
+// parseIntQuery reads an optional integer query parameter within
+// [min, max], returning def when it is absent
+func parseIntQuery(c *gin.Context, name string, def, min, max int) (int, []models.FieldError) {
+	value := strings.TrimSpace(c.Query(name))
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, []models.FieldError{intRangeError(name, min, max)}
+	}
+	return n, validateIntRange(name, n, min, max)
+}
+
+// validateIntRange checks that an integer parameter is within [min, max]
+func validateIntRange(name string, n, min, max int) []models.FieldError {
+	if n < min || n > max {
+		return []models.FieldError{intRangeError(name, min, max)}
+	}
+	return nil
+}
+
+// intRangeError describes an integer parameter outside [min, max]
+func intRangeError(name string, min, max int) models.FieldError {
+	return models.FieldError{Field: name, Message: fmt.Sprintf("must be an integer between %d and %d", min, max)}
+}
+
+// validateBatchItem checks a batch item and returns it normalized
+func validateBatchItem(item models.BatchWeatherItem) (models.BatchWeatherItem, []models.FieldError) {
+	if item.City != "" {
+		city, problems := validateCity("city", item.City)
+		return models.BatchWeatherItem{City: city}, problems
+	}
+
+	var problems []models.FieldError
+	if item.Lat != nil {
+		if err := utils.CheckCoordinateRange(*item.Lat, utils.MaxLatitude); err != nil {
+			problems = append(problems, models.FieldError{Field: "lat", Message: err.Error()})
+		}
+	}
+	if item.Lon != nil {
+		if err := utils.CheckCoordinateRange(*item.Lon, utils.MaxLongitude); err != nil {
+			problems = append(problems, models.FieldError{Field: "lon", Message: err.Error()})
+		}
+	}
+	return item, problems
+}
+
+// describeProblems summarizes field errors in a single message
+func describeProblems(problems []models.FieldError) string {
+	messages := make([]string, len(problems))
+	for i, problem := range problems {
+		messages[i] = problem.Field + " " + problem.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// respondValidationError writes a 400 response listing each invalid field
+func respondValidationError(c *gin.Context, problems []models.FieldError) {
+	c.JSON(http.StatusBadRequest, models.APIError{
+		Error:   "invalid request: " + describeProblems(problems),
+		Code:    models.ErrorCodeInvalidInput,
+		Details: problems,
+	})
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"weather-dashboard/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingWeatherService records the normalized parameters it receives
+type recordingWeatherService struct {
+	MockWeatherService
+	city     string
+	lat, lon string
+}
+
+func (m *recordingWeatherService) GetWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
+	m.city = city
+	return &models.WeatherData{City: city}, nil
+}
+
+func (m *recordingWeatherService) GetWeatherByCoordinates(ctx context.Context, lat, lon string) (*models.WeatherData, error) {
+	m.lat, m.lon = lat, lon
+	return &models.WeatherData{City: lat + "," + lon}, nil
+}
+
+func TestWeatherHandler_Validation(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedCity   string
+		expectedLat    string
+		expectedLon    string
+		expectedFields []string
+	}{
+		{
+			name:           "unicode city normalized",
+			path:           "/api/weather/" + url.PathEscape("  são   paulo "),
+			expectedStatus: http.StatusOK,
+			expectedCity:   "São Paulo",
+		},
+		{
+			name:           "decomposed accent composed",
+			path:           "/api/weather/" + url.PathEscape("Zu\u0308rich"),
+			expectedStatus: http.StatusOK,
+			expectedCity:   "Zürich",
+		},
+		{
+			name:           "city with digits",
+			path:           "/api/weather/London123",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"city"},
+		},
+		{
+			name:           "coordinates normalized",
+			path:           "/api/weather/coordinates/051.50/-0.1200",
+			expectedStatus: http.StatusOK,
+			expectedLat:    "51.5",
+			expectedLon:    "-0.12",
+		},
+		{
+			name:           "coordinates out of range",
+			path:           "/api/weather/coordinates/91/-181",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"lat", "lon"},
+		},
+		{
+			name:           "longitude not a number",
+			path:           "/api/weather/coordinates/51.5/west",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"lon"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weatherService := &recordingWeatherService{}
+			handler := NewWeatherHandler(weatherService, &MockDatabaseService{})
+			r := setupTestRouter(handler)
+
+			req, err := http.NewRequest("GET", tt.path, nil)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.expectedCity, weatherService.city)
+				assert.Equal(t, tt.expectedLat, weatherService.lat)
+				assert.Equal(t, tt.expectedLon, weatherService.lon)
+				return
+			}
+
+			var response models.APIError
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, models.ErrorCodeInvalidInput, response.Code)
+
+			var fields []string
+			for _, detail := range response.Details {
+				fields = append(fields, detail.Field)
+				assert.NotEmpty(t, detail.Message)
+			}
+			assert.Equal(t, tt.expectedFields, fields)
+			assert.Empty(t, weatherService.city)
+			assert.Empty(t, weatherService.lat)
+		})
+	}
+}
+
+func TestWeatherHandler_BatchValidation(t *testing.T) {
+	handler := NewWeatherHandler(&recordingWeatherService{}, &MockDatabaseService{})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/weather/batch", handler.GetWeatherBatch)
+
+	w := postBatch(t, r, `{"items":[{"city":"zürich"},{"city":"L0nd0n"},{"lat":95,"lon":10}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.BatchWeatherResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Results, 3)
+
+	assert.Equal(t, "Zürich", response.Results[0].Data.City)
+	assert.Equal(t, models.ErrorCodeInvalidInput, response.Results[1].Code)
+	assert.Contains(t, response.Results[1].Error, "city may only contain")
+	assert.Equal(t, models.ErrorCodeInvalidInput, response.Results[2].Code)
+	assert.Contains(t, response.Results[2].Error, "lat must be between -90 and 90")
+}
@@ -1,7 +1,8 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -12,15 +13,17 @@ import (
 // Define interfaces for dependency injection
 
 type WeatherServiceInterface interface {
-	SearchCity(city string) ([]models.WeatherAPISearchResult, error)
-	GetWeatherByCoordinates(lat, lon string) (*models.WeatherData, error)
-	GetWeatherByCity(city string) (*models.WeatherData, error)
+	SearchCity(ctx context.Context, city string) ([]models.WeatherAPISearchResult, error)
+	GetWeatherByCoordinates(ctx context.Context, lat, lon string) (*models.WeatherData, error)
+	GetWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error)
+	GetForecast(ctx context.Context, city string, days int) (*models.Forecast, error)
 }
 
 type DatabaseServiceInterface interface {
-	SaveWeatherData(data *models.WeatherData) error
-	GetWeatherHistory(limit int) ([]models.WeatherData, error)
-	GetWeatherHistoryDefault() ([]models.WeatherData, error)
+	SaveWeatherData(ctx context.Context, data *models.WeatherData) error
+	GetWeatherHistory(ctx context.Context, limit int) ([]models.WeatherData, error)
+	GetWeatherHistoryDefault(ctx context.Context) ([]models.WeatherData, error)
+	StreamWeatherHistory(ctx context.Context, filter models.HistoryFilter, fn func(*models.WeatherData) error) error
 	Close() error
 }
 
@@ -38,68 +41,139 @@ func NewWeatherHandler(weatherService WeatherServiceInterface, dbService Databas
 	}
 }
 
-// GetWeatherByCity handles GET /api/weather/:city
+// GetWeatherByCity handles GET /api/v1/weather/:city
 func (h *WeatherHandler) GetWeatherByCity(c *gin.Context) {
 	city := c.Param("city")
 	if city == "" {
-		c.JSON(http.StatusBadRequest, models.APIError{Error: "city parameter is required"})
+		respondInvalidInput(c, "city parameter is required")
 		return
 	}
 
-	weatherData, err := h.weatherService.GetWeatherByCity(city)
-	if err != nil {
-		log.Printf("Error fetching weather for %s: %v", city, err)
-		c.JSON(http.StatusInternalServerError, models.APIError{Error: err.Error()})
+	city, problems := validateCity("city", city)
+	if len(problems) > 0 {
+		respondValidationError(c, problems)
 		return
 	}
 
-	// Save to database
-	if err := h.dbService.SaveWeatherData(weatherData); err != nil {
-		log.Printf("Error saving weather data: %v", err)
-		// Don't return error to client, just log it
+	weatherData, err := h.weatherService.GetWeatherByCity(c.Request.Context(), city)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to fetch weather", "city", city, "error", err)
+		respondError(c, err)
+		return
 	}
 
+	saveWeatherData(c.Request.Context(), h.dbService, weatherData)
+
 	c.JSON(http.StatusOK, weatherData)
 }
 
-// GetWeatherByCoordinates handles GET /api/weather/coordinates/:lat/:lon
+// GetWeatherByCoordinates handles GET /api/v1/weather/coordinates/:lat/:lon
 func (h *WeatherHandler) GetWeatherByCoordinates(c *gin.Context) {
 	lat := c.Param("lat")
 	lon := c.Param("lon")
 
 	if lat == "" || lon == "" {
-		c.JSON(http.StatusBadRequest, models.APIError{Error: "latitude and longitude parameters are required"})
+		respondInvalidInput(c, "latitude and longitude parameters are required")
 		return
 	}
 
-	weatherData, err := h.weatherService.GetWeatherByCoordinates(lat, lon)
-	if err != nil {
-		log.Printf("Error fetching weather for coordinates %s,%s: %v", lat, lon, err)
-		c.JSON(http.StatusInternalServerError, models.APIError{Error: err.Error()})
+	lat, lon, problems := validateCoordinates(lat, lon)
+	if len(problems) > 0 {
+		respondValidationError(c, problems)
 		return
 	}
 
-	// Save to database
-	if err := h.dbService.SaveWeatherData(weatherData); err != nil {
-		log.Printf("Error saving weather data: %v", err)
-		// Don't return error to client, just log it
+	weatherData, err := h.weatherService.GetWeatherByCoordinates(c.Request.Context(), lat, lon)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to fetch weather by coordinates", "lat", lat, "lon", lon, "error", err)
+		respondError(c, err)
+		return
 	}
 
+	saveWeatherData(c.Request.Context(), h.dbService, weatherData)
+
 	c.JSON(http.StatusOK, weatherData)
 }
 
-// GetWeatherHistory handles GET /api/history
+// GetForecast handles GET /api/v1/forecast/:city
+func (h *WeatherHandler) GetForecast(c *gin.Context) {
+	city, problems := validateCity("city", c.Param("city"))
+	days, dayProblems := parseIntQuery(c, "days", models.DefaultForecastDays, 1, models.MaxForecastDays)
+	problems = append(problems, dayProblems...)
+	if len(problems) > 0 {
+		respondValidationError(c, problems)
+		return
+	}
+
+	forecast, err := h.weatherService.GetForecast(c.Request.Context(), city, days)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to fetch forecast", "city", city, "error", err)
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, forecast)
+}
+
+// SearchCities handles GET /api/v1/search
+func (h *WeatherHandler) SearchCities(c *gin.Context) {
+	query, problems := validateCity("q", c.Query("q"))
+	if len(problems) > 0 {
+		respondValidationError(c, problems)
+		return
+	}
+
+	results, err := h.weatherService.SearchCity(c.Request.Context(), query)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to search cities", "query", query, "error", err)
+		respondError(c, err)
+		return
+	}
+	if results == nil {
+		results = []models.WeatherAPISearchResult{}
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// GetWeatherHistory handles GET /api/v1/history. Without a limit query
+// parameter the configured history limit applies.
 func (h *WeatherHandler) GetWeatherHistory(c *gin.Context) {
-	history, err := h.dbService.GetWeatherHistoryDefault()
+	var history []models.WeatherData
+	var err error
+	if c.Query("limit") == "" {
+		history, err = h.dbService.GetWeatherHistoryDefault(c.Request.Context())
+	} else {
+		limit, problems := parseIntQuery(c, "limit", 0, 1, models.MaxHistoryLimit)
+		if len(problems) > 0 {
+			respondValidationError(c, problems)
+			return
+		}
+		history, err = h.dbService.GetWeatherHistory(c.Request.Context(), limit)
+	}
 	if err != nil {
-		log.Printf("Error fetching weather history: %v", err)
-		c.JSON(http.StatusInternalServerError, models.APIError{Error: err.Error()})
+		slog.ErrorContext(c.Request.Context(), "failed to fetch weather history", "error", err)
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, history)
 }
 
+// saveWeatherData stores a fresh observation, unless it was served from the
+// database. The save is not cancelled if the client disconnects, so readings
+// already paid for upstream are kept.
+func saveWeatherData(ctx context.Context, dbService DatabaseServiceInterface, weatherData *models.WeatherData) {
+	if weatherData.Cached {
+		return
+	}
+
+	if err := dbService.SaveWeatherData(context.WithoutCancel(ctx), weatherData); err != nil {
+		slog.ErrorContext(ctx, "failed to save weather data", "city", weatherData.City, "error", err)
+		// Don't return error to client, just log it
+	}
+}
+
 // ServeIndex handles GET /
 func (h *WeatherHandler) ServeIndex(c *gin.Context) {
 	c.HTML(http.StatusOK, "index.html", nil)
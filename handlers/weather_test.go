@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,36 +25,67 @@ type MockWeatherService struct {
 	weatherError  error
 }
 
-func (m *MockWeatherService) SearchCity(city string) ([]models.WeatherAPISearchResult, error) {
+func (m *MockWeatherService) SearchCity(ctx context.Context, city string) ([]models.WeatherAPISearchResult, error) {
 	return m.searchResults, m.searchError
 }
 
-func (m *MockWeatherService) GetWeatherByCoordinates(lat, lon string) (*models.WeatherData, error) {
+func (m *MockWeatherService) GetWeatherByCoordinates(ctx context.Context, lat, lon string) (*models.WeatherData, error) {
 	return m.weatherData, m.weatherError
 }
 
-func (m *MockWeatherService) GetWeatherByCity(city string) (*models.WeatherData, error) {
+func (m *MockWeatherService) GetWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
 	return m.weatherData, m.weatherError
 }
 
+func (m *MockWeatherService) GetForecast(ctx context.Context, city string, days int) (*models.Forecast, error) {
+	if m.weatherError != nil {
+		return nil, m.weatherError
+	}
+	forecast := &models.Forecast{City: city}
+	for i := 0; i < days; i++ {
+		forecast.Days = append(forecast.Days, models.ForecastDay{Date: fmt.Sprintf("2024-03-%02d", i+1)})
+	}
+	return forecast, nil
+}
+
 type MockDatabaseService struct {
 	saveError    error
 	historyData  []models.WeatherData
 	historyError error
+	historyLimit int
 }
 
-func (m *MockDatabaseService) SaveWeatherData(data *models.WeatherData) error {
+func (m *MockDatabaseService) SaveWeatherData(ctx context.Context, data *models.WeatherData) error {
 	return m.saveError
 }
 
-func (m *MockDatabaseService) GetWeatherHistory(limit int) ([]models.WeatherData, error) {
+func (m *MockDatabaseService) GetWeatherHistory(ctx context.Context, limit int) ([]models.WeatherData, error) {
+	m.historyLimit = limit
 	return m.historyData, m.historyError
 }
 
-func (m *MockDatabaseService) GetWeatherHistoryDefault() ([]models.WeatherData, error) {
+func (m *MockDatabaseService) GetWeatherHistoryDefault(ctx context.Context) ([]models.WeatherData, error) {
 	return m.historyData, m.historyError
 }
 
+func (m *MockDatabaseService) StreamWeatherHistory(ctx context.Context, filter models.HistoryFilter, fn func(*models.WeatherData) error) error {
+	if m.historyError != nil {
+		return m.historyError
+	}
+	for i := range m.historyData {
+		if filter.City != "" && !strings.EqualFold(m.historyData[i].City, filter.City) {
+			continue
+		}
+		if !filter.Matches(m.historyData[i].Timestamp) {
+			continue
+		}
+		if err := fn(&m.historyData[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *MockDatabaseService) Close() error {
 	return nil
 }
@@ -63,6 +97,8 @@ func setupTestRouter(handler *WeatherHandler) *gin.Engine {
 	// Setup routes
 	r.GET("/api/weather/:city", handler.GetWeatherByCity)
 	r.GET("/api/weather/coordinates/:lat/:lon", handler.GetWeatherByCoordinates)
+	r.GET("/api/forecast/:city", handler.GetForecast)
+	r.GET("/api/search", handler.SearchCities)
 	r.GET("/api/history", handler.GetWeatherHistory)
 	r.GET("/", handler.ServeIndex)
 
@@ -111,10 +147,53 @@ func TestWeatherHandler_GetWeatherByCity(t *testing.T) {
 			name:           "city not found",
 			city:           "nonexistent",
 			mockWeather:    nil,
+			mockError:      fmt.Errorf("city %w: nonexistent", models.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedBody: map[string]interface{}{
+				"error": "city not found: nonexistent",
+				"code":  models.ErrorCodeNotFound,
+			},
+		},
+		{
+			name:           "unexpected error",
+			city:           "london",
+			mockWeather:    nil,
 			mockError:      assert.AnError,
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody: map[string]interface{}{
 				"error": assert.AnError.Error(),
+				"code":  models.ErrorCodeInternal,
+			},
+		},
+		{
+			name:           "upstream quota exceeded",
+			city:           "london",
+			mockWeather:    nil,
+			mockError:      models.ErrQuotaExceeded,
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody: map[string]interface{}{
+				"error": models.ErrQuotaExceeded.Error(),
+				"code":  models.ErrorCodeQuotaExceeded,
+			},
+		},
+		{
+			name:           "upstream unavailable",
+			city:           "london",
+			mockWeather:    nil,
+			mockError:      fmt.Errorf("%w: API request failed with status: 500", models.ErrUpstreamUnavailable),
+			expectedStatus: http.StatusBadGateway,
+			expectedBody: map[string]interface{}{
+				"code": models.ErrorCodeUpstreamUnavailable,
+			},
+		},
+		{
+			name:           "upstream rejected credentials",
+			city:           "london",
+			mockWeather:    nil,
+			mockError:      fmt.Errorf("failed to search city: %w", models.ErrUnauthorized),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody: map[string]interface{}{
+				"code": models.ErrorCodeUpstreamUnauthorized,
 			},
 		},
 		{
@@ -384,6 +463,126 @@ func TestWeatherHandler_GetWeatherHistory(t *testing.T) {
 	}
 }
 
+func TestWeatherHandler_GetWeatherHistoryLimit(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedLimit  int
+	}{
+		{"default limit", "", http.StatusOK, 0},
+		{"explicit limit", "?limit=25", http.StatusOK, 25},
+		{"maximum limit", fmt.Sprintf("?limit=%d", models.MaxHistoryLimit), http.StatusOK, models.MaxHistoryLimit},
+		{"zero limit", "?limit=0", http.StatusBadRequest, 0},
+		{"limit too large", fmt.Sprintf("?limit=%d", models.MaxHistoryLimit+1), http.StatusBadRequest, 0},
+		{"non-numeric limit", "?limit=ten", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDBService := &MockDatabaseService{historyData: []models.WeatherData{}}
+			r := setupTestRouter(NewWeatherHandler(&MockWeatherService{}, mockDBService))
+
+			req, err := http.NewRequest("GET", "/api/history"+tt.query, nil)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedLimit, mockDBService.historyLimit)
+		})
+	}
+}
+
+func TestWeatherHandler_GetForecast(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		mockError      error
+		expectedStatus int
+		expectedDays   int
+	}{
+		{"default days", "/api/forecast/London", nil, http.StatusOK, models.DefaultForecastDays},
+		{"explicit days", "/api/forecast/London?days=7", nil, http.StatusOK, 7},
+		{"too many days", fmt.Sprintf("/api/forecast/London?days=%d", models.MaxForecastDays+1), nil, http.StatusBadRequest, 0},
+		{"invalid city", "/api/forecast/London1", nil, http.StatusBadRequest, 0},
+		{"city not found", "/api/forecast/Atlantis", fmt.Errorf("city %w: Atlantis", models.ErrNotFound), http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockWeatherService := &MockWeatherService{weatherError: tt.mockError}
+			r := setupTestRouter(NewWeatherHandler(mockWeatherService, &MockDatabaseService{}))
+
+			req, err := http.NewRequest("GET", tt.path, nil)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var forecast models.Forecast
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &forecast))
+				assert.Equal(t, "London", forecast.City)
+				assert.Len(t, forecast.Days, tt.expectedDays)
+			}
+		})
+	}
+}
+
+func TestWeatherHandler_SearchCities(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		mockResults    []models.WeatherAPISearchResult
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "results",
+			query:          "?q=lon",
+			mockResults:    []models.WeatherAPISearchResult{{Name: "London", Country: "United Kingdom"}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"name":"London","region":"","country":"United Kingdom","lat":0,"lon":0}]`,
+		},
+		{
+			name:           "no results",
+			query:          "?q=xyz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[]`,
+		},
+		{
+			name:           "missing query",
+			query:          "",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "upstream unavailable",
+			query:          "?q=lon",
+			mockError:      models.ErrUpstreamUnavailable,
+			expectedStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockWeatherService := &MockWeatherService{searchResults: tt.mockResults, searchError: tt.mockError}
+			r := setupTestRouter(NewWeatherHandler(mockWeatherService, &MockDatabaseService{}))
+
+			req, err := http.NewRequest("GET", "/api/search"+tt.query, nil)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestWeatherHandler_ServeIndex(t *testing.T) {
 	// Skip this test since it requires HTML templates that aren't available in test mode
 	t.Skip("Skipping ServeIndex test - requires HTML templates not available in test mode")
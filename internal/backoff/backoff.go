@@ -0,0 +1,61 @@
+// Package backoff computes retry delays for HTTP calls, shared by the
+// upstream WeatherAPI client and the API client package.
+package backoff
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Policy is an exponential backoff starting at BaseDelay and doubling for
+// each further retry up to MaxDelay
+type Policy struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Delay returns how long to wait before retrying after the given attempt.
+// A server's Retry-After takes precedence over exponential backoff; it
+// reports false if that is longer than the maximum delay.
+func (p Policy) Delay(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	if retryAfter > 0 {
+		return retryAfter, retryAfter <= p.MaxDelay
+	}
+
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	// Equal jitter: wait between half and all of the backoff so that
+	// clients failing together do not retry together
+	half := delay / 2
+	if half > 0 {
+		delay = half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+
+	return delay, true
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date. It returns 0 if the header is absent or invalid.
+func ParseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
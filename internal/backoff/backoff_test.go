@@ -0,0 +1,54 @@
+package backoff
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Delay(t *testing.T) {
+	policy := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	t.Run("exponential with jitter", func(t *testing.T) {
+		for attempt, backoff := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond} {
+			delay, ok := policy.Delay(attempt, 0)
+			assert.True(t, ok)
+			assert.GreaterOrEqual(t, delay, backoff/2)
+			assert.LessOrEqual(t, delay, backoff)
+		}
+	})
+
+	t.Run("capped at max delay", func(t *testing.T) {
+		delay, ok := policy.Delay(10, 0)
+		assert.True(t, ok)
+		assert.LessOrEqual(t, delay, time.Second)
+
+		delay, ok = policy.Delay(100, 0)
+		assert.True(t, ok)
+		assert.LessOrEqual(t, delay, time.Second)
+	})
+
+	t.Run("retry after honored", func(t *testing.T) {
+		delay, ok := policy.Delay(0, 700*time.Millisecond)
+		assert.True(t, ok)
+		assert.Equal(t, 700*time.Millisecond, delay)
+	})
+
+	t.Run("retry after too long", func(t *testing.T) {
+		_, ok := policy.Delay(0, time.Minute)
+		assert.False(t, ok)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("", now))
+	assert.Equal(t, 3*time.Second, ParseRetryAfter("3", now))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("-1", now))
+	assert.Equal(t, 30*time.Second, ParseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("soon", now))
+}
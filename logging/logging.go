@@ -0,0 +1,168 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDKey is the context key holding the request ID
+type requestIDKey struct{}
+
+// redacted replaces secret values in logged URLs
+const redacted = "REDACTED"
+
+// secretParams are query parameters whose values are never logged
+var secretParams = []string{"key"}
+
+// secrets holds values that are redacted wherever they appear in log
+// records or client-facing error messages
+var secrets struct {
+	sync.RWMutex
+	values []string
+}
+
+// level is the minimum level of the installed logger, adjustable at runtime
+var level = new(slog.LevelVar)
+
+// Setup installs a JSON logger writing to w as the default slog logger.
+// Records logged with a context carrying a request ID or span include them.
+func Setup(w io.Writer, name string) *slog.Logger {
+	SetLevel(name)
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	logger := slog.New(&contextHandler{Handler: handler})
+	slog.SetDefault(logger)
+	return logger
+}
+
+// ParseLevel converts a level name (debug, info, warn, error) to a slog level.
+// Unknown names default to info.
+func ParseLevel(level string) slog.Level {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return parsed
+}
+
+// SetLevel changes the minimum level of the installed logger
+func SetLevel(name string) {
+	level.Set(ParseLevel(name))
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, if any
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// RedactURL returns rawURL with secret query parameter values replaced
+func RedactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+
+	query := parsed.Query()
+	for _, param := range secretParams {
+		if query.Has(param) {
+			query.Set(param, redacted)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+// AddSecrets registers values to redact. Previously registered values stay
+// redacted so rotated-out keys never leak.
+func AddSecrets(values ...string) {
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	for _, value := range values {
+		if value != "" && !slices.Contains(secrets.values, value) {
+			secrets.values = append(secrets.values, value)
+		}
+	}
+}
+
+// Redact replaces every registered secret in text
+func Redact(text string) string {
+	secrets.RLock()
+	defer secrets.RUnlock()
+
+	for _, value := range secrets.values {
+		text = strings.ReplaceAll(text, value, redacted)
+	}
+	return text
+}
+
+// redactAttr redacts secrets from string and error attribute values
+func redactAttr(attr slog.Attr) slog.Attr {
+	switch value := attr.Value.Resolve(); value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(Redact(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redactedGroup := make([]slog.Attr, len(group))
+		for i, a := range group {
+			redactedGroup[i] = redactAttr(a)
+		}
+		attr.Value = slog.GroupValue(redactedGroup...)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			attr.Value = slog.StringValue(Redact(err.Error()))
+		}
+	}
+	return attr
+}
+
+// contextHandler adds the request and trace IDs from the record's context
+// and redacts registered secrets
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle implements slog.Handler
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	redactedRecord := slog.NewRecord(record.Time, record.Level, Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redactedRecord.AddAttrs(redactAttr(attr))
+		return true
+	})
+	record = redactedRecord
+
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redactedAttrs[i] = redactAttr(attr)
+	}
+	return &contextHandler{Handler: h.Handler.WithAttrs(redactedAttrs)}
+}
+
+// WithGroup implements slog.Handler
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *testing.T) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	var buf bytes.Buffer
+	Setup(&buf, "info")
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	slog.InfoContext(ctx, "hello", "city", "London")
+	slog.Debug("hidden")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "hello", line["msg"])
+	assert.Equal(t, "London", line["city"])
+	assert.Equal(t, "req-123", line["request_id"])
+	assert.NotContains(t, buf.String(), "hidden")
+}
+
+func TestSetLevel(t *testing.T) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+	defer SetLevel("info")
+
+	var buf bytes.Buffer
+	Setup(&buf, "warn")
+
+	slog.Info("before")
+	SetLevel("debug")
+	slog.Debug("after")
+
+	assert.NotContains(t, buf.String(), "before")
+	assert.Contains(t, buf.String(), "after")
+}
+
+func TestParseLevel(t *testing.T) {
+	assert.Equal(t, slog.LevelDebug, ParseLevel("debug"))
+	assert.Equal(t, slog.LevelWarn, ParseLevel("WARN"))
+	assert.Equal(t, slog.LevelInfo, ParseLevel("verbose"))
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Empty(t, RequestID(context.Background()))
+	assert.Equal(t, "abc", RequestID(WithRequestID(context.Background(), "abc")))
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawURL   string
+		expected string
+	}{
+		{
+			name:     "api key redacted",
+			rawURL:   "http://api.weatherapi.com/v1/search.json?key=secret&q=london",
+			expected: "http://api.weatherapi.com/v1/search.json?key=REDACTED&q=london",
+		},
+		{
+			name:     "no secrets",
+			rawURL:   "http://api.weatherapi.com/v1/search.json?q=london",
+			expected: "http://api.weatherapi.com/v1/search.json?q=london",
+		},
+		{
+			name:     "unparseable",
+			rawURL:   "http://[::1%zz/?key=secret",
+			expected: "REDACTED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, RedactURL(tt.rawURL))
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	AddSecrets("s3cr3t-value", "")
+	assert.Equal(t, "key=REDACTED&q=london", Redact("key=s3cr3t-value&q=london"))
+	assert.Equal(t, "nothing to hide", Redact("nothing to hide"))
+
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	var buf bytes.Buffer
+	logger := Setup(&buf, "info").With("token", "s3cr3t-value")
+	logger.Info("using s3cr3t-value",
+		"url", "http://example.com/?key=s3cr3t-value",
+		"error", errors.New("request with s3cr3t-value failed"),
+		slog.Group("upstream", "key", "s3cr3t-value"))
+
+	assert.NotContains(t, buf.String(), "s3cr3t-value")
+	assert.Contains(t, buf.String(), "REDACTED")
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"weather-dashboard/client"
+	"weather-dashboard/config"
+	"weather-dashboard/logging"
+	"weather-dashboard/models"
+	"weather-dashboard/services"
+)
+
+// lookupBackend answers the lookup commands, either in-process through the
+// service layer or by calling a running server
+type lookupBackend interface {
+	GetWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error)
+	GetForecast(ctx context.Context, city string, days int) (*models.Forecast, error)
+	SearchCity(ctx context.Context, query string) ([]models.WeatherAPISearchResult, error)
+	History(ctx context.Context, limit int) ([]models.WeatherData, error)
+	Close() error
+}
+
+// lookupFlags are the flags shared by every lookup command
+type lookupFlags struct {
+	server  string
+	json    bool
+	timeout time.Duration
+}
+
+// newLookupFlagSet creates the flag set of a lookup command
+func newLookupFlagSet(name, usage string) (*flag.FlagSet, *lookupFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags := &lookupFlags{}
+	fs.StringVar(&flags.server, "server", os.Getenv("WEATHER_SERVER"), "URL of a running server to query instead of calling WeatherAPI directly (default: $WEATHER_SERVER)")
+	fs.BoolVar(&flags.json, "json", false, "print JSON instead of a table")
+	fs.DurationVar(&flags.timeout, "timeout", 30*time.Second, "time allowed for the lookup")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), strings.TrimSpace("usage: weather-dashboard "+name+" [flags] "+usage))
+		fs.PrintDefaults()
+	}
+	return fs, flags
+}
+
+// parseLookup parses a lookup command's arguments and returns the positional
+// arguments joined by spaces. They are required when needsArg is set and
+// rejected otherwise.
+func parseLookup(fs *flag.FlagSet, args []string, needsArg bool) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if !needsArg {
+		if fs.NArg() > 0 {
+			fs.Usage()
+			return "", fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+		}
+		return "", nil
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return "", fmt.Errorf("missing argument")
+	}
+	return strings.Join(fs.Args(), " "), nil
+}
+
+// openBackend returns a remote backend if a server is set, otherwise one
+// using the local configuration and database
+func (f *lookupFlags) openBackend() (lookupBackend, error) {
+	if f.server != "" {
+		return newRemoteBackend(f.server, f.timeout)
+	}
+	return newLocalBackend()
+}
+
+// runGet prints the current weather for a city
+func runGet(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, flags := newLookupFlagSet("get", "<city>")
+	city, err := parseLookup(fs, args, true)
+	if err != nil {
+		return err
+	}
+
+	return withBackend(ctx, flags, func(ctx context.Context, backend lookupBackend) error {
+		weatherData, err := backend.GetWeatherByCity(ctx, city)
+		if err != nil {
+			return err
+		}
+		if flags.json {
+			return printJSON(stdout, weatherData)
+		}
+
+		tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "City:\t%s\n", joinNonEmpty(", ", weatherData.City, weatherData.State, weatherData.Country))
+		fmt.Fprintf(tw, "Temperature:\t%.1f°C\n", weatherData.Temperature)
+		fmt.Fprintf(tw, "Conditions:\t%s\n", weatherData.Description)
+		fmt.Fprintf(tw, "Humidity:\t%d%%\n", weatherData.Humidity)
+		observed := weatherData.Timestamp.Local().Format(time.DateTime)
+		if weatherData.Stale {
+			observed += fmt.Sprintf(" (stale, %s old)", time.Duration(weatherData.AgeSeconds)*time.Second)
+		}
+		fmt.Fprintf(tw, "Observed:\t%s\n", observed)
+		return tw.Flush()
+	})
+}
+
+// runForecast prints a daily forecast for a city
+func runForecast(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, flags := newLookupFlagSet("forecast", "<city>")
+	days := fs.Int("days", models.DefaultForecastDays, fmt.Sprintf("number of days, 1-%d", models.MaxForecastDays))
+	city, err := parseLookup(fs, args, true)
+	if err != nil {
+		return err
+	}
+	if *days < 1 || *days > models.MaxForecastDays {
+		return fmt.Errorf("-days must be between 1 and %d", models.MaxForecastDays)
+	}
+
+	return withBackend(ctx, flags, func(ctx context.Context, backend lookupBackend) error {
+		forecast, err := backend.GetForecast(ctx, city, *days)
+		if err != nil {
+			return err
+		}
+		if flags.json {
+			return printJSON(stdout, forecast)
+		}
+
+		fmt.Fprintln(stdout, joinNonEmpty(", ", forecast.City, forecast.State, forecast.Country))
+		tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DATE\tMIN\tMAX\tAVG\tHUMIDITY\tRAIN\tCONDITIONS")
+		for _, day := range forecast.Days {
+			fmt.Fprintf(tw, "%s\t%.1f°C\t%.1f°C\t%.1f°C\t%d%%\t%d%%\t%s\n",
+				day.Date, day.MinTemperature, day.MaxTemperature, day.AvgTemperature, day.Humidity, day.ChanceOfRain, day.Description)
+		}
+		return tw.Flush()
+	})
+}
+
+// runHistory prints the most recent stored observations
+func runHistory(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, flags := newLookupFlagSet("history", "")
+	limit := fs.Int("limit", 10, fmt.Sprintf("number of observations, 1-%d", models.MaxHistoryLimit))
+	if _, err := parseLookup(fs, args, false); err != nil {
+		return err
+	}
+	if *limit < 1 || *limit > models.MaxHistoryLimit {
+		return fmt.Errorf("-limit must be between 1 and %d", models.MaxHistoryLimit)
+	}
+
+	return withBackend(ctx, flags, func(ctx context.Context, backend lookupBackend) error {
+		history, err := backend.History(ctx, *limit)
+		if err != nil {
+			return err
+		}
+		if flags.json {
+			if history == nil {
+				history = []models.WeatherData{}
+			}
+			return printJSON(stdout, history)
+		}
+
+		tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "OBSERVED\tCITY\tCOUNTRY\tTEMPERATURE\tHUMIDITY\tCONDITIONS")
+		for _, data := range history {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%.1f°C\t%d%%\t%s\n",
+				data.Timestamp.Local().Format(time.DateTime), data.City, data.Country, data.Temperature, data.Humidity, data.Description)
+		}
+		return tw.Flush()
+	})
+}
+
+// runSearch prints the locations matching a query
+func runSearch(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, flags := newLookupFlagSet("search", "<query>")
+	query, err := parseLookup(fs, args, true)
+	if err != nil {
+		return err
+	}
+
+	return withBackend(ctx, flags, func(ctx context.Context, backend lookupBackend) error {
+		results, err := backend.SearchCity(ctx, query)
+		if err != nil {
+			return err
+		}
+		if flags.json {
+			if results == nil {
+				results = []models.WeatherAPISearchResult{}
+			}
+			return printJSON(stdout, results)
+		}
+
+		tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tREGION\tCOUNTRY\tLAT\tLON")
+		for _, result := range results {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%.4f\t%.4f\n", result.Name, result.Region, result.Country, result.Lat, result.Lon)
+		}
+		return tw.Flush()
+	})
+}
+
+// withBackend opens the backend selected by flags, runs fn with the lookup
+// timeout applied and closes the backend
+func withBackend(ctx context.Context, flags *lookupFlags, fn func(context.Context, lookupBackend) error) error {
+	backend, err := flags.openBackend()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, flags.timeout)
+	defer cancel()
+
+	return fn(ctx, backend)
+}
+
+// printJSON writes v as indented JSON
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// joinNonEmpty joins the non-empty parts with sep
+func joinNonEmpty(sep string, parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
+
+// errNoWeatherKey is returned by local lookups that call WeatherAPI when no
+// key is configured
+var errNoWeatherKey = errors.New("WEATHERAPI_KEY is required to look up weather without -server")
+
+// localBackend calls WeatherAPI through the service layer, with the same
+// configuration, quota accounting and database as the server. History is
+// read from the database and works without a WeatherAPI key.
+type localBackend struct {
+	weather *services.WeatherService
+	db      *services.DatabaseService
+	hasKey  bool
+}
+
+// newLocalBackend loads the configuration and opens the database
+func newLocalBackend() (*localBackend, error) {
+	cfg, err := config.LoadLocal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	logging.AddSecrets(cfg.Secrets()...)
+
+	dbService, err := services.NewDatabaseService(cfg.Database.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database service: %w", err)
+	}
+
+	weatherService := services.NewWeatherService(&cfg.Weather)
+	weatherService.SetQuota(services.NewQuotaTracker(services.ProviderWeatherAPI, cfg.Quota, dbService), dbService)
+
+	return &localBackend{weather: weatherService, db: dbService, hasKey: len(cfg.Weather.Keys()) > 0}, nil
+}
+
+// GetWeatherByCity looks up a city and, like the server, stores fresh
+// observations in the history
+func (b *localBackend) GetWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
+	if !b.hasKey {
+		return nil, errNoWeatherKey
+	}
+	weatherData, err := b.weather.GetWeatherByCity(ctx, city)
+	if err != nil {
+		return nil, err
+	}
+	if !weatherData.Cached {
+		if err := b.db.SaveWeatherData(ctx, weatherData); err != nil {
+			return nil, err
+		}
+	}
+	return weatherData, nil
+}
+
+func (b *localBackend) GetForecast(ctx context.Context, city string, days int) (*models.Forecast, error) {
+	if !b.hasKey {
+		return nil, errNoWeatherKey
+	}
+	return b.weather.GetForecast(ctx, city, days)
+}
+
+func (b *localBackend) SearchCity(ctx context.Context, query string) ([]models.WeatherAPISearchResult, error) {
+	if !b.hasKey {
+		return nil, errNoWeatherKey
+	}
+	return b.weather.SearchCity(ctx, query)
+}
+
+func (b *localBackend) History(ctx context.Context, limit int) ([]models.WeatherData, error) {
+	return b.db.GetWeatherHistory(ctx, limit)
+}
+
+func (b *localBackend) Close() error {
+	b.weather.Close()
+	return b.db.Close()
+}
+
+// remoteBackend calls the HTTP API of a running server
+type remoteBackend struct {
+	client     *client.Client
+	httpClient *http.Client
+}
+
+// newRemoteBackend creates a backend for the server at serverURL
+func newRemoteBackend(serverURL string, timeout time.Duration) (*remoteBackend, error) {
+	httpClient := &http.Client{Timeout: timeout}
+	apiClient, err := client.New(serverURL, client.Options{
+		HTTPClient: httpClient,
+		UserAgent:  "weather-dashboard-cli",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("-server must be an http(s) URL, got %q", serverURL)
+	}
+	return &remoteBackend{client: apiClient, httpClient: httpClient}, nil
+}
+
+func (b *remoteBackend) GetWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
+	return b.client.GetWeatherByCity(ctx, city)
+}
+
+func (b *remoteBackend) GetForecast(ctx context.Context, city string, days int) (*models.Forecast, error) {
+	return b.client.GetForecast(ctx, city, days)
+}
+
+func (b *remoteBackend) SearchCity(ctx context.Context, query string) ([]models.WeatherAPISearchResult, error) {
+	return b.client.SearchCity(ctx, query)
+}
+
+func (b *remoteBackend) History(ctx context.Context, limit int) ([]models.WeatherData, error) {
+	return b.client.History(ctx, limit)
+}
+
+func (b *remoteBackend) Close() error {
+	b.httpClient.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"weather-dashboard/models"
+)
+
+func TestParseLookup(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		needsArg      bool
+		expected      string
+		expectedJSON  bool
+		expectedError string
+	}{
+		{name: "single argument", args: []string{"London"}, needsArg: true, expected: "London"},
+		{name: "arguments are joined", args: []string{"New", "York"}, needsArg: true, expected: "New York"},
+		{name: "flags before the argument", args: []string{"-json", "Paris"}, needsArg: true, expected: "Paris", expectedJSON: true},
+		{name: "missing argument", needsArg: true, expectedError: "missing argument"},
+		{name: "no argument needed", args: []string{"-json"}, expectedJSON: true},
+		{name: "unexpected argument", args: []string{"London"}, expectedError: "unexpected arguments: London"},
+		{name: "unknown flag", args: []string{"-verbose", "London"}, needsArg: true, expectedError: "flag provided but not defined: -verbose"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, flags := newLookupFlagSet("get", "<city>")
+			fs.SetOutput(io.Discard)
+
+			arg, err := parseLookup(fs, tt.args, tt.needsArg)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, arg)
+			assert.Equal(t, tt.expectedJSON, flags.json)
+		})
+	}
+}
+
+func TestNewLookupFlagSet_ServerFromEnvironment(t *testing.T) {
+	t.Setenv("WEATHER_SERVER", "http://weather.internal")
+
+	fs, flags := newLookupFlagSet("get", "<city>")
+	require.NoError(t, fs.Parse(nil))
+	assert.Equal(t, "http://weather.internal", flags.server)
+	assert.Equal(t, 30*time.Second, flags.timeout)
+
+	require.NoError(t, fs.Parse([]string{"-server", "http://other.internal"}))
+	assert.Equal(t, "http://other.internal", flags.server)
+}
+
+func TestLookupCommands_InvalidArguments(t *testing.T) {
+	t.Setenv("WEATHER_SERVER", "")
+
+	tests := []struct {
+		name          string
+		command       func(context.Context, []string, io.Writer) error
+		args          []string
+		expectedError string
+	}{
+		{name: "get without city", command: runGet, expectedError: "missing argument"},
+		{name: "search without query", command: runSearch, expectedError: "missing argument"},
+		{name: "forecast without city", command: runForecast, args: []string{"-days", "3"}, expectedError: "missing argument"},
+		{name: "forecast days too low", command: runForecast, args: []string{"-days", "0", "London"}, expectedError: "-days must be between 1 and 14"},
+		{name: "forecast days too high", command: runForecast, args: []string{"-days", "15", "London"}, expectedError: "-days must be between 1 and 14"},
+		{name: "history limit too low", command: runHistory, args: []string{"-limit", "0"}, expectedError: "-limit must be between 1 and 100"},
+		{name: "history rejects arguments", command: runHistory, args: []string{"London"}, expectedError: "unexpected arguments: London"},
+		{name: "invalid server", command: runGet, args: []string{"-server", "weather.internal", "London"}, expectedError: `-server must be an http(s) URL, got "weather.internal"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := tt.command(context.Background(), tt.args, &stdout)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedError)
+			assert.Empty(t, stdout.String())
+		})
+	}
+}
+
+func TestLookupCommands_Output(t *testing.T) {
+	t.Setenv("WEATHER_SERVER", "")
+	observed := time.Date(2026, 3, 15, 12, 30, 0, 0, time.UTC)
+	local := observed.Local().Format(time.DateTime)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body interface{}
+		switch r.URL.Path {
+		case "/api/v1/weather/London":
+			body = models.WeatherData{City: "London", State: "City of London", Country: "UK", Temperature: 15.25, Description: "Light rain", Humidity: 80, Timestamp: observed}
+		case "/api/v1/weather/Paris":
+			body = models.WeatherData{City: "Paris", Country: "France", Temperature: 9, Description: "Clear", Humidity: 40, Timestamp: observed, Stale: true, AgeSeconds: 5400}
+		case "/api/v1/forecast/London":
+			assert.Equal(t, "2", r.URL.Query().Get("days"))
+			body = models.Forecast{City: "London", Country: "UK", Days: []models.ForecastDay{
+				{Date: "2026-03-15", MinTemperature: 8, MaxTemperature: 14.5, AvgTemperature: 11.2, Humidity: 75, ChanceOfRain: 60, Description: "Patchy rain"},
+				{Date: "2026-03-16", MinTemperature: 6, MaxTemperature: 12, AvgTemperature: 9, Humidity: 70, ChanceOfRain: 10, Description: "Sunny"},
+			}}
+		case "/api/v1/search":
+			if r.URL.Query().Get("q") == "nowhere" {
+				body = []models.WeatherAPISearchResult{}
+				break
+			}
+			body = []models.WeatherAPISearchResult{{Name: "London", Region: "City of London", Country: "UK", Lat: 51.52, Lon: -0.11}}
+		case "/api/v1/history":
+			assert.Equal(t, "1", r.URL.Query().Get("limit"))
+			body = []models.WeatherData{{City: "London", Country: "UK", Temperature: 15, Description: "Light rain", Humidity: 80, Timestamp: observed}}
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(models.APIError{Error: "city not found", Code: models.ErrorCodeNotFound})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		command       func(context.Context, []string, io.Writer) error
+		args          []string
+		expected      string
+		expectedError string
+	}{
+		{
+			name:    "get",
+			command: runGet,
+			args:    []string{"London"},
+			expected: "City:         London, City of London, UK\n" +
+				"Temperature:  15.2°C\n" +
+				"Conditions:   Light rain\n" +
+				"Humidity:     80%\n" +
+				"Observed:     " + local + "\n",
+		},
+		{
+			name:    "get stale",
+			command: runGet,
+			args:    []string{"Paris"},
+			expected: "City:         Paris, France\n" +
+				"Temperature:  9.0°C\n" +
+				"Conditions:   Clear\n" +
+				"Humidity:     40%\n" +
+				"Observed:     " + local + " (stale, 1h30m0s old)\n",
+		},
+		{
+			name:    "forecast",
+			command: runForecast,
+			args:    []string{"-days", "2", "London"},
+			expected: "London, UK\n" +
+				"DATE        MIN    MAX     AVG     HUMIDITY  RAIN  CONDITIONS\n" +
+				"2026-03-15  8.0°C  14.5°C  11.2°C  75%       60%   Patchy rain\n" +
+				"2026-03-16  6.0°C  12.0°C  9.0°C   70%       10%   Sunny\n",
+		},
+		{
+			name:    "search",
+			command: runSearch,
+			args:    []string{"London"},
+			expected: "NAME    REGION          COUNTRY  LAT      LON\n" +
+				"London  City of London  UK       51.5200  -0.1100\n",
+		},
+		{
+			name:     "search without results as JSON",
+			command:  runSearch,
+			args:     []string{"-json", "nowhere"},
+			expected: "[]\n",
+		},
+		{
+			name:    "history",
+			command: runHistory,
+			args:    []string{"-limit", "1"},
+			expected: "OBSERVED             CITY    COUNTRY  TEMPERATURE  HUMIDITY  CONDITIONS\n" +
+				local + "  London  UK       15.0°C       80%       Light rain\n",
+		},
+		{
+			name:          "server error",
+			command:       runGet,
+			args:          []string{"Atlantis"},
+			expectedError: "city not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := tt.command(context.Background(), append([]string{"-server", server.URL}, tt.args...), &stdout)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, stdout.String())
+		})
+	}
+
+	t.Run("get as JSON", func(t *testing.T) {
+		var stdout bytes.Buffer
+		require.NoError(t, runGet(context.Background(), []string{"-server", server.URL, "-json", "London"}, &stdout))
+
+		var weatherData models.WeatherData
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &weatherData))
+		assert.Equal(t, "London", weatherData.City)
+		assert.Equal(t, 15.25, weatherData.Temperature)
+		assert.Contains(t, stdout.String(), "\n  \"city\": \"London\"")
+	})
+}
+
+func TestLookupFlags_OpenBackend(t *testing.T) {
+	// The local backend must not need a WeatherAPI key to open
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "weather.db"))
+	t.Setenv("WEATHERAPI_KEY", "")
+	t.Setenv("WEATHERAPI_KEYS", "")
+
+	tests := []struct {
+		name          string
+		server        string
+		expectedLocal bool
+		expectedError string
+	}{
+		{name: "local without server", expectedLocal: true},
+		{name: "remote with server", server: "http://localhost:8080"},
+		{name: "remote with trailing slash", server: "https://weather.example.com/"},
+		{name: "server without scheme", server: "localhost:8080", expectedError: "-server must be an http(s) URL"},
+		{name: "server with other scheme", server: "ftp://weather.example.com", expectedError: "-server must be an http(s) URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := &lookupFlags{server: tt.server, timeout: time.Second}
+			backend, err := flags.openBackend()
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			defer backend.Close()
+
+			if tt.expectedLocal {
+				assert.IsType(t, &localBackend{}, backend)
+			} else {
+				assert.IsType(t, &remoteBackend{}, backend)
+			}
+		})
+	}
+}
+
+func TestLocalBackend_WithoutWeatherKey(t *testing.T) {
+	t.Setenv("WEATHER_SERVER", "")
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "weather.db"))
+	t.Setenv("WEATHERAPI_KEY", "")
+	t.Setenv("WEATHERAPI_KEYS", "")
+
+	// History only reads the database
+	var stdout bytes.Buffer
+	require.NoError(t, runHistory(context.Background(), []string{"-json"}, &stdout))
+	assert.Equal(t, "[]\n", stdout.String())
+
+	// Lookups need the upstream and say why they cannot run
+	for _, command := range []func(context.Context, []string, io.Writer) error{runGet, runForecast, runSearch} {
+		err := command(context.Background(), []string{"London"}, io.Discard)
+		assert.ErrorIs(t, err, errNoWeatherKey)
+	}
+}
+
+func TestRunImport_WithoutWeatherKey(t *testing.T) {
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "weather.db"))
+	t.Setenv("WEATHERAPI_KEY", "")
+	t.Setenv("WEATHERAPI_KEYS", "")
+
+	input := filepath.Join(t.TempDir(), "observations.ndjson")
+	line := `{"city":"London","country":"UK","temperature":15,"timestamp":"2026-03-15T12:00:00Z"}` + "\n"
+	require.NoError(t, os.WriteFile(input, []byte(line), 0o600))
+
+	var stdout bytes.Buffer
+	require.NoError(t, runImport(context.Background(), []string{input}, &stdout))
+
+	var report models.ImportReport
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.Equal(t, 1, report.Imported)
+}
@@ -1,60 +1,354 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 
 	"weather-dashboard/config"
 	"weather-dashboard/handlers"
+	"weather-dashboard/logging"
+	"weather-dashboard/metrics"
+	"weather-dashboard/middleware"
+	"weather-dashboard/rpc"
 	"weather-dashboard/services"
+	"weather-dashboard/tracing"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			// Keep stdout for the command's output and only log problems
+			logging.Setup(os.Stderr, "warn")
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			err := command(ctx, os.Args[2:], os.Stdout)
+			stop()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[1], logging.Redact(err.Error()))
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Parse(os.Args[1:])
+	if err != nil {
+		slog.Error("failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	logging.Setup(os.Stdout, cfg.Log.Level)
+	logging.AddSecrets(cfg.Secrets()...)
+
+	// Stop on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, config.NewReloader(cfg, os.Args[1:])); err != nil {
+		slog.Error("server error", "error", err)
+		os.Exit(1)
+	}
+
+	slog.Info("server stopped")
+}
+
+// run starts the server and blocks until ctx is cancelled, then drains
+// in-flight requests and releases resources
+func run(ctx context.Context, reloader *config.Reloader) error {
+	cfg := reloader.Current()
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		return fmt.Errorf("failed to initialize tracing: %w", err)
 	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}()
 
 	// Initialize database service
 	dbService, err := services.NewDatabaseService(cfg.Database.Path)
 	if err != nil {
-		log.Fatalf("Failed to initialize database service: %v", err)
+		return fmt.Errorf("failed to initialize database service: %w", err)
 	}
 	defer dbService.Close()
+	dbService.SetHistoryLimit(cfg.Database.HistoryLimit)
 
-	// Initialize weather service
+	// Initialize weather service with upstream quota accounting
+	quotaTracker := services.NewQuotaTracker(services.ProviderWeatherAPI, cfg.Quota, dbService)
 	weatherService := services.NewWeatherService(&cfg.Weather)
+	weatherService.SetQuota(quotaTracker, dbService)
+	// Runs before the database closes, as refreshes write to it
+	defer weatherService.Close()
 
 	// Initialize handlers
-	weatherHandler := handlers.NewWeatherHandler(weatherService, dbService)
+	api := apiHandlers{
+		weather: handlers.NewWeatherHandler(weatherService, dbService),
+		admin:   handlers.NewAdminHandler(quotaTracker, reloader),
+		imports: handlers.NewImportHandler(services.NewImporter(dbService)),
+		graphql: handlers.NewGraphQLHandler(weatherService, dbService, handlers.GraphQLLimits{
+			MaxDepth:      cfg.GraphQL.MaxDepth,
+			MaxComplexity: cfg.GraphQL.MaxComplexity,
+		}),
+	}
+
+	healthCheckers := map[string]handlers.HealthCheckerInterface{
+		"database": dbService,
+	}
+	if cfg.Health.CheckUpstream {
+		healthCheckers[services.ProviderWeatherAPI] = weatherService
+	}
+	healthHandler := handlers.NewHealthHandler(healthCheckers)
+
+	limiters := middleware.NewRateLimiters(cfg.RateLimit)
+
+	// Apply reloadable settings on SIGHUP or config file changes
+	reloader.OnReload(func(next *config.Config) {
+		logging.AddSecrets(next.Secrets()...)
+		logging.SetLevel(next.Log.Level)
+		weatherService.Reconfigure(&next.Weather)
+		limiters.Update(next.RateLimit)
+	})
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// Background jobs are stopped and joined before the database closes,
+	// including when run returns early on a server error
+	backgroundCtx, stopBackground := context.WithCancel(ctx)
+	var background sync.WaitGroup
+	defer func() {
+		stopBackground()
+		background.Wait()
+	}()
+
+	background.Add(2)
+	go func() {
+		defer background.Done()
+		reloader.Watch(backgroundCtx, hup)
+	}()
+
+	// Roll up and prune old observations in the background
+	go func() {
+		defer background.Done()
+		services.NewRetentionJob(cfg.Retention, dbService).Run(backgroundCtx)
+	}()
 
 	// Setup Gin router
-	r := gin.Default()
+	r := gin.New()
+	// gin trusts X-Forwarded-For from any peer unless told otherwise, which
+	// would let clients pick the IP they are rate limited by
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	r.Use(middleware.Recovery(), middleware.RequestID(), tracing.Middleware(), middleware.AccessLog(), metrics.Middleware())
+	r.Use(middleware.CORS(cfg.Server.CORSOrigins))
 	r.LoadHTMLGlob("templates/*")
-	r.Static("/static", "./static")
 
 	// Setup routes
-	setupRoutes(r, weatherHandler)
+	setupRoutes(r, api, healthHandler, limiters, cfg)
+
+	srv := &http.Server{
+		Addr:              cfg.GetServerAddress(),
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+	}
 
 	// Start server
-	log.Printf("Server starting on %s", cfg.GetServerAddress())
-	if err := r.Run(cfg.GetServerAddress()); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("server starting", "address", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	// Start the gRPC server on its own port if enabled
+	var grpcServer *grpc.Server
+	grpcErr := make(chan error, 1)
+	if cfg.GRPC.Port != "" {
+		listener, err := net.Listen("tcp", cfg.GetGRPCAddress())
+		if err != nil {
+			return fmt.Errorf("failed to start gRPC server: %w", err)
+		}
+		grpcServer = rpc.NewGRPCServer(rpc.NewServer(weatherService, dbService, rpc.StreamLimits{
+			Interval:   cfg.GRPC.StreamInterval,
+			MaxStreams: cfg.GRPC.MaxStreams,
+			MaxCities:  cfg.GRPC.MaxStreamCities,
+			CacheTTL:   cfg.Weather.CacheTTL,
+		}), limiters)
+		go func() {
+			slog.Info("gRPC server starting", "address", listener.Addr().String())
+			if err := grpcServer.Serve(listener); err != nil {
+				grpcErr <- err
+			}
+			close(grpcErr)
+		}()
+	}
+
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("failed to start server: %w", err)
+	case err, ok := <-grpcErr:
+		if ok {
+			return fmt.Errorf("failed to serve gRPC: %w", err)
+		}
+	case <-ctx.Done():
+	}
+
+	// Drain in-flight requests
+	slog.Info("shutting down server", "timeout", cfg.Server.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	grpcStopped := make(chan struct{})
+	go func() {
+		if grpcServer != nil {
+			stopGRPC(shutdownCtx, grpcServer)
+		}
+		close(grpcStopped)
+	}()
+	// The database closes on return, so wait for gRPC calls too
+	defer func() { <-grpcStopped }()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down server: %w", err)
 	}
+
+	return nil
+}
+
+// stopGRPC drains in-flight calls, closing the remaining ones, such as
+// long-lived streams, once ctx is done
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+// API prefixes. /api/v1 is canonical; the unversioned /api paths are
+// deprecated aliases of v1 kept until legacyAPISunset. The API docs describe
+// every version, so they stay at /api for good.
+const (
+	legacyAPIPrefix = "/api"
+	apiV1Prefix     = "/api/v1"
+	apiDocsPrefix   = "/api"
+)
+
+var (
+	legacyAPIDeprecated = time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	legacyAPISunset     = time.Date(2027, 5, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// apiHandlers groups the handlers serving the versioned API
+type apiHandlers struct {
+	weather *handlers.WeatherHandler
+	admin   *handlers.AdminHandler
+	imports *handlers.ImportHandler
+	graphql *handlers.GraphQLHandler
+}
+
+// apiVersion is a versioned route tree under its own prefix
+type apiVersion struct {
+	prefix   string
+	register func(api *gin.RouterGroup, h apiHandlers, limiters *middleware.RateLimiters, cfg *config.Config)
+}
+
+// apiVersions lists the served API versions. Each registers its own routes,
+// so a new version with its own handlers and response models (such as a
+// different WeatherData shape) can be added without changing older ones.
+var apiVersions = []apiVersion{
+	{prefix: apiV1Prefix, register: registerAPIV1},
 }
 
 // setupRoutes configures all application routes
-func setupRoutes(r *gin.Engine, weatherHandler *handlers.WeatherHandler) {
-	// Main page
-	r.GET("/", weatherHandler.ServeIndex)
+func setupRoutes(r *gin.Engine, api apiHandlers, healthHandler *handlers.HealthHandler, limiters *middleware.RateLimiters, cfg *config.Config) {
+	// Probes and Prometheus metrics
+	r.GET("/healthz", healthHandler.Healthz)
+	r.GET("/readyz", healthHandler.Readyz)
+	r.GET("/metrics", metrics.Handler())
+
+	// Main page and static files
+	pages := r.Group("/", limiters.Middleware(limiters.General))
+	{
+		pages.GET("/", api.weather.ServeIndex)
+		pages.Static("/static", "./static")
+	}
+
+	// Versioned API routes
+	for _, version := range apiVersions {
+		version.register(r.Group(version.prefix, limiters.Middleware(limiters.API)), api, limiters, cfg)
+	}
+
+	// API docs, with aliases under /api/v1
+	for _, prefix := range []string{apiDocsPrefix, apiV1Prefix} {
+		registerAPIDocs(r.Group(prefix, limiters.Middleware(limiters.API)))
+	}
+
+	// Unversioned aliases of v1
+	legacy := r.Group(legacyAPIPrefix,
+		middleware.Deprecated(legacyAPIDeprecated, legacyAPISunset, legacyAPISuccessor),
+		limiters.Middleware(limiters.API))
+	registerAPIV1(legacy, api, limiters, cfg)
+
+	// GraphQL evolves its schema in place, so it is not versioned. One
+	// query can fan out like a batch, so it shares the batch limit.
+	r.POST("/graphql", limiters.Middleware(limiters.API), limiters.Middleware(limiters.Batch), api.graphql.Query)
+}
+
+// legacyAPISuccessor maps an unversioned API path to its v1 path
+func legacyAPISuccessor(path string) string {
+	return apiV1Prefix + strings.TrimPrefix(path, legacyAPIPrefix)
+}
+
+// registerAPIDocs registers the OpenAPI document and the docs page on api.
+// Keep handlers/openapi.json in step with the routes in setupRoutes.
+func registerAPIDocs(api *gin.RouterGroup) {
+	api.GET("/openapi.json", handlers.ServeOpenAPI)
+	api.GET("/docs", handlers.ServeAPIDocs)
+}
+
+// registerAPIV1 registers the v1 API routes on api
+func registerAPIV1(api *gin.RouterGroup, h apiHandlers, limiters *middleware.RateLimiters, cfg *config.Config) {
+	api.GET("/weather/:city", h.weather.GetWeatherByCity)
+	api.POST("/weather/batch", limiters.Middleware(limiters.Batch), h.weather.GetWeatherBatch)
+	api.GET("/weather/coordinates/:lat/:lon", h.weather.GetWeatherByCoordinates)
+	api.GET("/forecast/:city", h.weather.GetForecast)
+	api.GET("/search", h.weather.SearchCities)
+	api.GET("/history", h.weather.GetWeatherHistory)
+	api.GET("/history/export", h.weather.ExportWeatherHistory)
+	api.POST("/history/import", middleware.AdminAuth(cfg.Admin.Token), limiters.Middleware(limiters.Batch), h.imports.ImportWeatherHistory)
 
-	// API routes
-	api := r.Group("/api")
+	// Admin routes
+	admin := api.Group("/admin", middleware.AdminAuth(cfg.Admin.Token))
 	{
-		api.GET("/weather/:city", weatherHandler.GetWeatherByCity)
-		api.GET("/weather/coordinates/:lat/:lon", weatherHandler.GetWeatherByCoordinates)
-		api.GET("/history", weatherHandler.GetWeatherHistory)
+		admin.GET("/usage", h.admin.GetUsage)
+		admin.GET("/config", h.admin.GetConfigVersion)
 	}
 }
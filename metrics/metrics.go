@@ -0,0 +1,188 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "weather_dashboard"
+
+// Registry holds all application metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts handled HTTP requests per route
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes HTTP request latency per route
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// RPCRequests counts handled gRPC calls per method
+	RPCRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "Total number of gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+
+	// RPCDuration observes gRPC call latency per method. Streams are
+	// observed when they end.
+	RPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC call latency by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// RPCActiveStreams reports the number of open gRPC streams
+	RPCActiveStreams = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "grpc_active_streams",
+		Help:      "Number of open gRPC streams.",
+	})
+
+	// UpstreamRequests counts calls to weather providers
+	UpstreamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Total number of upstream API calls by provider and endpoint.",
+	}, []string{"provider", "endpoint"})
+
+	// UpstreamErrors counts failed calls to weather providers
+	UpstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_errors_total",
+		Help:      "Total number of failed upstream API calls by provider and endpoint.",
+	}, []string{"provider", "endpoint"})
+
+	// UpstreamDuration observes upstream call latency
+	UpstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Upstream API call latency by provider and endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider", "endpoint"})
+
+	// UpstreamRetries counts retried upstream calls
+	UpstreamRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_retries_total",
+		Help:      "Total number of retried upstream API calls by provider and endpoint.",
+	}, []string{"provider", "endpoint"})
+
+	// CircuitBreakerState reports each provider's circuit breaker state
+	CircuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "Upstream circuit breaker state by provider (0 closed, 1 half-open, 2 open).",
+	}, []string{"provider"})
+
+	// CacheLookups counts lookups of stored observations by result (hit or miss)
+	CacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Total number of cached weather lookups by result.",
+	}, []string{"result"})
+
+	// DBQueryDuration observes SQLite query latency
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "SQLite query latency by query name.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"query"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		RPCRequests,
+		RPCDuration,
+		RPCActiveStreams,
+		UpstreamRequests,
+		UpstreamErrors,
+		UpstreamDuration,
+		UpstreamRetries,
+		CircuitBreakerState,
+		CacheLookups,
+		DBQueryDuration,
+	)
+}
+
+// Middleware records request counts and latency per gin route
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		HTTPDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() gin.HandlerFunc {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	return gin.WrapH(h)
+}
+
+// ObserveRPC records a finished gRPC call with its status code name, such
+// as "OK" or "NotFound"
+func ObserveRPC(method, code string, start time.Time) {
+	RPCRequests.WithLabelValues(method, code).Inc()
+	RPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// ObserveUpstream records a single upstream call
+func ObserveUpstream(provider, endpoint string, start time.Time, err error) {
+	UpstreamRequests.WithLabelValues(provider, endpoint).Inc()
+	UpstreamDuration.WithLabelValues(provider, endpoint).Observe(time.Since(start).Seconds())
+	if err != nil {
+		UpstreamErrors.WithLabelValues(provider, endpoint).Inc()
+	}
+}
+
+// ObserveRetry records a retried upstream call
+func ObserveRetry(provider, endpoint string) {
+	UpstreamRetries.WithLabelValues(provider, endpoint).Inc()
+}
+
+// SetBreakerState records a provider's circuit breaker state
+func SetBreakerState(provider string, state int) {
+	CircuitBreakerState.WithLabelValues(provider).Set(float64(state))
+}
+
+// ObserveQuery records the duration of a named database query
+func ObserveQuery(query string, start time.Time) {
+	DBQueryDuration.WithLabelValues(query).Observe(time.Since(start).Seconds())
+}
+
+// ObserveCacheLookup records a cache hit or miss
+func ObserveCacheLookup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	CacheLookups.WithLabelValues(result).Inc()
+}
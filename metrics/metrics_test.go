@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/api/weather/:city", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/api/weather/london", "/api/weather/paris", "/missing"} {
+		req, err := http.NewRequest("GET", path, nil)
+		require.NoError(t, err)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Requests are labelled by route template, not by raw path
+	assert.Equal(t, 2.0, testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "/api/weather/:city", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "unmatched", "404")))
+}
+
+func TestObserveRPC(t *testing.T) {
+	ObserveRPC("/weather.v1.WeatherService/GetCurrent", "OK", time.Now())
+	ObserveRPC("/weather.v1.WeatherService/GetCurrent", "NotFound", time.Now())
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(RPCRequests.WithLabelValues("/weather.v1.WeatherService/GetCurrent", "OK")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(RPCRequests.WithLabelValues("/weather.v1.WeatherService/GetCurrent", "NotFound")))
+}
+
+func TestObserveUpstream(t *testing.T) {
+	ObserveUpstream("test", "search", time.Now(), nil)
+	ObserveUpstream("test", "search", time.Now(), errors.New("boom"))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(UpstreamRequests.WithLabelValues("test", "search")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(UpstreamErrors.WithLabelValues("test", "search")))
+}
+
+func TestObserveCacheLookup(t *testing.T) {
+	hits := testutil.ToFloat64(CacheLookups.WithLabelValues("hit"))
+	misses := testutil.ToFloat64(CacheLookups.WithLabelValues("miss"))
+
+	ObserveCacheLookup(true)
+	ObserveCacheLookup(false)
+	ObserveCacheLookup(false)
+
+	assert.Equal(t, hits+1, testutil.ToFloat64(CacheLookups.WithLabelValues("hit")))
+	assert.Equal(t, misses+2, testutil.ToFloat64(CacheLookups.WithLabelValues("miss")))
+}
+
+func TestHandler(t *testing.T) {
+	ObserveQuery("test_query", time.Now())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/metrics", Handler())
+
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `weather_dashboard_db_query_duration_seconds_count{query="test_query"} 1`)
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
+
+func TestObserveRetryAndBreakerState(t *testing.T) {
+	ObserveRetry("test", "current")
+	SetBreakerState("test", 2)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(UpstreamRetries.WithLabelValues("test", "current")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(CircuitBreakerState.WithLabelValues("test")))
+}
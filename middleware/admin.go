@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"weather-dashboard/models"
+)
+
+// AdminAuth returns a middleware requiring "Authorization: Bearer <token>".
+// Admin routes are disabled entirely when no token is configured.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.APIError{Error: "admin endpoints are disabled", Code: models.ErrorCodeForbidden})
+			return
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.APIError{Error: "invalid admin token", Code: models.ErrorCodeUnauthorized})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminAuth(t *testing.T) {
+	tests := []struct {
+		name           string
+		token          string
+		header         string
+		expectedStatus int
+	}{
+		{name: "valid token", token: "secret", header: "Bearer secret", expectedStatus: http.StatusOK},
+		{name: "wrong token", token: "secret", header: "Bearer nope", expectedStatus: http.StatusUnauthorized},
+		{name: "missing header", token: "secret", header: "", expectedStatus: http.StatusUnauthorized},
+		{name: "disabled without token", token: "", header: "Bearer ", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.GET("/admin", AdminAuth(tt.token), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req, err := http.NewRequest("GET", "/admin", nil)
+			require.NoError(t, err)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name           string
+		origins        []string
+		method         string
+		origin         string
+		preflight      bool
+		expectedStatus int
+		expectedAllow  string
+	}{
+		{name: "allowed origin", origins: []string{"https://app.example.com"}, method: "GET", origin: "https://app.example.com", expectedStatus: http.StatusOK, expectedAllow: "https://app.example.com"},
+		{name: "other origin", origins: []string{"https://app.example.com"}, method: "GET", origin: "https://evil.example.com", expectedStatus: http.StatusOK, expectedAllow: ""},
+		{name: "wildcard", origins: []string{"*"}, method: "GET", origin: "https://any.example.com", expectedStatus: http.StatusOK, expectedAllow: "*"},
+		{name: "disabled", origins: nil, method: "GET", origin: "https://app.example.com", expectedStatus: http.StatusOK, expectedAllow: ""},
+		{name: "preflight", origins: []string{"https://app.example.com"}, method: "OPTIONS", origin: "https://app.example.com", preflight: true, expectedStatus: http.StatusNoContent, expectedAllow: "https://app.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(CORS(tt.origins))
+			r.GET("/api/history", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req, err := http.NewRequest(tt.method, "/api/history", nil)
+			require.NoError(t, err)
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "GET")
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedAllow, w.Header().Get("Access-Control-Allow-Origin"))
+			if tt.preflight {
+				assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), APIKeyHeader)
+			}
+		})
+	}
+}
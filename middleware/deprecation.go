@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated returns a middleware marking responses of deprecated routes
+// with a Deprecation header (RFC 9745) giving when they were deprecated, a
+// Sunset header (RFC 8594) giving when they will be removed and, if
+// successor returns a path, a Link to the replacement
+func Deprecated(deprecatedAt, sunset time.Time, successor func(path string) string) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Sunset", sunsetDate)
+		if successor != nil {
+			if path := successor(c.Request.URL.Path); path != "" {
+				c.Header("Link", "<"+path+`>; rel="successor-version"`)
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecated(t *testing.T) {
+	deprecatedAt := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 5, 1, 0, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	successor := func(path string) string {
+		if path == "/old/gone" {
+			return ""
+		}
+		return "/new" + strings.TrimPrefix(path, "/old")
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	old := r.Group("/old", Deprecated(deprecatedAt, sunset, successor))
+	old.GET("/weather", func(c *gin.Context) { c.Status(http.StatusOK) })
+	old.GET("/gone", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/new/weather", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name       string
+		path       string
+		deprecated bool
+		link       string
+	}{
+		{name: "deprecated route", path: "/old/weather", deprecated: true, link: `</new/weather>; rel="successor-version"`},
+		{name: "deprecated route without successor", path: "/old/gone", deprecated: true},
+		{name: "current route", path: "/new/weather"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tt.path, nil)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			if tt.deprecated {
+				assert.Equal(t, "@1793491200", w.Header().Get("Deprecation"))
+				assert.Equal(t, "Fri, 30 Apr 2027 22:00:00 GMT", w.Header().Get("Sunset"))
+			} else {
+				assert.Empty(t, w.Header().Get("Deprecation"))
+				assert.Empty(t, w.Header().Get("Sunset"))
+			}
+			assert.Equal(t, tt.link, w.Header().Get("Link"))
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"weather-dashboard/config"
+	"weather-dashboard/models"
+)
+
+// APIKeyHeader is the header clients use to identify themselves for rate
+// limiting. Only configured keys are honoured.
+const APIKeyHeader = "X-API-Key"
+
+// sweepInterval controls how often idle buckets are removed
+const sweepInterval = time.Minute
+
+// bucket is a single client's token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter is an in-process token bucket rate limiter keyed by client
+type RateLimiter struct {
+	rule config.RateLimitRule
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewRateLimiter creates a new rate limiter for the given rule
+func NewRateLimiter(rule config.RateLimitRule) *RateLimiter {
+	return &RateLimiter{
+		rule:      rule,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Decision is the outcome of taking a token from a client's bucket
+type Decision struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left
+	Remaining int
+	// RetryAfter is how long until the next token becomes available, or 0
+	// if the request was allowed
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again
+	ResetAfter time.Duration
+}
+
+// Allow takes a token from the client's bucket
+func (l *RateLimiter) Allow(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(l.rule.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	// Refill based on elapsed time
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(float64(l.rule.Burst), b.tokens+elapsed*l.rule.Rate)
+	b.last = now
+
+	decision := Decision{Allowed: b.tokens >= 1}
+	if decision.Allowed {
+		b.tokens--
+		decision.Remaining = int(b.tokens)
+	} else {
+		decision.RetryAfter = l.refillTime(1 - b.tokens)
+	}
+	decision.ResetAfter = l.refillTime(float64(l.rule.Burst) - b.tokens)
+	return decision
+}
+
+// refillTime returns how long the bucket takes to gain tokens. A bucket
+// that never refills is reported as refilling after sweepInterval. The
+// caller must hold l.mu.
+func (l *RateLimiter) refillTime(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if l.rule.Rate <= 0 {
+		return sweepInterval
+	}
+	return time.Duration(tokens / l.rule.Rate * float64(time.Second))
+}
+
+// Limit returns the maximum burst size of the limiter
+func (l *RateLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rule.Burst
+}
+
+// SetRule changes the rate and burst size. Existing buckets keep their
+// tokens, capped at the new burst size on their next request.
+func (l *RateLimiter) SetRule(rule config.RateLimitRule) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rule = rule
+}
+
+// sweep removes buckets that have been idle long enough to be full again.
+// The caller must hold l.mu.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval || l.rule.Rate <= 0 {
+		return
+	}
+	l.lastSweep = now
+
+	refill := time.Duration(float64(l.rule.Burst) / l.rule.Rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// APIKeys is the set of client API keys that are rate limited per key. It
+// can be replaced while the server runs.
+type APIKeys struct {
+	digests atomic.Pointer[map[[sha256.Size]byte]bool]
+}
+
+// NewAPIKeys creates a key set holding keys
+func NewAPIKeys(keys []string) *APIKeys {
+	k := &APIKeys{}
+	k.Set(keys)
+	return k
+}
+
+// Set replaces the accepted keys
+func (k *APIKeys) Set(keys []string) {
+	digests := make(map[[sha256.Size]byte]bool, len(keys))
+	for _, key := range keys {
+		if key != "" {
+			digests[sha256.Sum256([]byte(key))] = true
+		}
+	}
+	k.digests.Store(&digests)
+}
+
+// Contains reports whether key is accepted. Keys are compared by digest so
+// the lookup time does not depend on how much of a guess matches.
+func (k *APIKeys) Contains(key string) bool {
+	return key != "" && (*k.digests.Load())[sha256.Sum256([]byte(key))]
+}
+
+// ClientKey identifies the caller by API key if it is one of keys, and
+// otherwise by IP. Unknown keys are ignored so that callers cannot get a
+// fresh bucket by sending a new key with each request. The IP comes from
+// X-Forwarded-For only when the engine trusts the proxy that sent it.
+func ClientKey(c *gin.Context, keys *APIKeys) string {
+	return clientKey(keys, c.GetHeader(APIKeyHeader), c.ClientIP())
+}
+
+// clientKey identifies a caller by apiKey if it is one of keys, and
+// otherwise by ip
+func clientKey(keys *APIKeys, apiKey, ip string) string {
+	if keys.Contains(apiKey) {
+		sum := sha256.Sum256([]byte(apiKey))
+		return "key:" + hex.EncodeToString(sum[:8])
+	}
+	return "ip:" + ip
+}
+
+// RateLimit returns a middleware enforcing the given limiter, with buckets
+// per accepted API key or client IP. Every response reports the limit, the
+// remaining tokens and when the bucket is full again (X-RateLimit-Reset);
+// rejected requests receive 429 with a Retry-After header.
+func RateLimit(limiter *RateLimiter, keys *APIKeys) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		decision := limiter.Allow(ClientKey(c, keys))
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limiter.Limit()))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(decision.ResetAfter).Unix(), 10))
+
+		if !decision.Allowed {
+			retryAfter := int(math.Ceil(decision.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, models.APIError{Error: "rate limit exceeded", Code: models.ErrorCodeRateLimited})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RateLimiters holds one limiter per route group so their rules can be
+// changed while the server runs
+type RateLimiters struct {
+	enabled bool
+	keys    *APIKeys
+	General *RateLimiter
+	API     *RateLimiter
+	Batch   *RateLimiter
+}
+
+// NewRateLimiters creates the route group limiters
+func NewRateLimiters(cfg config.RateLimitConfig) *RateLimiters {
+	return &RateLimiters{
+		enabled: cfg.Enabled,
+		keys:    NewAPIKeys(cfg.APIKeys),
+		General: NewRateLimiter(cfg.General),
+		API:     NewRateLimiter(cfg.API),
+		Batch:   NewRateLimiter(cfg.Batch),
+	}
+}
+
+// Update applies new rules and API keys to every limiter
+func (l *RateLimiters) Update(cfg config.RateLimitConfig) {
+	l.keys.Set(cfg.APIKeys)
+	l.General.SetRule(cfg.General)
+	l.API.SetRule(cfg.API)
+	l.Batch.SetRule(cfg.Batch)
+}
+
+// Middleware returns a middleware enforcing limiter, or a no-op middleware
+// when rate limiting is disabled
+func (l *RateLimiters) Middleware(limiter *RateLimiter) gin.HandlerFunc {
+	if !l.enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return RateLimit(limiter, l.keys)
+}
+
+// Allow takes a token from limiter for a caller outside gin, such as a gRPC
+// call, identified by apiKey if it is configured and otherwise by ip. It
+// reports whether the call may proceed and, if not, how long until it may.
+// Every call is allowed when rate limiting is disabled.
+func (l *RateLimiters) Allow(limiter *RateLimiter, apiKey, ip string) (bool, time.Duration) {
+	if !l.enabled {
+		return true, 0
+	}
+	decision := limiter.Allow(clientKey(l.keys, apiKey, ip))
+	return decision.Allowed, decision.RetryAfter
+}
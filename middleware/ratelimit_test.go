@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"weather-dashboard/config"
+	"weather-dashboard/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	limiter := NewRateLimiter(config.RateLimitRule{Rate: 1, Burst: 2})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	decision := limiter.Allow("client")
+	assert.Equal(t, Decision{Allowed: true, Remaining: 1, ResetAfter: time.Second}, decision)
+
+	decision = limiter.Allow("client")
+	assert.Equal(t, Decision{Allowed: true, Remaining: 0, ResetAfter: 2 * time.Second}, decision)
+
+	decision = limiter.Allow("client")
+	assert.Equal(t, Decision{Allowed: false, RetryAfter: time.Second, ResetAfter: 2 * time.Second}, decision)
+
+	// Other clients have their own bucket
+	assert.True(t, limiter.Allow("other").Allowed)
+
+	// Tokens refill over time
+	now = now.Add(1500 * time.Millisecond)
+	decision = limiter.Allow("client")
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, 1500*time.Millisecond, decision.ResetAfter)
+}
+
+func TestRateLimiter_Sweep(t *testing.T) {
+	limiter := NewRateLimiter(config.RateLimitRule{Rate: 1, Burst: 2})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("idle")
+	now = now.Add(2 * sweepInterval)
+	limiter.Allow("active")
+
+	assert.NotContains(t, limiter.buckets, "idle")
+	assert.Contains(t, limiter.buckets, "active")
+}
+
+func TestRateLimit_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RateLimit(NewRateLimiter(config.RateLimitRule{Rate: 0.5, Burst: 1}), NewAPIKeys([]string{"team-key"})))
+	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+
+	request := func(apiKey string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/ping", nil)
+		require.NoError(t, err)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if apiKey != "" {
+			req.Header.Set(APIKeyHeader, apiKey)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Every response says when the bucket is full again
+	resetAt := func(w *httptest.ResponseRecorder) time.Duration {
+		reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+		require.NoError(t, err)
+		return time.Until(time.Unix(reset, 0))
+	}
+
+	w := request("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.InDelta(t, 2*time.Second, resetAt(w), float64(time.Second))
+
+	w = request("")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.InDelta(t, 2*time.Second, resetAt(w), float64(time.Second))
+	assert.Contains(t, w.Body.String(), "rate limit exceeded")
+	assert.Contains(t, w.Body.String(), models.ErrorCodeRateLimited)
+
+	// Unknown keys do not escape the IP's bucket
+	w = request("random-key-1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	w = request("random-key-2")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// A client with a configured API key is limited separately from its IP
+	w = request("team-key")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = request("team-key")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestClientKey(t *testing.T) {
+	keys := NewAPIKeys([]string{"team-key", ""})
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		apiKey         string
+		forwardedFor   string
+		expected       string
+	}{
+		{name: "remote IP", expected: "ip:192.0.2.1"},
+		{name: "untrusted X-Forwarded-For is ignored", forwardedFor: "203.0.113.9", expected: "ip:192.0.2.1"},
+		{name: "trusted proxy", trustedProxies: []string{"192.0.2.0/24"}, forwardedFor: "203.0.113.9", expected: "ip:203.0.113.9"},
+		{name: "unknown API key", apiKey: "guess", expected: "ip:192.0.2.1"},
+		{name: "configured API key", apiKey: "team-key", forwardedFor: "203.0.113.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			require.NoError(t, r.SetTrustedProxies(tt.trustedProxies))
+
+			var key string
+			r.GET("/ping", func(c *gin.Context) { key = ClientKey(c, keys) })
+
+			req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tt.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tt.expected == "" {
+				assert.True(t, strings.HasPrefix(key, "key:"), key)
+				assert.NotContains(t, key, tt.apiKey)
+				return
+			}
+			assert.Equal(t, tt.expected, key)
+		})
+	}
+}
+
+func TestRateLimiters_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiters := NewRateLimiters(config.RateLimitConfig{Enabled: false, API: config.RateLimitRule{Rate: 1, Burst: 1}})
+	r := gin.New()
+	r.Use(limiters.Middleware(limiters.API))
+	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+
+	for i := 0; i < 5; i++ {
+		req, err := http.NewRequest("GET", "/ping", nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+	}
+}
+
+func TestRateLimiter_SetRule(t *testing.T) {
+	limiter := NewRateLimiter(config.RateLimitRule{Rate: 0.001, Burst: 1})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	assert.True(t, limiter.Allow("client").Allowed)
+	assert.False(t, limiter.Allow("client").Allowed)
+
+	limiter.SetRule(config.RateLimitRule{Rate: 1000, Burst: 5})
+	assert.Equal(t, 5, limiter.Limit())
+
+	now = now.Add(time.Second)
+	for i := 0; i < 5; i++ {
+		assert.True(t, limiter.Allow("client").Allowed)
+	}
+}
+
+func TestRateLimiters(t *testing.T) {
+	limiters := NewRateLimiters(config.RateLimitConfig{
+		Enabled: true,
+		General: config.RateLimitRule{Rate: 1, Burst: 1},
+		API:     config.RateLimitRule{Rate: 1, Burst: 2},
+		Batch:   config.RateLimitRule{Rate: 1, Burst: 3},
+	})
+	assert.Equal(t, 2, limiters.API.Limit())
+	assert.False(t, limiters.keys.Contains("new-key"))
+
+	limiters.Update(config.RateLimitConfig{
+		Enabled: true,
+		APIKeys: []string{"new-key"},
+		General: config.RateLimitRule{Rate: 1, Burst: 10},
+		API:     config.RateLimitRule{Rate: 1, Burst: 20},
+		Batch:   config.RateLimitRule{Rate: 1, Burst: 30},
+	})
+	assert.Equal(t, 10, limiters.General.Limit())
+	assert.Equal(t, 20, limiters.API.Limit())
+	assert.Equal(t, 30, limiters.Batch.Limit())
+	assert.True(t, limiters.keys.Contains("new-key"))
+}
+
+func TestRateLimiters_Allow(t *testing.T) {
+	limiters := NewRateLimiters(config.RateLimitConfig{
+		Enabled: true,
+		APIKeys: []string{"team-key"},
+		API:     config.RateLimitRule{Rate: 0.001, Burst: 1},
+	})
+
+	allowed, _ := limiters.Allow(limiters.API, "", "192.0.2.1")
+	assert.True(t, allowed)
+	allowed, wait := limiters.Allow(limiters.API, "guess", "192.0.2.1")
+	assert.False(t, allowed, "unknown keys share the IP bucket")
+	assert.Positive(t, wait)
+
+	allowed, _ = limiters.Allow(limiters.API, "team-key", "192.0.2.1")
+	assert.True(t, allowed, "configured keys get their own bucket")
+
+	disabled := NewRateLimiters(config.RateLimitConfig{Enabled: false, API: config.RateLimitRule{Rate: 0.001, Burst: 1}})
+	for i := 0; i < 3; i++ {
+		allowed, _ = disabled.Allow(disabled.API, "", "192.0.2.1")
+		assert.True(t, allowed)
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+
+	"weather-dashboard/models"
+)
+
+// Recovery returns a middleware that turns a panic into a 500 response and
+// logs it with its stack. http.ErrAbortHandler is passed on to net/http,
+// which resets the connection, so a handler can signal that a response it
+// has already started is incomplete.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+
+			slog.ErrorContext(c.Request.Context(), "panic recovered",
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"panic", fmt.Sprint(r),
+				"stack", string(debug.Stack()),
+			)
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.APIError{
+				Error: "internal server error",
+				Code:  models.ErrorCodeInternal,
+			})
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"weather-dashboard/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery())
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	r.GET("/abort", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Writer.WriteString("partial")
+		c.Writer.Flush()
+		panic(http.ErrAbortHandler)
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("panic becomes a 500", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/panic")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		var response models.APIError
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		assert.Equal(t, models.ErrorCodeInternal, response.Code)
+	})
+
+	t.Run("abort resets the connection", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/abort")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		_, err = io.ReadAll(resp.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"weather-dashboard/logging"
+)
+
+// RequestIDHeader carries the request ID between clients, proxies and this service
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern limits propagated IDs to a safe charset and length
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// ValidRequestID reports whether an incoming request ID is safe to
+// propagate and log
+func ValidRequestID(requestID string) bool {
+	return requestIDPattern.MatchString(requestID)
+}
+
+// RequestID returns a middleware that propagates a valid incoming
+// X-Request-ID or generates a new one, echoes it in the response and
+// attaches it to the request context for logging
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !ValidRequestID(requestID) {
+			requestID = NewRequestID()
+		}
+
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+
+		c.Next()
+	}
+}
+
+// AccessLog returns a middleware logging one structured line per request
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelError
+		}
+
+		slog.Log(c.Request.Context(), level, "request completed",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		)
+	}
+}
+
+// NewRequestID generates a random 128-bit hex request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"weather-dashboard/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, logging.RequestID(c.Request.Context()))
+	})
+
+	tests := []struct {
+		name      string
+		incoming  string
+		propagate bool
+	}{
+		{name: "propagates valid id", incoming: "abc-123", propagate: true},
+		{name: "generates when missing", incoming: ""},
+		{name: "replaces invalid id", incoming: "bad id\nwith newline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/ping", nil)
+			require.NoError(t, err)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			requestID := w.Header().Get(RequestIDHeader)
+			assert.Equal(t, requestID, w.Body.String())
+			if tt.propagate {
+				assert.Equal(t, tt.incoming, requestID)
+			} else {
+				assert.Len(t, requestID, 32)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	var buf bytes.Buffer
+	logging.Setup(&buf, "info")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID(), AccessLog())
+	r.GET("/api/weather/:city", func(c *gin.Context) { c.Status(http.StatusTeapot) })
+
+	req, err := http.NewRequest("GET", "/api/weather/london", nil)
+	require.NoError(t, err)
+	req.Header.Set(RequestIDHeader, "trace-me")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "request completed", line["msg"])
+	assert.Equal(t, "trace-me", line["request_id"])
+	assert.Equal(t, "/api/weather/:city", line["route"])
+	assert.Equal(t, "/api/weather/london", line["path"])
+	assert.Equal(t, float64(http.StatusTeapot), line["status"])
+}
@@ -0,0 +1,41 @@
+package models
+
+import "errors"
+
+// Errors returned by services. Handlers map them to HTTP statuses and
+// stable error codes; wrap them with context using fmt.Errorf and %w.
+var (
+	// ErrNotFound is returned when the requested location does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrInvalidInput is returned when a request parameter is malformed
+	ErrInvalidInput = errors.New("invalid input")
+
+	// ErrUpstreamUnavailable is returned when the weather provider cannot be
+	// reached or returns an unusable response
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+
+	// ErrUnauthorized is returned when the weather provider rejects our
+	// credentials
+	ErrUnauthorized = errors.New("upstream rejected credentials")
+
+	// ErrQuotaExceeded is returned when the configured upstream call budget
+	// is used up, or the provider reports its own limit was reached
+	ErrQuotaExceeded = errors.New("upstream quota exceeded")
+)
+
+// Error codes returned in APIError.Code. They are part of the API and must
+// not change.
+const (
+	ErrorCodeInvalidInput         = "invalid_input"
+	ErrorCodeNotFound             = "not_found"
+	ErrorCodeQuotaExceeded        = "quota_exceeded"
+	ErrorCodeRateLimited          = "rate_limited"
+	ErrorCodeUnauthorized         = "unauthorized"
+	ErrorCodeForbidden            = "forbidden"
+	ErrorCodeUpstreamUnavailable  = "upstream_unavailable"
+	ErrorCodeUpstreamUnauthorized = "upstream_unauthorized"
+	ErrorCodeInternal             = "internal_error"
+	ErrorCodeInvalidQuery         = "invalid_query"
+	ErrorCodeQueryTooComplex      = "query_too_complex"
+)
@@ -14,6 +14,9 @@ type WeatherData struct {
 	Icon          string    `json:"icon"`
 	ConditionCode int       `json:"condition_code"`
 	Timestamp     time.Time `json:"timestamp"`
+	Cached        bool      `json:"cached,omitempty"`
+	Stale         bool      `json:"stale,omitempty"`
+	AgeSeconds    int64     `json:"age_seconds,omitempty"`
 }
 
 // WeatherAPISearchResult represents a search result from WeatherAPI
@@ -47,14 +50,264 @@ type WeatherAPICurrentResult struct {
 	} `json:"current"`
 }
 
+// WeatherAPIForecastResult represents a forecast from WeatherAPI
+type WeatherAPIForecastResult struct {
+	Location struct {
+		Name    string `json:"name"`
+		Region  string `json:"region"`
+		Country string `json:"country"`
+	} `json:"location"`
+	Forecast struct {
+		ForecastDay []struct {
+			Date string `json:"date"`
+			Day  struct {
+				MaxTempC          float64 `json:"maxtemp_c"`
+				MinTempC          float64 `json:"mintemp_c"`
+				AvgTempC          float64 `json:"avgtemp_c"`
+				AvgHumidity       float64 `json:"avghumidity"`
+				DailyChanceOfRain int     `json:"daily_chance_of_rain"`
+				Condition         struct {
+					Text string `json:"text"`
+					Icon string `json:"icon"`
+					Code int    `json:"code"`
+				} `json:"condition"`
+			} `json:"day"`
+		} `json:"forecastday"`
+	} `json:"forecast"`
+}
+
+// Forecast represents a daily weather forecast for a location
+type Forecast struct {
+	City    string        `json:"city"`
+	Country string        `json:"country"`
+	State   string        `json:"state"`
+	Days    []ForecastDay `json:"days"`
+}
+
+// ForecastDay represents the forecast for a single day
+type ForecastDay struct {
+	Date           string  `json:"date"`
+	MinTemperature float64 `json:"min_temperature"`
+	MaxTemperature float64 `json:"max_temperature"`
+	AvgTemperature float64 `json:"avg_temperature"`
+	Humidity       int     `json:"humidity"`
+	ChanceOfRain   int     `json:"chance_of_rain"`
+	Description    string  `json:"description"`
+	Icon           string  `json:"icon"`
+	ConditionCode  int     `json:"condition_code"`
+}
+
 // APIError represents an API error response
 type APIError struct {
-	Error string `json:"error"`
+	Error   string       `json:"error"`
+	Code    string       `json:"code,omitempty"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// FieldError describes a problem with a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Health statuses reported by health endpoints
+const (
+	HealthStatusOK       = "ok"
+	HealthStatusDegraded = "degraded"
+	HealthStatusDown     = "down"
+)
+
+// ComponentHealth reports the status of a single dependency
+type ComponentHealth struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HealthReport represents the response of the health endpoints
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
+
+// UpstreamUsage reports upstream API call counts against the configured budget
+type UpstreamUsage struct {
+	Provider     string `json:"provider"`
+	Day          string `json:"day"`
+	DailyCalls   int    `json:"daily_calls"`
+	DailyLimit   int    `json:"daily_limit"`
+	Month        string `json:"month"`
+	MonthlyCalls int    `json:"monthly_calls"`
+	MonthlyLimit int    `json:"monthly_limit"`
+	Exhausted    bool   `json:"exhausted"`
+}
+
+// ConfigVersion reports the active configuration version and the outcome
+// of the most recent reload attempt
+type ConfigVersion struct {
+	Version         int        `json:"version"`
+	Checksum        string     `json:"checksum"`
+	Source          string     `json:"source,omitempty"`
+	LoadedAt        time.Time  `json:"loaded_at"`
+	LastReloadAt    *time.Time `json:"last_reload_at,omitempty"`
+	LastReloadError string     `json:"last_reload_error,omitempty"`
+}
+
+// HistoryFilter selects stored observations. Zero values match everything.
+type HistoryFilter struct {
+	City  string
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+// Matches reports whether an observation taken at t falls within the
+// filter's time range. From is inclusive, To exclusive.
+func (f HistoryFilter) Matches(t time.Time) bool {
+	if !f.From.IsZero() && t.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !t.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// RetentionReport summarizes a run of the retention job
+type RetentionReport struct {
+	RolledUp      int  `json:"rolled_up"`
+	DeletedHourly int  `json:"deleted_hourly"`
+	Vacuumed      bool `json:"vacuumed"`
+}
+
+// DatabaseStats describes the contents and size of the database
+type DatabaseStats struct {
+	SchemaVersion int              `json:"schema_version"`
+	SizeBytes     int64            `json:"size_bytes"`
+	FreeBytes     int64            `json:"free_bytes"`
+	Rows          map[string]int64 `json:"rows"`
+	Oldest        *time.Time       `json:"oldest,omitempty"`
+	Newest        *time.Time       `json:"newest,omitempty"`
+}
+
+// PruneReport counts the rows deleted by a prune
+type PruneReport struct {
+	Observations int64 `json:"observations"`
+	Hourly       int64 `json:"hourly"`
+	Daily        int64 `json:"daily"`
+}
+
+// File formats for exporting and importing observations
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// HistoryCSVColumns lists the columns of observations in CSV form
+var HistoryCSVColumns = []string{"id", "city", "country", "state", "temperature", "description", "humidity", "icon", "condition_code", "timestamp"}
+
+// ImportReport summarizes a bulk import of observations
+type ImportReport struct {
+	Total           int               `json:"total"`
+	Imported        int               `json:"imported"`
+	Duplicates      int               `json:"duplicates"`
+	Failed          int               `json:"failed"`
+	Errors          []ImportLineError `json:"errors,omitempty"`
+	ErrorsTruncated bool              `json:"errors_truncated,omitempty"`
+}
+
+// ImportLineError describes why a line of an import was rejected
+type ImportLineError struct {
+	Line    int          `json:"line"`
+	Error   string       `json:"error"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// BatchWeatherItem represents a single lookup in a batch request.
+// Either City or both Lat and Lon must be set.
+type BatchWeatherItem struct {
+	City string   `json:"city,omitempty"`
+	Lat  *float64 `json:"lat,omitempty"`
+	Lon  *float64 `json:"lon,omitempty"`
+}
+
+// BatchWeatherRequest represents the body of a batch weather lookup
+type BatchWeatherRequest struct {
+	Items []BatchWeatherItem `json:"items"`
+}
+
+// BatchWeatherResult holds the outcome of a single batch item
+type BatchWeatherResult struct {
+	Index int          `json:"index"`
+	Query string       `json:"query"`
+	Data  *WeatherData `json:"data,omitempty"`
+	Error string       `json:"error,omitempty"`
+	Code  string       `json:"code,omitempty"`
+}
+
+// BatchWeatherResponse represents the response of a batch weather lookup
+type BatchWeatherResponse struct {
+	Results   []BatchWeatherResult `json:"results"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+}
+
+// GraphQLRequest represents the body of a GraphQL request
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLResponse represents the result of a GraphQL request. Data is
+// omitted when the document could not be executed at all.
+type GraphQLResponse struct {
+	Data   interface{}    `json:"data,omitempty"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+// GraphQLError describes a GraphQL request or field error. Extensions
+// carries the error code, as in APIError.Code.
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Locations  []GraphQLErrorLocation `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLErrorLocation points at the part of a GraphQL document an error
+// refers to
+type GraphQLErrorLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
 }
 
 // Constants for weather condition codes
 const (
 	HistoryLimit = 3
+
+	// MaxBatchSize is the maximum number of items accepted by a batch lookup
+	MaxBatchSize = 25
+	// BatchWorkers is the number of concurrent upstream lookups per batch
+	BatchWorkers = 5
+
+	// DefaultForecastDays and MaxForecastDays bound forecast requests
+	DefaultForecastDays = 3
+	MaxForecastDays     = 14
+
+	// MaxHistoryLimit is the largest history page a client can request
+	MaxHistoryLimit = 1000
+
+	// MaxGraphQLBytes is the largest GraphQL request body accepted
+	MaxGraphQLBytes = 64 << 10
+
+	// ImportBatchSize is the number of rows inserted per transaction
+	ImportBatchSize = 500
+	// MaxImportErrors is the number of rejected lines listed in an import report
+	MaxImportErrors = 100
+	// MaxImportBytes is the largest import body accepted over HTTP
+	MaxImportBytes = 64 << 20
 )
 
 // WeatherConditionCodes maps condition codes to descriptions
@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"weather-dashboard/logging"
+	"weather-dashboard/models"
+)
+
+// errorDomain identifies this service in ErrorInfo details
+const errorDomain = "weather-dashboard"
+
+var (
+	// errRateLimited is returned when a client exceeds its rate limit
+	errRateLimited = errors.New("rate limit exceeded")
+	// errTooManyStreams is returned when the server has no room for another
+	// stream
+	errTooManyStreams = errors.New("too many concurrent streams")
+)
+
+// errorMappings maps service errors to gRPC codes and the error codes of the
+// HTTP API. The first match wins.
+var errorMappings = []struct {
+	err      error
+	grpcCode codes.Code
+	code     string
+}{
+	{models.ErrInvalidInput, codes.InvalidArgument, models.ErrorCodeInvalidInput},
+	{models.ErrNotFound, codes.NotFound, models.ErrorCodeNotFound},
+	{models.ErrQuotaExceeded, codes.ResourceExhausted, models.ErrorCodeQuotaExceeded},
+	{errRateLimited, codes.ResourceExhausted, models.ErrorCodeRateLimited},
+	{errTooManyStreams, codes.ResourceExhausted, models.ErrorCodeRateLimited},
+	{models.ErrUnauthorized, codes.Unavailable, models.ErrorCodeUpstreamUnauthorized},
+	{models.ErrUpstreamUnavailable, codes.Unavailable, models.ErrorCodeUpstreamUnavailable},
+	{context.Canceled, codes.Canceled, models.ErrorCodeInternal},
+	{context.DeadlineExceeded, codes.DeadlineExceeded, models.ErrorCodeInternal},
+}
+
+// errorCodes maps a service error to a gRPC code and an HTTP API error code
+func errorCodes(err error) (codes.Code, string) {
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return mapping.grpcCode, mapping.code
+		}
+	}
+	return codes.Internal, models.ErrorCodeInternal
+}
+
+// statusError converts a service error to a gRPC status error with any
+// secrets redacted and the HTTP API error code as the ErrorInfo reason
+func statusError(err error) error {
+	grpcCode, code := errorCodes(err)
+	st := status.New(grpcCode, logging.Redact(err.Error()))
+	if detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{Reason: code, Domain: errorDomain}); detailErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// rateLimited returns the status error for a rate limited call, telling the
+// client how long to wait before retrying
+func rateLimited(wait time.Duration) error {
+	st := status.Convert(statusError(errRateLimited))
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// invalidArgument returns the status error for a request field failing
+// validation
+func invalidArgument(field string, err error) error {
+	return statusError(fmt.Errorf("%w: %s %s", models.ErrInvalidInput, field, err.Error()))
+}
+
+// ErrorReason returns the HTTP API error code carried by a status error from
+// this service, such as "not_found", or "" if there is none
+func ErrorReason(err error) string {
+	st, ok := status.FromError(err)
+	if !ok {
+		return ""
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == errorDomain {
+			return info.Reason
+		}
+	}
+	return ""
+}
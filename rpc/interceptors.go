@@ -0,0 +1,264 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"weather-dashboard/logging"
+	"weather-dashboard/metrics"
+	"weather-dashboard/middleware"
+	"weather-dashboard/tracing"
+)
+
+// requestIDKey is the metadata key carrying the request ID, the gRPC
+// counterpart of the X-Request-ID header
+const requestIDKey = "x-request-id"
+
+// withRequestID propagates a valid incoming request ID or generates a new
+// one, echoes it in the response header and attaches it to the context for
+// logging
+func withRequestID(ctx context.Context) context.Context {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDKey); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if !middleware.ValidRequestID(requestID) {
+		requestID = middleware.NewRequestID()
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID))
+	return logging.WithRequestID(ctx, requestID)
+}
+
+func unaryRequestID(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(withRequestID(ctx), req)
+}
+
+func streamRequestID(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &contextStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+}
+
+// contextStream overrides the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// metadataCarrier adapts incoming metadata for trace context propagation
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// startCallSpan starts a server span for a call, continuing any trace
+// propagated by the caller, and returns a function ending it with the
+// call's status
+func startCallSpan(ctx context.Context, method string) (context.Context, func(error)) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	}
+
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	ctx, span := tracing.Tracer().Start(ctx, service+"/"+name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.RPCSystemGRPC, semconv.RPCService(service), semconv.RPCMethod(name)))
+
+	return ctx, func(err error) {
+		code := status.Code(err)
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+		if serverFault(code) {
+			span.SetStatus(otelcodes.Error, code.String())
+		}
+		span.End()
+	}
+}
+
+// serverFault reports whether a status code means the server failed rather
+// than the caller
+func serverFault(code codes.Code) bool {
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented, codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+func unaryTracing(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, end := startCallSpan(ctx, info.FullMethod)
+	resp, err := handler(ctx, req)
+	end(err)
+	return resp, err
+}
+
+func streamTracing(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, end := startCallSpan(ss.Context(), info.FullMethod)
+	err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	end(err)
+	return err
+}
+
+func unaryMetrics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	metrics.ObserveRPC(info.FullMethod, status.Code(err).String(), start)
+	return resp, err
+}
+
+func streamMetrics(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	metrics.RPCActiveStreams.Inc()
+	defer metrics.RPCActiveStreams.Dec()
+
+	err := handler(srv, ss)
+	metrics.ObserveRPC(info.FullMethod, status.Code(err).String(), start)
+	return err
+}
+
+// apiKeyKey is the metadata key clients use to identify themselves for rate
+// limiting, the gRPC counterpart of the X-API-Key header
+var apiKeyKey = strings.ToLower(middleware.APIKeyHeader)
+
+// rateLimit returns interceptors applying the HTTP API's rate limits, with
+// buckets per configured API key or peer IP. Every call takes a token from
+// the API limiter; streams fan out to several lookups like a batch request,
+// so they also take one from the batch limiter.
+func rateLimit(limiters *middleware.RateLimiters) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	allow := func(ctx context.Context, limiter *middleware.RateLimiter) error {
+		var apiKey string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(apiKeyKey); len(values) > 0 {
+				apiKey = values[0]
+			}
+		}
+
+		if allowed, wait := limiters.Allow(limiter, apiKey, peerIP(ctx)); !allowed {
+			return rateLimited(wait)
+		}
+		return nil
+	}
+
+	unary := func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := allow(ctx, limiters.API); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+
+	stream := func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := allow(ss.Context(), limiters.API); err != nil {
+			return err
+		}
+		if err := allow(ss.Context(), limiters.Batch); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+
+	return unary, stream
+}
+
+// peerIP returns the IP address of the caller, or "" if it is unknown
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// logCall logs one structured line per call, like the HTTP access log
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented:
+		level = slog.LevelError
+	}
+
+	slog.Log(ctx, level, "rpc completed",
+		"method", method,
+		"code", code.String(),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+}
+
+func unaryAccessLog(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func streamAccessLog(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	logCall(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// recovered converts a panic to an Internal error so one bad call does not
+// take the server down
+func recovered(ctx context.Context, method string, r interface{}) error {
+	slog.ErrorContext(ctx, "panic recovered", "method", method, "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+	return statusError(fmt.Errorf("internal error"))
+}
+
+func unaryRecovery(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(ctx, info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func streamRecovery(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(ss.Context(), info.FullMethod, r)
+		}
+	}()
+	return handler(srv, ss)
+}
@@ -0,0 +1,255 @@
+// Package rpc serves WeatherService over gRPC, backed by the same services
+// as the HTTP API
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"weather-dashboard/handlers"
+	"weather-dashboard/middleware"
+	"weather-dashboard/models"
+	weatherv1 "weather-dashboard/proto/weather/v1"
+	"weather-dashboard/utils"
+)
+
+// Store provides the stored observations behind the service. Streams serve
+// the latest stored reading of a city while it is fresh.
+type Store interface {
+	handlers.DatabaseServiceInterface
+	GetLatestWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error)
+}
+
+// StreamLimits bounds the upstream load of StreamUpdates
+type StreamLimits struct {
+	// Interval is the shortest time between polls of a stream
+	Interval time.Duration
+	// MaxStreams is the number of streams served at once
+	MaxStreams int
+	// MaxCities is the number of cities a stream may follow
+	MaxCities int
+	// CacheTTL is the maximum age of a stored reading served to a stream
+	// instead of looking the city up again
+	CacheTTL time.Duration
+}
+
+// Server implements weatherv1.WeatherServiceServer
+type Server struct {
+	weatherv1.UnimplementedWeatherServiceServer
+
+	weatherService handlers.WeatherServiceInterface
+	dbService      Store
+	limits         StreamLimits
+	// streams holds a slot for each open stream
+	streams chan struct{}
+}
+
+var _ weatherv1.WeatherServiceServer = (*Server)(nil)
+
+// NewServer creates a WeatherService backed by the given services, with
+// streams bounded by limits
+func NewServer(weatherService handlers.WeatherServiceInterface, dbService Store, limits StreamLimits) *Server {
+	return &Server{
+		weatherService: weatherService,
+		dbService:      dbService,
+		limits:         limits,
+		streams:        make(chan struct{}, limits.MaxStreams),
+	}
+}
+
+// NewGRPCServer creates a gRPC server with request IDs, tracing, access
+// logging, metrics, panic recovery and the rate limits of the HTTP API, and
+// registers s on it
+func NewGRPCServer(s *Server, limiters *middleware.RateLimiters, opts ...grpc.ServerOption) *grpc.Server {
+	unaryRateLimit, streamRateLimit := rateLimit(limiters)
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryRequestID, unaryTracing, unaryAccessLog, unaryMetrics, unaryRecovery, unaryRateLimit),
+		grpc.ChainStreamInterceptor(streamRequestID, streamTracing, streamAccessLog, streamMetrics, streamRecovery, streamRateLimit),
+	}, opts...)
+
+	server := grpc.NewServer(opts...)
+	weatherv1.RegisterWeatherServiceServer(server, s)
+	return server
+}
+
+// GetCurrent returns the current weather for a city or coordinates
+func (s *Server) GetCurrent(ctx context.Context, req *weatherv1.GetCurrentRequest) (*weatherv1.Weather, error) {
+	var weatherData *models.WeatherData
+	var err error
+
+	switch location := req.GetLocation().(type) {
+	case *weatherv1.GetCurrentRequest_City:
+		city, cityErr := validateCity(location.City)
+		if cityErr != nil {
+			return nil, invalidArgument("city", cityErr)
+		}
+		weatherData, err = s.weatherService.GetWeatherByCity(ctx, city)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to fetch weather", "city", city, "error", err)
+		}
+	case *weatherv1.GetCurrentRequest_Coordinates:
+		lat, lon := location.Coordinates.GetLat(), location.Coordinates.GetLon()
+		if rangeErr := utils.CheckCoordinateRange(lat, utils.MaxLatitude); rangeErr != nil {
+			return nil, invalidArgument("coordinates.lat", rangeErr)
+		}
+		if rangeErr := utils.CheckCoordinateRange(lon, utils.MaxLongitude); rangeErr != nil {
+			return nil, invalidArgument("coordinates.lon", rangeErr)
+		}
+		weatherData, err = s.weatherService.GetWeatherByCoordinates(ctx, utils.FormatCoordinate(lat), utils.FormatCoordinate(lon))
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to fetch weather by coordinates", "lat", lat, "lon", lon, "error", err)
+		}
+	default:
+		return nil, invalidArgument("location", fmt.Errorf("is required"))
+	}
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	s.save(ctx, weatherData)
+	return weatherToProto(weatherData), nil
+}
+
+// GetForecast returns a daily forecast for a city
+func (s *Server) GetForecast(ctx context.Context, req *weatherv1.GetForecastRequest) (*weatherv1.Forecast, error) {
+	city, err := validateCity(req.GetCity())
+	if err != nil {
+		return nil, invalidArgument("city", err)
+	}
+
+	days := int(req.GetDays())
+	if days == 0 {
+		days = models.DefaultForecastDays
+	}
+	if days < 1 || days > models.MaxForecastDays {
+		return nil, invalidArgument("days", fmt.Errorf("must be between 1 and %d", models.MaxForecastDays))
+	}
+
+	forecast, err := s.weatherService.GetForecast(ctx, city, days)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to fetch forecast", "city", city, "error", err)
+		return nil, statusError(err)
+	}
+
+	return forecastToProto(forecast), nil
+}
+
+// SearchCity returns the locations matching a name
+func (s *Server) SearchCity(ctx context.Context, req *weatherv1.SearchCityRequest) (*weatherv1.SearchCityResponse, error) {
+	query, err := validateCity(req.GetQuery())
+	if err != nil {
+		return nil, invalidArgument("query", err)
+	}
+
+	results, err := s.weatherService.SearchCity(ctx, query)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to search cities", "query", query, "error", err)
+		return nil, statusError(err)
+	}
+
+	response := &weatherv1.SearchCityResponse{Locations: make([]*weatherv1.Location, len(results))}
+	for i, result := range results {
+		response.Locations[i] = &weatherv1.Location{
+			Name:    result.Name,
+			Region:  result.Region,
+			Country: result.Country,
+			Lat:     result.Lat,
+			Lon:     result.Lon,
+		}
+	}
+	return response, nil
+}
+
+// ListHistory returns the most recent stored observations. A zero limit
+// applies the configured history limit.
+func (s *Server) ListHistory(ctx context.Context, req *weatherv1.ListHistoryRequest) (*weatherv1.ListHistoryResponse, error) {
+	var history []models.WeatherData
+	var err error
+	if limit := int(req.GetLimit()); limit == 0 {
+		history, err = s.dbService.GetWeatherHistoryDefault(ctx)
+	} else {
+		if limit < 1 || limit > models.MaxHistoryLimit {
+			return nil, invalidArgument("limit", fmt.Errorf("must be between 1 and %d", models.MaxHistoryLimit))
+		}
+		history, err = s.dbService.GetWeatherHistory(ctx, limit)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to fetch weather history", "error", err)
+		return nil, statusError(err)
+	}
+
+	response := &weatherv1.ListHistoryResponse{Observations: make([]*weatherv1.Weather, len(history))}
+	for i := range history {
+		response.Observations[i] = weatherToProto(&history[i])
+	}
+	return response, nil
+}
+
+// save stores a fresh reading, like the HTTP API does. Failures are logged
+// and do not fail the call.
+func (s *Server) save(ctx context.Context, weatherData *models.WeatherData) {
+	if weatherData.Cached {
+		return
+	}
+
+	if err := s.dbService.SaveWeatherData(context.WithoutCancel(ctx), weatherData); err != nil {
+		slog.ErrorContext(ctx, "failed to save weather data", "city", weatherData.City, "error", err)
+	}
+}
+
+// validateCity checks a city name and returns it normalized
+func validateCity(city string) (string, error) {
+	if err := utils.ValidateCityName(city); err != nil {
+		return "", err
+	}
+	return utils.SanitizeCityName(city), nil
+}
+
+func weatherToProto(data *models.WeatherData) *weatherv1.Weather {
+	weather := &weatherv1.Weather{
+		Id:            int64(data.ID),
+		City:          data.City,
+		Country:       data.Country,
+		State:         data.State,
+		Temperature:   data.Temperature,
+		Description:   data.Description,
+		Humidity:      int32(data.Humidity),
+		Icon:          data.Icon,
+		ConditionCode: int32(data.ConditionCode),
+		Cached:        data.Cached,
+		Stale:         data.Stale,
+		AgeSeconds:    data.AgeSeconds,
+	}
+	if !data.Timestamp.IsZero() {
+		weather.Timestamp = timestamppb.New(data.Timestamp)
+	}
+	return weather
+}
+
+func forecastToProto(forecast *models.Forecast) *weatherv1.Forecast {
+	result := &weatherv1.Forecast{
+		City:    forecast.City,
+		Country: forecast.Country,
+		State:   forecast.State,
+		Days:    make([]*weatherv1.ForecastDay, len(forecast.Days)),
+	}
+	for i, day := range forecast.Days {
+		result.Days[i] = &weatherv1.ForecastDay{
+			Date:           day.Date,
+			MinTemperature: day.MinTemperature,
+			MaxTemperature: day.MaxTemperature,
+			AvgTemperature: day.AvgTemperature,
+			Humidity:       int32(day.Humidity),
+			ChanceOfRain:   int32(day.ChanceOfRain),
+			Description:    day.Description,
+			Icon:           day.Icon,
+			ConditionCode:  int32(day.ConditionCode),
+		}
+	}
+	return result
+}
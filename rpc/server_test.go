@@ -0,0 +1,528 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"weather-dashboard/config"
+	"weather-dashboard/logging"
+	"weather-dashboard/metrics"
+	"weather-dashboard/middleware"
+	"weather-dashboard/models"
+	weatherv1 "weather-dashboard/proto/weather/v1"
+)
+
+// mockWeatherService serves readings from a map of lower-cased city names
+type mockWeatherService struct {
+	mu      sync.Mutex
+	cities  map[string]models.WeatherData
+	lastLat string
+	lastLon string
+}
+
+func (m *mockWeatherService) set(city string, data models.WeatherData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cities[strings.ToLower(city)] = data
+}
+
+func (m *mockWeatherService) SearchCity(ctx context.Context, city string) ([]models.WeatherAPISearchResult, error) {
+	if city == "Nowhere" {
+		return nil, fmt.Errorf("%w: search failed for key=s3cr3t-key", models.ErrUpstreamUnavailable)
+	}
+	return []models.WeatherAPISearchResult{{Name: city, Country: "United Kingdom", Lat: 51.52, Lon: -0.11}}, nil
+}
+
+func (m *mockWeatherService) GetWeatherByCoordinates(ctx context.Context, lat, lon string) (*models.WeatherData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastLat, m.lastLon = lat, lon
+	return &models.WeatherData{City: "London", Cached: true}, nil
+}
+
+func (m *mockWeatherService) GetWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.cities[strings.ToLower(city)]
+	if !ok {
+		return nil, fmt.Errorf("city %w: %s", models.ErrNotFound, city)
+	}
+	return &data, nil
+}
+
+func (m *mockWeatherService) GetForecast(ctx context.Context, city string, days int) (*models.Forecast, error) {
+	forecast := &models.Forecast{City: city}
+	for i := 0; i < days; i++ {
+		forecast.Days = append(forecast.Days, models.ForecastDay{Date: fmt.Sprintf("2024-03-%02d", i+1), ChanceOfRain: 40})
+	}
+	return forecast, nil
+}
+
+// mockDatabaseService records saved readings and serves a fixed history and
+// latest readings keyed by lower-cased city name
+type mockDatabaseService struct {
+	mu           sync.Mutex
+	saved        []string
+	history      []models.WeatherData
+	historyLimit int
+	latest       map[string]models.WeatherData
+}
+
+func (m *mockDatabaseService) GetLatestWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.latest[strings.ToLower(city)]
+	if !ok {
+		return nil, nil
+	}
+	return &data, nil
+}
+
+func (m *mockDatabaseService) SaveWeatherData(ctx context.Context, data *models.WeatherData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saved = append(m.saved, data.City)
+	return nil
+}
+
+func (m *mockDatabaseService) GetWeatherHistory(ctx context.Context, limit int) ([]models.WeatherData, error) {
+	m.historyLimit = limit
+	return m.history, nil
+}
+
+func (m *mockDatabaseService) GetWeatherHistoryDefault(ctx context.Context) ([]models.WeatherData, error) {
+	m.historyLimit = -1
+	return m.history, nil
+}
+
+func (m *mockDatabaseService) StreamWeatherHistory(ctx context.Context, filter models.HistoryFilter, fn func(*models.WeatherData) error) error {
+	return nil
+}
+
+func (m *mockDatabaseService) Close() error {
+	return nil
+}
+
+// testLimits returns stream limits polling every interval without the cache
+func testLimits(interval time.Duration) StreamLimits {
+	return StreamLimits{Interval: interval, MaxStreams: 10, MaxCities: models.MaxBatchSize}
+}
+
+// newTestClient serves s over an in-memory connection, rate limited by
+// limiters if they are not nil
+func newTestClient(t *testing.T, limiters *middleware.RateLimiters, s *Server) weatherv1.WeatherServiceClient {
+	if limiters == nil {
+		limiters = middleware.NewRateLimiters(config.RateLimitConfig{})
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewGRPCServer(s, limiters)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return weatherv1.NewWeatherServiceClient(conn)
+}
+
+func newTestServices() (*mockWeatherService, *mockDatabaseService) {
+	weatherService := &mockWeatherService{cities: map[string]models.WeatherData{
+		"london": {City: "London", Country: "United Kingdom", Temperature: 15.5, Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+	}}
+	dbService := &mockDatabaseService{history: []models.WeatherData{{ID: 7, City: "Paris", Temperature: 18.2}}}
+	return weatherService, dbService
+}
+
+func TestServer_GetCurrent(t *testing.T) {
+	weatherService, dbService := newTestServices()
+	client := newTestClient(t, nil, NewServer(weatherService, dbService, testLimits(time.Minute)))
+	ctx := context.Background()
+
+	var header metadata.MD
+	weather, err := client.GetCurrent(ctx, &weatherv1.GetCurrentRequest{
+		Location: &weatherv1.GetCurrentRequest_City{City: " london "},
+	}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, "London", weather.GetCity())
+	assert.Equal(t, 15.5, weather.GetTemperature())
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), weather.GetTimestamp().AsTime())
+	assert.NotEmpty(t, header.Get(requestIDKey))
+
+	weather, err = client.GetCurrent(ctx, &weatherv1.GetCurrentRequest{
+		Location: &weatherv1.GetCurrentRequest_Coordinates{Coordinates: &weatherv1.Coordinates{Lat: 51.52, Lon: -0.1}},
+	})
+	require.NoError(t, err)
+	assert.True(t, weather.GetCached())
+	assert.Nil(t, weather.GetTimestamp())
+	assert.Equal(t, "51.52", weatherService.lastLat)
+	assert.Equal(t, "-0.1", weatherService.lastLon)
+
+	// Only the fresh reading is stored
+	assert.Equal(t, []string{"London"}, dbService.saved)
+}
+
+func TestServer_Errors(t *testing.T) {
+	logging.AddSecrets("s3cr3t-key")
+	weatherService, dbService := newTestServices()
+	client := newTestClient(t, nil, NewServer(weatherService, dbService, testLimits(time.Minute)))
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		call   func() error
+		code   codes.Code
+		reason string
+	}{
+		{
+			name: "missing location",
+			call: func() error {
+				_, err := client.GetCurrent(ctx, &weatherv1.GetCurrentRequest{})
+				return err
+			},
+			code:   codes.InvalidArgument,
+			reason: models.ErrorCodeInvalidInput,
+		},
+		{
+			name: "invalid city",
+			call: func() error {
+				_, err := client.GetCurrent(ctx, &weatherv1.GetCurrentRequest{Location: &weatherv1.GetCurrentRequest_City{City: "L0nd0n"}})
+				return err
+			},
+			code:   codes.InvalidArgument,
+			reason: models.ErrorCodeInvalidInput,
+		},
+		{
+			name: "latitude out of range",
+			call: func() error {
+				_, err := client.GetCurrent(ctx, &weatherv1.GetCurrentRequest{
+					Location: &weatherv1.GetCurrentRequest_Coordinates{Coordinates: &weatherv1.Coordinates{Lat: 91}},
+				})
+				return err
+			},
+			code:   codes.InvalidArgument,
+			reason: models.ErrorCodeInvalidInput,
+		},
+		{
+			name: "unknown city",
+			call: func() error {
+				_, err := client.GetCurrent(ctx, &weatherv1.GetCurrentRequest{Location: &weatherv1.GetCurrentRequest_City{City: "Atlantis"}})
+				return err
+			},
+			code:   codes.NotFound,
+			reason: models.ErrorCodeNotFound,
+		},
+		{
+			name: "too many forecast days",
+			call: func() error {
+				_, err := client.GetForecast(ctx, &weatherv1.GetForecastRequest{City: "London", Days: 15})
+				return err
+			},
+			code:   codes.InvalidArgument,
+			reason: models.ErrorCodeInvalidInput,
+		},
+		{
+			name: "upstream unavailable",
+			call: func() error {
+				_, err := client.SearchCity(ctx, &weatherv1.SearchCityRequest{Query: "Nowhere"})
+				return err
+			},
+			code:   codes.Unavailable,
+			reason: models.ErrorCodeUpstreamUnavailable,
+		},
+		{
+			name: "history limit too large",
+			call: func() error {
+				_, err := client.ListHistory(ctx, &weatherv1.ListHistoryRequest{Limit: models.MaxHistoryLimit + 1})
+				return err
+			},
+			code:   codes.InvalidArgument,
+			reason: models.ErrorCodeInvalidInput,
+		},
+		{
+			name: "too many stream cities",
+			call: func() error {
+				stream, err := client.StreamUpdates(ctx, &weatherv1.StreamUpdatesRequest{Cities: make([]string, models.MaxBatchSize+1)})
+				require.NoError(t, err)
+				_, err = stream.Recv()
+				return err
+			},
+			code:   codes.InvalidArgument,
+			reason: models.ErrorCodeInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			require.Error(t, err)
+			assert.Equal(t, tt.code, status.Code(err), err.Error())
+			assert.Equal(t, tt.reason, ErrorReason(err))
+			assert.NotContains(t, err.Error(), "s3cr3t-key")
+		})
+	}
+}
+
+func TestServer_GetForecast(t *testing.T) {
+	weatherService, dbService := newTestServices()
+	client := newTestClient(t, nil, NewServer(weatherService, dbService, testLimits(time.Minute)))
+
+	requests := metrics.RPCRequests.WithLabelValues(weatherv1.WeatherService_GetForecast_FullMethodName, codes.OK.String())
+	before := testutil.ToFloat64(requests)
+
+	forecast, err := client.GetForecast(context.Background(), &weatherv1.GetForecastRequest{City: "london"})
+	require.NoError(t, err)
+	assert.Equal(t, before+1, testutil.ToFloat64(requests))
+	assert.Equal(t, "London", forecast.GetCity())
+	require.Len(t, forecast.GetDays(), models.DefaultForecastDays)
+	assert.Equal(t, "2024-03-01", forecast.GetDays()[0].GetDate())
+	assert.Equal(t, int32(40), forecast.GetDays()[0].GetChanceOfRain())
+}
+
+func TestServer_SearchCity(t *testing.T) {
+	weatherService, dbService := newTestServices()
+	client := newTestClient(t, nil, NewServer(weatherService, dbService, testLimits(time.Minute)))
+
+	response, err := client.SearchCity(context.Background(), &weatherv1.SearchCityRequest{Query: "london"})
+	require.NoError(t, err)
+	require.Len(t, response.GetLocations(), 1)
+	assert.Equal(t, "London", response.GetLocations()[0].GetName())
+	assert.Equal(t, -0.11, response.GetLocations()[0].GetLon())
+}
+
+func TestServer_ListHistory(t *testing.T) {
+	weatherService, dbService := newTestServices()
+	client := newTestClient(t, nil, NewServer(weatherService, dbService, testLimits(time.Minute)))
+	ctx := context.Background()
+
+	response, err := client.ListHistory(ctx, &weatherv1.ListHistoryRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, -1, dbService.historyLimit)
+	require.Len(t, response.GetObservations(), 1)
+	assert.Equal(t, int64(7), response.GetObservations()[0].GetId())
+
+	_, err = client.ListHistory(ctx, &weatherv1.ListHistoryRequest{Limit: 5})
+	require.NoError(t, err)
+	assert.Equal(t, 5, dbService.historyLimit)
+}
+
+func TestServer_StreamUpdates(t *testing.T) {
+	weatherService, dbService := newTestServices()
+	client := newTestClient(t, nil, NewServer(weatherService, dbService, testLimits(10*time.Millisecond)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamUpdates(ctx, &weatherv1.StreamUpdatesRequest{
+		Cities:   []string{"London", "Atlantis", "london"},
+		Interval: durationpb.New(time.Millisecond),
+	})
+	require.NoError(t, err)
+
+	// Each city is sent once up front, duplicates dropped
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "London", first.GetCity())
+	assert.Equal(t, 15.5, first.GetWeather().GetTemperature())
+
+	second, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "Atlantis", second.GetCity())
+	assert.Equal(t, models.ErrorCodeNotFound, second.GetError().GetCode())
+
+	// Later polls send only changed readings
+	weatherService.set("London", models.WeatherData{City: "London", Temperature: 17, Timestamp: time.Date(2024, 3, 1, 12, 15, 0, 0, time.UTC)})
+
+	update, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "London", update.GetCity())
+	assert.Equal(t, 17.0, update.GetWeather().GetTemperature())
+
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+}
+
+func TestUnchanged(t *testing.T) {
+	reading := func(temperature float64) *weatherv1.WeatherUpdate {
+		return &weatherv1.WeatherUpdate{City: "London", Result: &weatherv1.WeatherUpdate_Weather{
+			Weather: &weatherv1.Weather{City: "London", Temperature: temperature},
+		}}
+	}
+	failure := func(code string) *weatherv1.WeatherUpdate {
+		return &weatherv1.WeatherUpdate{City: "London", Result: &weatherv1.WeatherUpdate_Error{
+			Error: &weatherv1.UpdateError{Code: code},
+		}}
+	}
+
+	tests := []struct {
+		name     string
+		previous *weatherv1.WeatherUpdate
+		update   *weatherv1.WeatherUpdate
+		want     bool
+	}{
+		{"first update", nil, reading(15), false},
+		{"same reading", reading(15), reading(15), true},
+		{"new temperature", reading(15), reading(16), false},
+		{"same error", failure(models.ErrorCodeNotFound), failure(models.ErrorCodeNotFound), true},
+		{"different error", failure(models.ErrorCodeNotFound), failure(models.ErrorCodeUpstreamUnavailable), false},
+		{"recovered", failure(models.ErrorCodeNotFound), reading(15), false},
+		{"failed", reading(15), failure(models.ErrorCodeNotFound), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, unchanged(tt.previous, tt.update))
+		})
+	}
+}
+
+func TestServer_StreamUpdatesFromCache(t *testing.T) {
+	weatherService, dbService := newTestServices()
+	dbService.latest = map[string]models.WeatherData{
+		"london":  {City: "London", Temperature: 12, Timestamp: time.Now().Add(-time.Minute)},
+		"oldtown": {City: "Oldtown", Temperature: 9, Timestamp: time.Now().Add(-2 * time.Hour)},
+	}
+	limits := testLimits(time.Minute)
+	limits.CacheTTL = time.Hour
+	client := newTestClient(t, nil, NewServer(weatherService, dbService, limits))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamUpdates(ctx, &weatherv1.StreamUpdatesRequest{Cities: []string{"London", "Oldtown"}})
+	require.NoError(t, err)
+
+	// A fresh stored reading is served without a lookup
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, 12.0, first.GetWeather().GetTemperature())
+	assert.True(t, first.GetWeather().GetCached())
+
+	// An expired one is looked up again
+	second, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, models.ErrorCodeNotFound, second.GetError().GetCode())
+
+	dbService.mu.Lock()
+	defer dbService.mu.Unlock()
+	assert.Empty(t, dbService.saved)
+}
+
+func TestServer_StreamUpdatesCacheDisabled(t *testing.T) {
+	weatherService, dbService := newTestServices()
+	dbService.latest = map[string]models.WeatherData{
+		"london": {City: "London", Temperature: 12, Timestamp: time.Now()},
+	}
+	limits := testLimits(time.Minute)
+	limits.CacheTTL = 0
+	client := newTestClient(t, nil, NewServer(weatherService, dbService, limits))
+
+	// A cache TTL of 0 looks every city up, however fresh its stored reading
+	update, err := receive(context.Background(), client, "London")
+	require.NoError(t, err)
+	assert.Equal(t, 15.5, update.GetWeather().GetTemperature())
+	assert.False(t, update.GetWeather().GetCached())
+}
+
+func TestServer_StreamLimits(t *testing.T) {
+	weatherService, dbService := newTestServices()
+	limits := testLimits(time.Minute)
+	limits.MaxStreams = 1
+	limits.MaxCities = 2
+	client := newTestClient(t, nil, NewServer(weatherService, dbService, limits))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := receive(ctx, client, "London", "Paris", "Rome")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	open, err := client.StreamUpdates(ctx, &weatherv1.StreamUpdatesRequest{Cities: []string{"London"}})
+	require.NoError(t, err)
+	_, err = open.Recv()
+	require.NoError(t, err)
+
+	// The only slot is taken until the open stream ends
+	_, err = receive(ctx, client, "London")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, models.ErrorCodeRateLimited, ErrorReason(err))
+
+	cancel()
+	_, err = open.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	assert.Eventually(t, func() bool {
+		_, err := receive(context.Background(), client, "London")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+// receive opens a stream following cities and returns its first update
+func receive(ctx context.Context, client weatherv1.WeatherServiceClient, cities ...string) (*weatherv1.WeatherUpdate, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := client.StreamUpdates(ctx, &weatherv1.StreamUpdatesRequest{Cities: cities})
+	if err != nil {
+		return nil, err
+	}
+	return stream.Recv()
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	weatherService, dbService := newTestServices()
+	limiters := middleware.NewRateLimiters(config.RateLimitConfig{
+		Enabled: true,
+		API:     config.RateLimitRule{Rate: 0.1, Burst: 2},
+		Batch:   config.RateLimitRule{Rate: 0.1, Burst: 1},
+		APIKeys: []string{"client-key"},
+	})
+	client := newTestClient(t, limiters, NewServer(weatherService, dbService, testLimits(time.Minute)))
+	ctx := context.Background()
+
+	// A stream takes a token from both the API and the batch limiter
+	_, err := receive(ctx, client, "London")
+	require.NoError(t, err)
+	_, err = receive(ctx, client, "London")
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = client.SearchCity(ctx, &weatherv1.SearchCityRequest{Query: "London"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, models.ErrorCodeRateLimited, ErrorReason(err))
+
+	var retryDelay time.Duration
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retryDelay = info.GetRetryDelay().AsDuration()
+		}
+	}
+	assert.Positive(t, retryDelay)
+
+	// A configured API key has its own bucket
+	keyed := metadata.AppendToOutgoingContext(ctx, apiKeyKey, "client-key")
+	_, err = client.SearchCity(keyed, &weatherv1.SearchCityRequest{Query: "London"})
+	assert.NoError(t, err)
+}
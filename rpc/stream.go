@@ -0,0 +1,186 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"weather-dashboard/logging"
+	"weather-dashboard/metrics"
+	"weather-dashboard/models"
+	weatherv1 "weather-dashboard/proto/weather/v1"
+)
+
+// StreamUpdates sends the current weather of each city, then polls them
+// every interval and sends only the readings that changed, until the client
+// cancels. A failed lookup is sent as an error update and does not end the
+// stream.
+func (s *Server) StreamUpdates(req *weatherv1.StreamUpdatesRequest, stream weatherv1.WeatherService_StreamUpdatesServer) error {
+	ctx := stream.Context()
+
+	cities, err := streamCities(req.GetCities(), s.limits.MaxCities)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case s.streams <- struct{}{}:
+		defer func() { <-s.streams }()
+	default:
+		return statusError(errTooManyStreams)
+	}
+
+	interval := s.limits.Interval
+	if requested := req.GetInterval().AsDuration(); requested > interval {
+		interval = requested
+	}
+
+	last := make(map[string]*weatherv1.WeatherUpdate, len(cities))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for i, update := range s.poll(ctx, cities) {
+			if ctx.Err() != nil {
+				return statusError(ctx.Err())
+			}
+			if unchanged(last[cities[i]], update) {
+				continue
+			}
+			if err := stream.Send(update); err != nil {
+				return err
+			}
+			last[cities[i]] = update
+		}
+
+		select {
+		case <-ctx.Done():
+			return statusError(ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// streamCities validates and normalizes the cities of a stream, dropping
+// duplicates
+func streamCities(names []string, maxCities int) ([]string, error) {
+	if len(names) == 0 {
+		return nil, invalidArgument("cities", fmt.Errorf("is required"))
+	}
+	if len(names) > maxCities {
+		return nil, invalidArgument("cities", fmt.Errorf("must not list more than %d cities", maxCities))
+	}
+
+	seen := make(map[string]bool, len(names))
+	cities := make([]string, 0, len(names))
+	for i, name := range names {
+		city, err := validateCity(name)
+		if err != nil {
+			return nil, invalidArgument(fmt.Sprintf("cities[%d]", i), err)
+		}
+		if !seen[city] {
+			seen[city] = true
+			cities = append(cities, city)
+		}
+	}
+	return cities, nil
+}
+
+// poll looks up the current weather of each city concurrently, with at most
+// models.BatchWorkers lookups in flight, and returns the updates in the
+// order of cities
+func (s *Server) poll(ctx context.Context, cities []string) []*weatherv1.WeatherUpdate {
+	updates := make([]*weatherv1.WeatherUpdate, len(cities))
+	sem := make(chan struct{}, models.BatchWorkers)
+
+	var wg sync.WaitGroup
+	for i, city := range cities {
+		wg.Add(1)
+		go func(i int, city string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			updates[i] = s.lookup(ctx, city)
+		}(i, city)
+	}
+	wg.Wait()
+
+	return updates
+}
+
+// lookup returns the current weather of one city as a stream update,
+// serving the latest stored reading while it is younger than the cache TTL
+// so that streams following the same cities share upstream lookups
+func (s *Server) lookup(ctx context.Context, city string) *weatherv1.WeatherUpdate {
+	weatherData := s.latestStored(ctx, city)
+
+	var err error
+	if weatherData == nil {
+		weatherData, err = s.weatherService.GetWeatherByCity(ctx, city)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "stream lookup failed", "city", city, "error", err)
+		_, code := errorCodes(err)
+		return &weatherv1.WeatherUpdate{
+			City: city,
+			Result: &weatherv1.WeatherUpdate_Error{Error: &weatherv1.UpdateError{
+				Code:    code,
+				Message: logging.Redact(err.Error()),
+			}},
+		}
+	}
+
+	s.save(ctx, weatherData)
+	return &weatherv1.WeatherUpdate{
+		City:   city,
+		Result: &weatherv1.WeatherUpdate_Weather{Weather: weatherToProto(weatherData)},
+	}
+}
+
+// latestStored returns the latest stored reading of a city if it is younger
+// than the cache TTL, or nil. A cache TTL of 0 disables the cache.
+func (s *Server) latestStored(ctx context.Context, city string) *models.WeatherData {
+	if s.limits.CacheTTL <= 0 {
+		return nil
+	}
+
+	stored, err := s.dbService.GetLatestWeatherByCity(ctx, city)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to look up stored weather", "city", city, "error", err)
+		stored = nil
+	}
+	if stored != nil && time.Since(stored.Timestamp) > s.limits.CacheTTL {
+		stored = nil
+	}
+	metrics.ObserveCacheLookup(stored != nil)
+	if stored == nil {
+		return nil
+	}
+
+	stored.Cached = true
+	stored.AgeSeconds = int64(time.Since(stored.Timestamp).Seconds())
+	return stored
+}
+
+// unchanged reports whether update repeats the previous update of its city.
+// A reading is unchanged when its observation time and values are, however
+// it was served.
+func unchanged(previous, update *weatherv1.WeatherUpdate) bool {
+	if previous == nil {
+		return false
+	}
+
+	if previousErr, updateErr := previous.GetError(), update.GetError(); previousErr != nil || updateErr != nil {
+		return previousErr != nil && updateErr != nil && previousErr.GetCode() == updateErr.GetCode()
+	}
+
+	a, b := previous.GetWeather(), update.GetWeather()
+	return a.GetTimestamp().AsTime().Equal(b.GetTimestamp().AsTime()) &&
+		a.GetTemperature() == b.GetTemperature() &&
+		a.GetDescription() == b.GetDescription() &&
+		a.GetHumidity() == b.GetHumidity() &&
+		a.GetConditionCode() == b.GetConditionCode()
+}
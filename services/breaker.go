@@ -0,0 +1,137 @@
+package services
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"weather-dashboard/metrics"
+)
+
+// breakerState is the state of a circuit breaker
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+// String returns the state name used in logs
+func (s breakerState) String() string {
+	switch s {
+	case breakerHalfOpen:
+		return "half-open"
+	case breakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker stops calls to a provider after a run of consecutive
+// failures. Once the cooldown has passed a single probe call is let
+// through: success closes the breaker, failure opens it again. A nil
+// breaker allows every call.
+type circuitBreaker struct {
+	provider  string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+// newCircuitBreaker creates a breaker that opens after threshold
+// consecutive failures, or returns nil if threshold is not positive
+func newCircuitBreaker(provider string, threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	b := &circuitBreaker{
+		provider:  provider,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+	metrics.SetBreakerState(provider, int(breakerClosed))
+	return b
+}
+
+// allow reports whether a call may be made now
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record reports the outcome of an allowed call
+func (b *circuitBreaker) record(success bool) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		b.setState(breakerClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(breakerOpen)
+	}
+}
+
+// abort releases an allowed call that was never made
+func (b *circuitBreaker) abort() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// setState changes state, logging transitions. The caller must hold b.mu.
+func (b *circuitBreaker) setState(state breakerState) {
+	if b.state == state {
+		return
+	}
+
+	slog.Warn("circuit breaker state changed", "provider", b.provider, "from", b.state.String(), "to", state.String())
+	b.state = state
+	metrics.SetBreakerState(b.provider, int(state))
+}
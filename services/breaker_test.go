@@ -0,0 +1,88 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	newBreaker := func() (*circuitBreaker, *time.Time) {
+		b := newCircuitBreaker("test", 3, time.Minute)
+		now := time.Now()
+		b.now = func() time.Time { return now }
+		return b, &now
+	}
+
+	t.Run("opens after consecutive failures", func(t *testing.T) {
+		b, _ := newBreaker()
+		for i := 0; i < 2; i++ {
+			assert.True(t, b.allow())
+			b.record(false)
+		}
+
+		// A success resets the count
+		assert.True(t, b.allow())
+		b.record(true)
+		for i := 0; i < 3; i++ {
+			assert.True(t, b.allow())
+			b.record(false)
+		}
+
+		assert.Equal(t, breakerOpen, b.state)
+		assert.False(t, b.allow())
+	})
+
+	t.Run("single probe after cooldown", func(t *testing.T) {
+		b, now := newBreaker()
+		for i := 0; i < 3; i++ {
+			b.allow()
+			b.record(false)
+		}
+
+		*now = now.Add(time.Minute)
+		assert.True(t, b.allow())
+		assert.Equal(t, breakerHalfOpen, b.state)
+		assert.False(t, b.allow())
+
+		b.record(true)
+		assert.Equal(t, breakerClosed, b.state)
+		assert.True(t, b.allow())
+	})
+
+	t.Run("failed probe reopens", func(t *testing.T) {
+		b, now := newBreaker()
+		for i := 0; i < 3; i++ {
+			b.allow()
+			b.record(false)
+		}
+
+		*now = now.Add(time.Minute)
+		assert.True(t, b.allow())
+		b.record(false)
+		assert.Equal(t, breakerOpen, b.state)
+		assert.False(t, b.allow())
+	})
+
+	t.Run("aborted probe released", func(t *testing.T) {
+		b, now := newBreaker()
+		for i := 0; i < 3; i++ {
+			b.allow()
+			b.record(false)
+		}
+
+		*now = now.Add(time.Minute)
+		assert.True(t, b.allow())
+		b.abort()
+		assert.True(t, b.allow())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		b := newCircuitBreaker("test", 0, time.Minute)
+		assert.Nil(t, b)
+		assert.True(t, b.allow())
+		b.record(false)
+		b.abort()
+	})
+}
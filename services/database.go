@@ -1,42 +1,99 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"weather-dashboard/handlers"
+	"weather-dashboard/metrics"
 	"weather-dashboard/models"
+	"weather-dashboard/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 
 	_ "modernc.org/sqlite"
 )
 
 // DatabaseService handles all database operations
 type DatabaseService struct {
-	db *sql.DB
+	db           *sql.DB
+	historyLimit int
 }
 
 // NewDatabaseService creates a new database service
 func NewDatabaseService(dbPath string) (*DatabaseService, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := sql.Open("sqlite", withPragmas(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	service := &DatabaseService{db: db}
-	if err := service.initDB(); err != nil {
+	service := &DatabaseService{db: db, historyLimit: models.HistoryLimit}
+	if err := service.Migrate(context.Background()); err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
 	return service, nil
 }
 
+// withPragmas adds the connection pragmas to a database path: a busy
+// timeout, so concurrent writers wait for the lock instead of failing
+// immediately with SQLITE_BUSY, and WAL journal mode, so long reads such as
+// a streamed export do not block writers
+func withPragmas(dbPath string) string {
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	return dbPath + separator + "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
+
+// SetHistoryLimit changes the number of entries returned by
+// GetWeatherHistoryDefault
+func (s *DatabaseService) SetHistoryLimit(limit int) {
+	s.historyLimit = limit
+}
+
+// Ping verifies the database connection is usable
+func (s *DatabaseService) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
 // Close closes the database connection
 func (s *DatabaseService) Close() error {
 	return s.db.Close()
 }
 
+// schemaVersion is recorded in PRAGMA user_version once the schema is
+// current. Bump it when the schema changes.
+const schemaVersion = 2
+
+// Migrate brings the database schema up to date. It is safe to run
+// repeatedly and is done automatically when the service is created.
+func (s *DatabaseService) Migrate(ctx context.Context) error {
+	if err := s.initDB(ctx); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, schemaVersion)); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	return nil
+}
+
+// SchemaVersion returns the schema version recorded in the database
+func (s *DatabaseService) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	if err := s.db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
 // initDB initializes the database schema
-func (s *DatabaseService) initDB() error {
+func (s *DatabaseService) initDB(ctx context.Context) error {
 	createTable := `
 	CREATE TABLE IF NOT EXISTS weather_data (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -51,22 +108,85 @@ func (s *DatabaseService) initDB() error {
 		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
 	);`
 
-	_, err := s.db.Exec(createTable)
+	_, err := s.db.ExecContext(ctx, createTable)
 	if err != nil {
 		return fmt.Errorf("failed to create table: %w", err)
 	}
 
+	createUsageTable := `
+	CREATE TABLE IF NOT EXISTS upstream_usage (
+		provider TEXT NOT NULL,
+		period TEXT NOT NULL,
+		period_key TEXT NOT NULL,
+		calls INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (provider, period, period_key)
+	);`
+
+	_, err = s.db.ExecContext(ctx, createUsageTable)
+	if err != nil {
+		return fmt.Errorf("failed to create usage table: %w", err)
+	}
+
+	createMetaTable := `
+	CREATE TABLE IF NOT EXISTS meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);`
+
+	_, err = s.db.ExecContext(ctx, createMetaTable)
+	if err != nil {
+		return fmt.Errorf("failed to create meta table: %w", err)
+	}
+
+	for _, table := range aggregateTables {
+		createAggregateTable := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		city TEXT NOT NULL COLLATE NOCASE,
+		bucket TEXT NOT NULL,
+		country TEXT,
+		state TEXT,
+		samples INTEGER NOT NULL,
+		temperature_min REAL NOT NULL,
+		temperature_max REAL NOT NULL,
+		temperature_sum REAL NOT NULL,
+		humidity_sum INTEGER NOT NULL,
+		PRIMARY KEY (city, bucket)
+	);`, table)
+
+		_, err = s.db.ExecContext(ctx, createAggregateTable)
+		if err != nil {
+			return fmt.Errorf("failed to create %s table: %w", table, err)
+		}
+	}
+
 	return nil
 }
 
+// startQuery starts a span for a named query and returns a function that
+// ends it and records the query duration
+func startQuery(ctx context.Context, name string) (context.Context, func()) {
+	ctx, span := tracing.StartSpan(ctx, "sqlite."+name,
+		attribute.String("db.system", "sqlite"),
+		attribute.String("db.operation", name))
+
+	start := time.Now()
+	return ctx, func() {
+		span.End()
+		metrics.ObserveQuery(name, start)
+	}
+}
+
 // SaveWeatherData saves weather data to the database
-func (s *DatabaseService) SaveWeatherData(data *models.WeatherData) error {
+func (s *DatabaseService) SaveWeatherData(ctx context.Context, data *models.WeatherData) error {
+	ctx, done := startQuery(ctx, "save_weather_data")
+	defer done()
+
 	query := `
 		INSERT INTO weather_data 
 		(city, country, state, temperature, description, humidity, icon, condition_code, timestamp) 
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := s.db.Exec(query,
+	_, err := s.db.ExecContext(ctx, query,
 		data.City, data.Country, data.State, data.Temperature,
 		data.Description, data.Humidity, data.Icon, data.ConditionCode, data.Timestamp)
 
@@ -78,14 +198,17 @@ func (s *DatabaseService) SaveWeatherData(data *models.WeatherData) error {
 }
 
 // GetWeatherHistory retrieves recent weather history
-func (s *DatabaseService) GetWeatherHistory(limit int) ([]models.WeatherData, error) {
+func (s *DatabaseService) GetWeatherHistory(ctx context.Context, limit int) ([]models.WeatherData, error) {
+	ctx, done := startQuery(ctx, "get_weather_history")
+	defer done()
+
 	query := `
 		SELECT id, city, country, state, temperature, description, humidity, icon, condition_code, timestamp 
 		FROM weather_data 
 		ORDER BY timestamp DESC 
 		LIMIT ?`
 
-	rows, err := s.db.Query(query, limit)
+	rows, err := s.db.QueryContext(ctx, query, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query weather history: %w", err)
 	}
@@ -111,9 +234,565 @@ func (s *DatabaseService) GetWeatherHistory(limit int) ([]models.WeatherData, er
 	return history, nil
 }
 
+// StreamWeatherHistory calls fn for each stored observation matching filter,
+// newest first like GetWeatherHistory, so a limit keeps the most recent
+// observations. The result set is not loaded into memory. Iteration stops
+// at the first error returned by fn.
+func (s *DatabaseService) StreamWeatherHistory(ctx context.Context, filter models.HistoryFilter, fn func(*models.WeatherData) error) error {
+	ctx, done := startQuery(ctx, "stream_weather_history")
+	defer done()
+
+	query, args := historyQuery(filter)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query weather history: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var data models.WeatherData
+		err := rows.Scan(
+			&data.ID, &data.City, &data.Country, &data.State,
+			&data.Temperature, &data.Description, &data.Humidity,
+			&data.Icon, &data.ConditionCode, &data.Timestamp)
+		if err != nil {
+			return fmt.Errorf("failed to scan weather data: %w", err)
+		}
+		if !filter.Matches(data.Timestamp) {
+			continue
+		}
+		if err := fn(&data); err != nil {
+			return err
+		}
+		count++
+		if filter.Limit > 0 && count >= filter.Limit {
+			break
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return nil
+}
+
+// historyQuery builds the query for a history filter. Timestamps are stored
+// as text in the writer's local time, so the time range is only narrowed to
+// whole days here, with a day of slack on each side for zone offsets;
+// callers apply the exact range to the scanned values.
+func historyQuery(filter models.HistoryFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if filter.City != "" {
+		conditions = append(conditions, "city = ? COLLATE NOCASE")
+		args = append(args, filter.City)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, filter.From.UTC().AddDate(0, 0, -1).Format(time.DateOnly))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "timestamp < ?")
+		args = append(args, filter.To.UTC().AddDate(0, 0, 2).Format(time.DateOnly))
+	}
+
+	query := `
+		SELECT id, city, country, state, temperature, description, humidity, icon, condition_code, timestamp 
+		FROM weather_data`
+	if len(conditions) > 0 {
+		query += `
+		WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += `
+		ORDER BY timestamp DESC, id DESC`
+
+	return query, args
+}
+
+// ImportWeatherData inserts a batch of observations in one transaction,
+// skipping any whose city and timestamp are already stored or appear earlier
+// in the batch. It reports which observations were skipped.
+func (s *DatabaseService) ImportWeatherData(ctx context.Context, batch []models.WeatherData) ([]bool, error) {
+	ctx, done := startQuery(ctx, "import_weather_data")
+	defer done()
+
+	duplicates := make([]bool, len(batch))
+	if len(batch) == 0 {
+		return duplicates, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin import: %w", err)
+	}
+	defer tx.Rollback()
+
+	seen, err := storedObservations(ctx, tx, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO weather_data 
+		(city, country, state, temperature, description, humidity, icon, condition_code, timestamp) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare import: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, data := range batch {
+		key := observationKey(data.City, data.Timestamp)
+		if seen[key] {
+			duplicates[i] = true
+			continue
+		}
+		seen[key] = true
+
+		_, err := stmt.ExecContext(ctx,
+			data.City, data.Country, data.State, data.Temperature,
+			data.Description, data.Humidity, data.Icon, data.ConditionCode, data.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import weather data: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	return duplicates, nil
+}
+
+// storedObservations returns the keys of stored observations for the cities
+// and time span of a batch
+func storedObservations(ctx context.Context, tx *sql.Tx, batch []models.WeatherData) (map[string]bool, error) {
+	cities := make(map[string]bool)
+	from, to := batch[0].Timestamp, batch[0].Timestamp
+	for _, data := range batch {
+		cities[asciiLower(data.City)] = true
+		if data.Timestamp.Before(from) {
+			from = data.Timestamp
+		}
+		if data.Timestamp.After(to) {
+			to = data.Timestamp
+		}
+	}
+
+	// Same day-level narrowing as historyQuery; exact matching happens on
+	// the scanned values
+	placeholders := make([]string, 0, len(cities))
+	args := make([]interface{}, 0, len(cities)+2)
+	for city := range cities {
+		placeholders = append(placeholders, "?")
+		args = append(args, city)
+	}
+	args = append(args,
+		from.UTC().AddDate(0, 0, -1).Format(time.DateOnly),
+		to.UTC().AddDate(0, 0, 2).Format(time.DateOnly))
+
+	query := `
+		SELECT city, timestamp 
+		FROM weather_data 
+		WHERE lower(city) IN (` + strings.Join(placeholders, ", ") + `) 
+		AND timestamp >= ? AND timestamp < ?`
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stored observations: %w", err)
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var city string
+		var timestamp time.Time
+		if err := rows.Scan(&city, &timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan stored observation: %w", err)
+		}
+		seen[observationKey(city, timestamp)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return seen, nil
+}
+
+// asciiLower lowercases ASCII letters only, matching SQLite's lower()
+func asciiLower(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, s)
+}
+
+// observationKey identifies an observation by city and instant
+func observationKey(city string, timestamp time.Time) string {
+	return strings.ToLower(city) + "|" + strconv.FormatInt(timestamp.UnixNano(), 10)
+}
+
 // GetWeatherHistoryDefault retrieves weather history with default limit
-func (s *DatabaseService) GetWeatherHistoryDefault() ([]models.WeatherData, error) {
-	return s.GetWeatherHistory(models.HistoryLimit)
+func (s *DatabaseService) GetWeatherHistoryDefault(ctx context.Context) ([]models.WeatherData, error) {
+	return s.GetWeatherHistory(ctx, s.historyLimit)
+}
+
+// GetLatestWeatherByCity retrieves the most recent stored observation for a city
+func (s *DatabaseService) GetLatestWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
+	ctx, done := startQuery(ctx, "get_latest_weather_by_city")
+	defer done()
+
+	query := `
+		SELECT id, city, country, state, temperature, description, humidity, icon, condition_code, timestamp 
+		FROM weather_data 
+		WHERE city = ? COLLATE NOCASE 
+		ORDER BY timestamp DESC 
+		LIMIT 1`
+
+	var data models.WeatherData
+	err := s.db.QueryRowContext(ctx, query, city).Scan(
+		&data.ID, &data.City, &data.Country, &data.State,
+		&data.Temperature, &data.Description, &data.Humidity,
+		&data.Icon, &data.ConditionCode, &data.Timestamp)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest weather data: %w", err)
+	}
+
+	return &data, nil
+}
+
+// Aggregate tables and the bucket format of each. Buckets are UTC so they
+// sort and compare as text.
+const (
+	hourlyTable = "weather_hourly"
+	dailyTable  = "weather_daily"
+)
+
+var aggregateTables = []string{hourlyTable, dailyTable}
+
+var aggregateBuckets = map[string]string{
+	hourlyTable: "2006-01-02T15:00:00Z",
+	dailyTable:  time.DateOnly,
+}
+
+// aggregate accumulates the observations of one city in one bucket
+type aggregate struct {
+	city, country, state string
+	bucket               string
+	samples              int
+	temperatureMin       float64
+	temperatureMax       float64
+	temperatureSum       float64
+	humiditySum          int
+}
+
+// add includes an observation in the aggregate
+func (a *aggregate) add(data *models.WeatherData) {
+	if a.samples == 0 || data.Temperature < a.temperatureMin {
+		a.temperatureMin = data.Temperature
+	}
+	if a.samples == 0 || data.Temperature > a.temperatureMax {
+		a.temperatureMax = data.Temperature
+	}
+	a.samples++
+	a.temperatureSum += data.Temperature
+	a.humiditySum += data.Humidity
+}
+
+// RollUpWeatherData folds raw observations taken before cutoff into the
+// hourly and daily aggregates and deletes them, batchSize rows per
+// transaction. It returns the number of rows rolled up.
+func (s *DatabaseService) RollUpWeatherData(ctx context.Context, cutoff time.Time, batchSize int) (int, error) {
+	ctx, done := startQuery(ctx, "roll_up_weather_data")
+	defer done()
+
+	total := 0
+	afterID := 0
+	for {
+		expired, lastID, scanned, err := s.expiredWeatherData(ctx, cutoff, afterID, batchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(expired) > 0 {
+			if err := s.rollUp(ctx, expired); err != nil {
+				return total, err
+			}
+			total += len(expired)
+		}
+		if scanned < batchSize {
+			return total, nil
+		}
+		afterID = lastID
+	}
+}
+
+// expiredWeatherData scans up to limit raw rows with an id above afterID
+// that may be older than cutoff and returns those that are, along with the
+// last id and number of rows scanned. Like historyQuery, the SQL only
+// narrows the range to whole days.
+func (s *DatabaseService) expiredWeatherData(ctx context.Context, cutoff time.Time, afterID, limit int) ([]models.WeatherData, int, int, error) {
+	query := `
+		SELECT id, city, country, state, temperature, description, humidity, icon, condition_code, timestamp 
+		FROM weather_data 
+		WHERE id > ? AND timestamp < ? 
+		ORDER BY id 
+		LIMIT ?`
+
+	rows, err := s.db.QueryContext(ctx, query, afterID, cutoff.UTC().AddDate(0, 0, 2).Format(time.DateOnly), limit)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to query expired weather data: %w", err)
+	}
+	defer rows.Close()
+
+	var expired []models.WeatherData
+	lastID, scanned := afterID, 0
+	for rows.Next() {
+		var data models.WeatherData
+		err := rows.Scan(
+			&data.ID, &data.City, &data.Country, &data.State,
+			&data.Temperature, &data.Description, &data.Humidity,
+			&data.Icon, &data.ConditionCode, &data.Timestamp)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to scan weather data: %w", err)
+		}
+		lastID = data.ID
+		scanned++
+		if data.Timestamp.Before(cutoff) {
+			expired = append(expired, data)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, 0, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return expired, lastID, scanned, nil
+}
+
+// rollUp adds observations to the aggregate tables and deletes them in one
+// transaction
+func (s *DatabaseService) rollUp(ctx context.Context, observations []models.WeatherData) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin roll-up: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range aggregateTables {
+		if err := upsertAggregates(ctx, tx, table, observations); err != nil {
+			return err
+		}
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `DELETE FROM weather_data WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare roll-up delete: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, data := range observations {
+		if _, err := stmt.ExecContext(ctx, data.ID); err != nil {
+			return fmt.Errorf("failed to delete rolled-up weather data: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit roll-up: %w", err)
+	}
+
+	return nil
+}
+
+// upsertAggregates adds observations to the buckets of an aggregate table
+func upsertAggregates(ctx context.Context, tx *sql.Tx, table string, observations []models.WeatherData) error {
+	aggregates := make(map[string]*aggregate)
+	var order []string
+	for i := range observations {
+		data := &observations[i]
+		bucket := data.Timestamp.UTC().Format(aggregateBuckets[table])
+		key := strings.ToLower(data.City) + "|" + bucket
+		agg, ok := aggregates[key]
+		if !ok {
+			agg = &aggregate{city: data.City, country: data.Country, state: data.State, bucket: bucket}
+			aggregates[key] = agg
+			order = append(order, key)
+		}
+		agg.add(data)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s 
+		(city, bucket, country, state, samples, temperature_min, temperature_max, temperature_sum, humidity_sum) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) 
+		ON CONFLICT (city, bucket) DO UPDATE SET 
+			samples = samples + excluded.samples, 
+			temperature_min = min(temperature_min, excluded.temperature_min), 
+			temperature_max = max(temperature_max, excluded.temperature_max), 
+			temperature_sum = temperature_sum + excluded.temperature_sum, 
+			humidity_sum = humidity_sum + excluded.humidity_sum`, table))
+	if err != nil {
+		return fmt.Errorf("failed to prepare %s roll-up: %w", table, err)
+	}
+	defer stmt.Close()
+
+	for _, key := range order {
+		agg := aggregates[key]
+		_, err := stmt.ExecContext(ctx,
+			agg.city, agg.bucket, agg.country, agg.state, agg.samples,
+			agg.temperatureMin, agg.temperatureMax, agg.temperatureSum, agg.humiditySum)
+		if err != nil {
+			return fmt.Errorf("failed to roll up into %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteHourlyWeatherBefore deletes hourly aggregates for hours starting
+// before cutoff
+func (s *DatabaseService) DeleteHourlyWeatherBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, done := startQuery(ctx, "delete_hourly_weather")
+	defer done()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM weather_hourly WHERE bucket < ?`,
+		cutoff.UTC().Format(aggregateBuckets[hourlyTable]))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete hourly weather: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// Optimize runs PRAGMA optimize so SQLite can refresh query planner
+// statistics
+func (s *DatabaseService) Optimize(ctx context.Context) error {
+	ctx, done := startQuery(ctx, "optimize")
+	defer done()
+
+	if _, err := s.db.ExecContext(ctx, `PRAGMA optimize`); err != nil {
+		return fmt.Errorf("failed to optimize database: %w", err)
+	}
+	return nil
+}
+
+// Vacuum rebuilds the database file to reclaim space freed by deletes
+func (s *DatabaseService) Vacuum(ctx context.Context) error {
+	ctx, done := startQuery(ctx, "vacuum")
+	defer done()
+
+	if _, err := s.db.ExecContext(ctx, `VACUUM`); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	return nil
+}
+
+// lastVacuumKey is the meta key holding the time of the last vacuum
+const lastVacuumKey = "last_vacuum"
+
+// LastVacuum returns when the database was last vacuumed by the retention
+// job, or the zero time if it has not been recorded
+func (s *DatabaseService) LastVacuum(ctx context.Context) (time.Time, error) {
+	ctx, done := startQuery(ctx, "get_last_vacuum")
+	defer done()
+
+	var value string
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM meta WHERE key = ?`, lastVacuumKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to query last vacuum: %w", err)
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid last vacuum time %q: %w", value, err)
+	}
+	return at, nil
+}
+
+// RecordVacuum stores the time of the last vacuum so it survives restarts
+func (s *DatabaseService) RecordVacuum(ctx context.Context, at time.Time) error {
+	ctx, done := startQuery(ctx, "record_vacuum")
+	defer done()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO meta (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`,
+		lastVacuumKey, at.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("failed to record vacuum: %w", err)
+	}
+	return nil
+}
+
+// usagePeriods returns the day and month keys for a point in time
+func usagePeriods(at time.Time) (string, string) {
+	at = at.UTC()
+	return at.Format("2006-01-02"), at.Format("2006-01")
+}
+
+// IncrementUpstreamUsage records one upstream call for a provider
+func (s *DatabaseService) IncrementUpstreamUsage(ctx context.Context, provider string, at time.Time) error {
+	ctx, done := startQuery(ctx, "increment_upstream_usage")
+	defer done()
+
+	query := `
+		INSERT INTO upstream_usage (provider, period, period_key, calls) 
+		VALUES (?, ?, ?, 1) 
+		ON CONFLICT (provider, period, period_key) DO UPDATE SET calls = calls + 1`
+
+	day, month := usagePeriods(at)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, provider, "day", day); err != nil {
+		return fmt.Errorf("failed to increment daily usage: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, query, provider, "month", month); err != nil {
+		return fmt.Errorf("failed to increment monthly usage: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit usage: %w", err)
+	}
+
+	return nil
+}
+
+// GetUpstreamUsage returns the daily and monthly call counts for a provider
+func (s *DatabaseService) GetUpstreamUsage(ctx context.Context, provider string, at time.Time) (*models.UpstreamUsage, error) {
+	ctx, done := startQuery(ctx, "get_upstream_usage")
+	defer done()
+
+	query := `
+		SELECT calls FROM upstream_usage 
+		WHERE provider = ? AND period = ? AND period_key = ?`
+
+	day, month := usagePeriods(at)
+	usage := &models.UpstreamUsage{Provider: provider, Day: day, Month: month}
+
+	err := s.db.QueryRowContext(ctx, query, provider, "day", day).Scan(&usage.DailyCalls)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to query daily usage: %w", err)
+	}
+
+	err = s.db.QueryRowContext(ctx, query, provider, "month", month).Scan(&usage.MonthlyCalls)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to query monthly usage: %w", err)
+	}
+
+	return usage, nil
 }
 
 // Ensure DatabaseService implements handlers.DatabaseServiceInterface
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -50,7 +52,7 @@ func TestDatabaseService(t *testing.T) {
 			Timestamp:     time.Now(),
 		}
 
-		err = dbService.SaveWeatherData(weatherData)
+		err = dbService.SaveWeatherData(context.Background(), weatherData)
 		assert.NoError(t, err)
 	})
 
@@ -97,12 +99,12 @@ func TestDatabaseService(t *testing.T) {
 		}
 
 		for _, data := range testData {
-			err = dbService.SaveWeatherData(data)
+			err = dbService.SaveWeatherData(context.Background(), data)
 			require.NoError(t, err)
 		}
 
 		// Test getting history with default limit
-		history, err := dbService.GetWeatherHistoryDefault()
+		history, err := dbService.GetWeatherHistoryDefault(context.Background())
 		assert.NoError(t, err)
 		assert.Len(t, history, 3)
 
@@ -112,7 +114,7 @@ func TestDatabaseService(t *testing.T) {
 		assert.True(t, history[1].Timestamp.After(history[2].Timestamp) || history[1].Timestamp.Equal(history[2].Timestamp))
 
 		// Test getting history with custom limit
-		history, err = dbService.GetWeatherHistory(2)
+		history, err = dbService.GetWeatherHistory(context.Background(), 2)
 		assert.NoError(t, err)
 		assert.Len(t, history, 2)
 		assert.True(t, history[0].Timestamp.After(history[1].Timestamp) || history[0].Timestamp.Equal(history[1].Timestamp))
@@ -127,7 +129,7 @@ func TestDatabaseService(t *testing.T) {
 		require.NoError(t, err)
 		defer dbService.Close()
 
-		history, err := dbService.GetWeatherHistoryDefault()
+		history, err := dbService.GetWeatherHistoryDefault(context.Background())
 		assert.NoError(t, err)
 		assert.Len(t, history, 0)
 	})
@@ -149,11 +151,11 @@ func TestDatabaseService(t *testing.T) {
 			Timestamp:     time.Now(),
 		}
 
-		err = dbService.SaveWeatherData(weatherData)
+		err = dbService.SaveWeatherData(context.Background(), weatherData)
 		assert.NoError(t, err)
 
 		// Verify the data was saved correctly
-		history, err := dbService.GetWeatherHistory(1)
+		history, err := dbService.GetWeatherHistory(context.Background(), 1)
 		assert.NoError(t, err)
 		assert.Len(t, history, 1)
 
@@ -171,6 +173,62 @@ func TestDatabaseService(t *testing.T) {
 	})
 }
 
+func TestDatabaseService_GetLatestWeatherByCity(t *testing.T) {
+	testDBPath := "test_latest.db"
+	defer os.Remove(testDBPath)
+
+	dbService, err := NewDatabaseService(testDBPath)
+	require.NoError(t, err)
+	defer dbService.Close()
+
+	for i, temperature := range []float64{10.0, 12.5} {
+		err = dbService.SaveWeatherData(context.Background(), &models.WeatherData{
+			City:        "London",
+			Temperature: temperature,
+			Description: "Cloudy",
+			Humidity:    70,
+			Timestamp:   time.Now().Add(time.Duration(i) * time.Minute),
+		})
+		require.NoError(t, err)
+	}
+
+	latest, err := dbService.GetLatestWeatherByCity(context.Background(), "london")
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, 12.5, latest.Temperature)
+
+	missing, err := dbService.GetLatestWeatherByCity(context.Background(), "Paris")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestDatabaseService_UpstreamUsage(t *testing.T) {
+	testDBPath := "test_usage.db"
+	defer os.Remove(testDBPath)
+
+	dbService, err := NewDatabaseService(testDBPath)
+	require.NoError(t, err)
+	defer dbService.Close()
+
+	day := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, dbService.IncrementUpstreamUsage(context.Background(), "weatherapi", day))
+	require.NoError(t, dbService.IncrementUpstreamUsage(context.Background(), "weatherapi", day))
+	require.NoError(t, dbService.IncrementUpstreamUsage(context.Background(), "weatherapi", day.AddDate(0, 0, 1)))
+	require.NoError(t, dbService.IncrementUpstreamUsage(context.Background(), "other", day))
+
+	usage, err := dbService.GetUpstreamUsage(context.Background(), "weatherapi", day)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-15", usage.Day)
+	assert.Equal(t, "2024-03", usage.Month)
+	assert.Equal(t, 2, usage.DailyCalls)
+	assert.Equal(t, 3, usage.MonthlyCalls)
+
+	usage, err = dbService.GetUpstreamUsage(context.Background(), "weatherapi", day.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	assert.Equal(t, 0, usage.DailyCalls)
+	assert.Equal(t, 0, usage.MonthlyCalls)
+}
+
 func TestDatabaseServiceErrors(t *testing.T) {
 	t.Run("InvalidDatabasePath", func(t *testing.T) {
 		// Test with an invalid path (directory that doesn't exist)
@@ -185,6 +243,8 @@ func TestDatabaseServiceErrors(t *testing.T) {
 		dbService, err := NewDatabaseService(testDBPath)
 		require.NoError(t, err)
 
+		assert.NoError(t, dbService.Ping(context.Background()))
+
 		// Test that close doesn't return an error
 		err = dbService.Close()
 		assert.NoError(t, err)
@@ -192,6 +252,84 @@ func TestDatabaseServiceErrors(t *testing.T) {
 		// Test that close can be called multiple times safely
 		err = dbService.Close()
 		assert.NoError(t, err)
+
+		// A closed database is not ready
+		assert.Error(t, dbService.Ping(context.Background()))
+	})
+}
+
+func TestDatabaseService_StreamWeatherHistory(t *testing.T) {
+	testDBPath := "test_stream.db"
+	defer os.Remove(testDBPath)
+
+	dbService, err := NewDatabaseService(testDBPath)
+	require.NoError(t, err)
+	defer dbService.Close()
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, city := range []string{"London", "Paris", "London", "London"} {
+		err = dbService.SaveWeatherData(context.Background(), &models.WeatherData{
+			City:        city,
+			Temperature: float64(i),
+			Description: "Cloudy",
+			Humidity:    70,
+			Timestamp:   start.Add(time.Duration(i) * 24 * time.Hour).In(time.FixedZone("CET", 3600)),
+		})
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   models.HistoryFilter
+		expected []float64
+	}{
+		{name: "all, newest first", expected: []float64{3, 2, 1, 0}},
+		{name: "city", filter: models.HistoryFilter{City: "london"}, expected: []float64{3, 2, 0}},
+		{name: "time range", filter: models.HistoryFilter{From: start.Add(24 * time.Hour), To: start.Add(72 * time.Hour)}, expected: []float64{2, 1}},
+		{name: "limit keeps the newest", filter: models.HistoryFilter{City: "London", Limit: 2}, expected: []float64{3, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var temperatures []float64
+			err := dbService.StreamWeatherHistory(context.Background(), tt.filter, func(data *models.WeatherData) error {
+				temperatures = append(temperatures, data.Temperature)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, temperatures)
+		})
+	}
+
+	t.Run("callback error stops iteration", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		err := dbService.StreamWeatherHistory(context.Background(), models.HistoryFilter{}, func(data *models.WeatherData) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("writers are not blocked while streaming", func(t *testing.T) {
+		// The callback runs with the read cursor open, as during a slow export
+		writeErrs := make([]error, 0, 3)
+		err := dbService.StreamWeatherHistory(context.Background(), models.HistoryFilter{Limit: 1}, func(data *models.WeatherData) error {
+			start := time.Now()
+			writeErrs = append(writeErrs,
+				dbService.SaveWeatherData(context.Background(), &models.WeatherData{City: "Rome", Description: "Sunny", Timestamp: time.Now()}),
+				dbService.IncrementUpstreamUsage(context.Background(), ProviderWeatherAPI, time.Now()),
+			)
+			_, rollUpErr := dbService.RollUpWeatherData(context.Background(), start.Add(-365*24*time.Hour), 100)
+			writeErrs = append(writeErrs, rollUpErr)
+			assert.Less(t, time.Since(start), time.Second, "writes waited for the reader")
+			return nil
+		})
+		require.NoError(t, err)
+		for _, writeErr := range writeErrs {
+			assert.NoError(t, writeErr)
+		}
 	})
 }
 
@@ -219,7 +357,7 @@ func TestDatabaseConcurrency(t *testing.T) {
 				Humidity:    50,
 				Timestamp:   time.Now(),
 			}
-			err := dbService.SaveWeatherData(weatherData)
+			err := dbService.SaveWeatherData(context.Background(), weatherData)
 			assert.NoError(t, err)
 			done <- true
 		}(i)
@@ -231,7 +369,7 @@ func TestDatabaseConcurrency(t *testing.T) {
 	}
 
 	// Verify all data was saved
-	history, err := dbService.GetWeatherHistory(20)
+	history, err := dbService.GetWeatherHistory(context.Background(), 20)
 	assert.NoError(t, err)
 	assert.Len(t, history, 10)
 }
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"weather-dashboard/handlers"
+	"weather-dashboard/models"
+	"weather-dashboard/utils"
+)
+
+// ImportStore inserts observations, skipping ones already stored
+type ImportStore interface {
+	// ImportWeatherData inserts a batch in one transaction and reports, per
+	// observation, whether it was skipped as a duplicate
+	ImportWeatherData(ctx context.Context, batch []models.WeatherData) ([]bool, error)
+}
+
+// Importer validates observations read from CSV or NDJSON and inserts them
+// in batches
+type Importer struct {
+	store     ImportStore
+	batchSize int
+	now       func() time.Time
+}
+
+// NewImporter creates a new importer writing to store
+func NewImporter(store ImportStore) *Importer {
+	return &Importer{
+		store:     store,
+		batchSize: models.ImportBatchSize,
+		now:       time.Now,
+	}
+}
+
+// importRecord is a decoded line of an import, or the problems found
+// decoding it
+type importRecord struct {
+	line     int
+	data     models.WeatherData
+	problems []models.FieldError
+	err      string
+}
+
+// Import reads observations in format from r and stores the valid ones.
+// Invalid lines are listed in the report rather than failing the import.
+// Batches committed before a store error are kept; since duplicates are
+// skipped, an import can safely be repeated.
+func (i *Importer) Import(ctx context.Context, r io.Reader, format string) (*models.ImportReport, error) {
+	report := &models.ImportReport{}
+	batch := make([]models.WeatherData, 0, i.batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		duplicates, err := i.store.ImportWeatherData(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, duplicate := range duplicates {
+			if duplicate {
+				report.Duplicates++
+			} else {
+				report.Imported++
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	err := decodeImport(r, format, func(record importRecord) error {
+		report.Total++
+		if record.err == "" {
+			record.problems = mergeProblems(record.problems, i.validate(&record.data))
+		}
+		if record.err != "" || len(record.problems) > 0 {
+			report.Failed++
+			addImportError(report, record)
+			return nil
+		}
+
+		batch = append(batch, record.data)
+		if len(batch) < i.batchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+
+	return report, err
+}
+
+// validate checks an observation and normalizes its city name
+func (i *Importer) validate(data *models.WeatherData) []models.FieldError {
+	var problems []models.FieldError
+
+	if err := utils.ValidateCityName(data.City); err != nil {
+		problems = append(problems, models.FieldError{Field: "city", Message: err.Error()})
+	} else {
+		data.City = utils.SanitizeCityName(data.City)
+	}
+	if data.Timestamp.IsZero() {
+		problems = append(problems, models.FieldError{Field: "timestamp", Message: "is required"})
+	} else if data.Timestamp.After(i.now().Add(time.Minute)) {
+		problems = append(problems, models.FieldError{Field: "timestamp", Message: "must not be in the future"})
+	}
+	// Offsets such as +02:00 parse into unnamed zones, which are stored as
+	// text the driver cannot read back; UTC always round-trips
+	data.Timestamp = data.Timestamp.UTC()
+	if data.Temperature < -100 || data.Temperature > 100 {
+		problems = append(problems, models.FieldError{Field: "temperature", Message: "must be between -100 and 100"})
+	}
+	if data.Humidity < 0 || data.Humidity > 100 {
+		problems = append(problems, models.FieldError{Field: "humidity", Message: "must be between 0 and 100"})
+	}
+
+	data.ID = 0
+	data.Cached, data.Stale, data.AgeSeconds = false, false, 0
+	return problems
+}
+
+// mergeProblems adds the problems in more that concern fields not already
+// reported in problems
+func mergeProblems(problems, more []models.FieldError) []models.FieldError {
+	reported := make(map[string]bool, len(problems))
+	for _, problem := range problems {
+		reported[problem.Field] = true
+	}
+	for _, problem := range more {
+		if !reported[problem.Field] {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// addImportError records a rejected line, up to models.MaxImportErrors
+func addImportError(report *models.ImportReport, record importRecord) {
+	if len(report.Errors) >= models.MaxImportErrors {
+		report.ErrorsTruncated = true
+		return
+	}
+
+	message := record.err
+	if message == "" {
+		messages := make([]string, len(record.problems))
+		for i, problem := range record.problems {
+			messages[i] = problem.Field + " " + problem.Message
+		}
+		message = "invalid observation: " + strings.Join(messages, "; ")
+	}
+	report.Errors = append(report.Errors, models.ImportLineError{
+		Line:    record.line,
+		Error:   message,
+		Details: record.problems,
+	})
+}
+
+// decodeImport calls fn for each line of r. Lines that cannot be decoded are
+// passed on with an error so they can be reported; only unreadable input
+// stops decoding.
+func decodeImport(r io.Reader, format string, fn func(importRecord) error) error {
+	switch format {
+	case models.FormatCSV:
+		return decodeCSVImport(r, fn)
+	case models.FormatNDJSON:
+		return decodeNDJSONImport(r, fn)
+	default:
+		return fmt.Errorf("%w: unsupported import format %q (use csv or ndjson)", models.ErrInvalidInput, format)
+	}
+}
+
+// decodeCSVImport reads CSV with a header row naming the columns, using the
+// column names of models.HistoryCSVColumns. The id column is ignored.
+func decodeCSVImport(r io.Reader, fn func(importRecord) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: failed to read CSV header: %v", models.ErrInvalidInput, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"city", "timestamp"} {
+		if _, ok := columns[required]; !ok {
+			return fmt.Errorf("%w: CSV header has no %s column", models.ErrInvalidInput, required)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := fn(importRecord{line: parseErr.StartLine, err: "malformed CSV: " + parseErr.Err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if err := fn(parseCSVRecord(line, columns, record)); err != nil {
+			return err
+		}
+	}
+}
+
+// parseCSVRecord converts a CSV row to an observation
+func parseCSVRecord(line int, columns map[string]int, record []string) importRecord {
+	result := importRecord{line: line}
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	number := func(name string, parse func(string) error) {
+		value := field(name)
+		if value == "" {
+			return
+		}
+		if err := parse(value); err != nil {
+			result.problems = append(result.problems, models.FieldError{Field: name, Message: "must be a number"})
+		}
+	}
+
+	data := &result.data
+	data.City = field("city")
+	data.Country = field("country")
+	data.State = field("state")
+	data.Description = field("description")
+	data.Icon = field("icon")
+	number("temperature", func(v string) (err error) {
+		data.Temperature, err = strconv.ParseFloat(v, 64)
+		return err
+	})
+	number("humidity", func(v string) (err error) {
+		data.Humidity, err = strconv.Atoi(v)
+		return err
+	})
+	number("condition_code", func(v string) (err error) {
+		data.ConditionCode, err = strconv.Atoi(v)
+		return err
+	})
+	if value := field("timestamp"); value != "" {
+		timestamp, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			result.problems = append(result.problems, models.FieldError{Field: "timestamp", Message: "must be an RFC 3339 timestamp"})
+		}
+		data.Timestamp = timestamp
+	}
+
+	return result
+}
+
+// decodeNDJSONImport reads one JSON-encoded models.WeatherData per line.
+// Blank lines are skipped.
+func decodeNDJSONImport(r io.Reader, fn func(importRecord) error) error {
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		raw, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read NDJSON: %w", err)
+		}
+
+		if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 {
+			record := importRecord{line: line}
+			if jsonErr := json.Unmarshal(trimmed, &record.data); jsonErr != nil {
+				record.err = "malformed JSON: " + jsonErr.Error()
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+	}
+}
+
+// Ensure Importer implements the handler interface
+var _ handlers.HistoryImporterInterface = (*Importer)(nil)
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"weather-dashboard/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImporter_Import(t *testing.T) {
+	testDBPath := "test_import.db"
+	defer os.Remove(testDBPath)
+
+	dbService, err := NewDatabaseService(testDBPath)
+	require.NoError(t, err)
+	defer dbService.Close()
+
+	err = dbService.SaveWeatherData(context.Background(), &models.WeatherData{
+		City: "London", Temperature: 10, Description: "Cloudy", Humidity: 70,
+		Timestamp: time.Date(2024, 3, 1, 13, 0, 0, 0, time.FixedZone("CET", 3600)),
+	})
+	require.NoError(t, err)
+
+	importer := NewImporter(dbService)
+	importer.batchSize = 2
+
+	t.Run("csv", func(t *testing.T) {
+		input := strings.Join([]string{
+			"city,country,temperature,humidity,description,timestamp",
+			"london,United Kingdom,10,70,Cloudy,2024-03-01T12:00:00Z",
+			"paris,France,18.2,55,Sunny,2024-03-01T12:00:00Z",
+			"Paris,France,18.2,55,Sunny,2024-03-01T12:00:00Z",
+			"Berlin,Germany,hot,150,Sunny,2024-03-01T12:00:00Z",
+			"Madrid,Spain,21,40,Sunny,",
+			"Rome,Italy,19,60,Sunny,2024-03-01T12:00:00Z",
+		}, "\n")
+
+		report, err := importer.Import(context.Background(), strings.NewReader(input), models.FormatCSV)
+		require.NoError(t, err)
+		assert.Equal(t, 6, report.Total)
+		assert.Equal(t, 2, report.Imported)
+		assert.Equal(t, 2, report.Duplicates)
+		assert.Equal(t, 2, report.Failed)
+
+		require.Len(t, report.Errors, 2)
+		assert.Equal(t, 5, report.Errors[0].Line)
+		assert.Equal(t, []models.FieldError{
+			{Field: "temperature", Message: "must be a number"},
+			{Field: "humidity", Message: "must be between 0 and 100"},
+		}, report.Errors[0].Details)
+		assert.Equal(t, 6, report.Errors[1].Line)
+		assert.Equal(t, []models.FieldError{{Field: "timestamp", Message: "is required"}}, report.Errors[1].Details)
+
+		latest, err := dbService.GetLatestWeatherByCity(context.Background(), "Paris")
+		require.NoError(t, err)
+		require.NotNil(t, latest)
+		assert.Equal(t, "Paris", latest.City)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		input := `{"city":"Tokyo","temperature":22.1,"humidity":80,"description":"Light rain","timestamp":"2024-03-02T00:00:00Z"}
+
+{"city":"Tokyo",
+{"city":"Tokyo","temperature":22.1,"humidity":80,"description":"Light rain","timestamp":"2024-03-02T00:00:00Z"}`
+
+		report, err := importer.Import(context.Background(), strings.NewReader(input), models.FormatNDJSON)
+		require.NoError(t, err)
+		assert.Equal(t, &models.ImportReport{
+			Total: 3, Imported: 1, Duplicates: 1, Failed: 1,
+			Errors: []models.ImportLineError{{Line: 3, Error: report.Errors[0].Error}},
+		}, report)
+		assert.Contains(t, report.Errors[0].Error, "malformed JSON")
+	})
+
+	t.Run("numeric offsets", func(t *testing.T) {
+		input := `{"city":"Cairo","temperature":25,"humidity":30,"description":"Sunny","timestamp":"2024-03-02T14:30:00+02:00"}`
+
+		report, err := importer.Import(context.Background(), strings.NewReader(input), models.FormatNDJSON)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Imported)
+
+		latest, err := dbService.GetLatestWeatherByCity(context.Background(), "Cairo")
+		require.NoError(t, err)
+		require.NotNil(t, latest)
+		assert.True(t, latest.Timestamp.Equal(time.Date(2024, 3, 2, 12, 30, 0, 0, time.UTC)))
+	})
+
+	t.Run("missing columns", func(t *testing.T) {
+		_, err := importer.Import(context.Background(), strings.NewReader("name,temp\nLondon,10\n"), models.FormatCSV)
+		assert.ErrorIs(t, err, models.ErrInvalidInput)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := importer.Import(context.Background(), strings.NewReader(""), "xml")
+		assert.ErrorIs(t, err, models.ErrInvalidInput)
+	})
+}
+
+// failingImportStore fails every batch
+type failingImportStore struct{}
+
+func (failingImportStore) ImportWeatherData(ctx context.Context, batch []models.WeatherData) ([]bool, error) {
+	return nil, errors.New("disk full")
+}
+
+func TestImporter_StoreError(t *testing.T) {
+	input := "city,temperature,timestamp\nLondon,10,2024-03-01T12:00:00Z\n"
+
+	report, err := NewImporter(failingImportStore{}).Import(context.Background(), strings.NewReader(input), models.FormatCSV)
+	assert.EqualError(t, err, "disk full")
+	assert.Equal(t, 1, report.Total)
+	assert.Zero(t, report.Imported)
+}
+
+func TestImporter_ErrorsTruncated(t *testing.T) {
+	var lines []string
+	for i := 0; i < models.MaxImportErrors+5; i++ {
+		lines = append(lines, `{"city":"1"}`)
+	}
+
+	report, err := NewImporter(failingImportStore{}).Import(context.Background(), strings.NewReader(strings.Join(lines, "\n")), models.FormatNDJSON)
+	require.NoError(t, err)
+	assert.Equal(t, models.MaxImportErrors+5, report.Failed)
+	assert.Len(t, report.Errors, models.MaxImportErrors)
+	assert.True(t, report.ErrorsTruncated)
+}
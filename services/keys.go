@@ -0,0 +1,66 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// keyCooldown is how long a key rejected by the provider is skipped
+const keyCooldown = 10 * time.Minute
+
+// keyRing hands out API keys round-robin, skipping keys the provider has
+// recently rejected
+type keyRing struct {
+	keys []string
+
+	mu       sync.Mutex
+	next     int
+	rejected map[string]time.Time
+	now      func() time.Time
+}
+
+// newKeyRing creates a key ring over keys
+func newKeyRing(keys []string) *keyRing {
+	return &keyRing{
+		keys:     keys,
+		rejected: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// pick returns the next usable key. If every key was rejected recently the
+// least recently rejected one is returned. It returns "" when there are no
+// keys.
+func (r *keyRing) pick() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.keys) == 0 {
+		return ""
+	}
+
+	now := r.now()
+	var fallback string
+	var fallbackAt time.Time
+	for i := 0; i < len(r.keys); i++ {
+		key := r.keys[(r.next+i)%len(r.keys)]
+		rejectedAt, rejected := r.rejected[key]
+		if !rejected || now.Sub(rejectedAt) >= keyCooldown {
+			delete(r.rejected, key)
+			r.next = (r.next + i + 1) % len(r.keys)
+			return key
+		}
+		if fallback == "" || rejectedAt.Before(fallbackAt) {
+			fallback, fallbackAt = key, rejectedAt
+		}
+	}
+
+	return fallback
+}
+
+// reject marks key as refused by the provider
+func (r *keyRing) reject(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rejected[key] = r.now()
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRing(t *testing.T) {
+	t.Run("round robin", func(t *testing.T) {
+		ring := newKeyRing([]string{"a", "b", "c"})
+		var picked []string
+		for i := 0; i < 6; i++ {
+			picked = append(picked, ring.pick())
+		}
+		assert.Equal(t, []string{"a", "b", "c", "a", "b", "c"}, picked)
+	})
+
+	t.Run("rejected keys skipped until cooldown", func(t *testing.T) {
+		ring := newKeyRing([]string{"a", "b"})
+		now := time.Now()
+		ring.now = func() time.Time { return now }
+
+		ring.reject("a")
+		assert.Equal(t, "b", ring.pick())
+		assert.Equal(t, "b", ring.pick())
+
+		now = now.Add(keyCooldown)
+		assert.Equal(t, "a", ring.pick())
+	})
+
+	t.Run("all rejected falls back to oldest rejection", func(t *testing.T) {
+		ring := newKeyRing([]string{"a", "b"})
+		now := time.Now()
+		ring.now = func() time.Time { return now }
+
+		ring.reject("b")
+		now = now.Add(time.Second)
+		ring.reject("a")
+		assert.Equal(t, "b", ring.pick())
+	})
+
+	t.Run("no keys", func(t *testing.T) {
+		assert.Empty(t, newKeyRing(nil).pick())
+	})
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"weather-dashboard/models"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// backupStepPages is the number of pages copied per backup step. Locks are
+// released between steps so the server can keep writing during a backup.
+const backupStepPages = 256
+
+// backupRetryDelay is how long a backup step waits when the database is busy
+const backupRetryDelay = 50 * time.Millisecond
+
+// statsTables are the tables whose rows are counted by Stats
+var statsTables = []string{"weather_data", hourlyTable, dailyTable, "upstream_usage"}
+
+// sqliteBackuper is implemented by the SQLite driver's connections
+type sqliteBackuper interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// Backup writes a consistent copy of the database to path using SQLite's
+// online backup, so it can run while the server is writing. An existing file
+// at path is not overwritten.
+func (s *DatabaseService) Backup(ctx context.Context, path string) error {
+	ctx, done := startQuery(ctx, "backup")
+	defer done()
+
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup destination %s already exists", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to check backup destination: %w", err)
+	}
+
+	err := s.withBackuper(ctx, func(b sqliteBackuper) error {
+		backup, err := b.NewBackup(path)
+		if err != nil {
+			return fmt.Errorf("failed to start backup: %w", err)
+		}
+		return runBackup(ctx, backup)
+	})
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// Restore replaces the contents of the database with the backup at path.
+// The backup is checked for integrity first, and the schema is migrated
+// afterwards so backups from older versions can be restored.
+func (s *DatabaseService) Restore(ctx context.Context, path string) error {
+	ctx, done := startQuery(ctx, "restore")
+	defer done()
+
+	if err := verifyBackup(ctx, path); err != nil {
+		return err
+	}
+
+	err := s.withBackuper(ctx, func(b sqliteBackuper) error {
+		restore, err := b.NewRestore(path)
+		if err != nil {
+			return fmt.Errorf("failed to start restore: %w", err)
+		}
+		return runBackup(ctx, restore)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.Migrate(ctx)
+}
+
+// verifyBackup checks that path is an intact database holding observations
+func verifyBackup(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer db.Close()
+
+	problems, err := integrityCheck(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to check backup: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("backup %s is corrupt: %s", path, problems[0])
+	}
+
+	var tables int
+	err = db.QueryRowContext(ctx, `SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'weather_data'`).Scan(&tables)
+	if err != nil {
+		return fmt.Errorf("failed to check backup: %w", err)
+	}
+	if tables == 0 {
+		return fmt.Errorf("%s is not a weather database backup", path)
+	}
+	return nil
+}
+
+// withBackuper calls fn with a dedicated connection's backup API
+func (s *DatabaseService) withBackuper(ctx context.Context, fn func(sqliteBackuper) error) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn interface{}) error {
+		b, ok := driverConn.(sqliteBackuper)
+		if !ok {
+			return fmt.Errorf("database driver does not support backups")
+		}
+		return fn(b)
+	})
+}
+
+// runBackup copies all pages, waiting while the database is locked by
+// other writers, and releases the backup
+func runBackup(ctx context.Context, backup *sqlite.Backup) error {
+	for {
+		more, err := backup.Step(backupStepPages)
+		if err != nil && !isBusy(err) {
+			backup.Finish()
+			return fmt.Errorf("failed to copy database: %w", err)
+		}
+		if err == nil && !more {
+			break
+		}
+
+		if err := ctx.Err(); err != nil {
+			backup.Finish()
+			return err
+		}
+		if err != nil {
+			time.Sleep(backupRetryDelay)
+		}
+	}
+
+	if err := backup.Finish(); err != nil {
+		return fmt.Errorf("failed to finish copying database: %w", err)
+	}
+	return nil
+}
+
+// isBusy reports whether err is SQLite reporting a locked database
+func isBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code() & 0xff
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
+
+// IntegrityCheck runs SQLite's integrity check and returns the problems it
+// found, or none if the database is intact
+func (s *DatabaseService) IntegrityCheck(ctx context.Context) ([]string, error) {
+	ctx, done := startQuery(ctx, "integrity_check")
+	defer done()
+
+	return integrityCheck(ctx, s.db)
+}
+
+// integrityCheck runs PRAGMA integrity_check on db
+func integrityCheck(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `PRAGMA integrity_check`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run integrity check: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return nil, fmt.Errorf("failed to scan integrity check: %w", err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return problems, nil
+}
+
+// Stats reports the schema version, file size, row counts and the time
+// span of the stored observations
+func (s *DatabaseService) Stats(ctx context.Context) (*models.DatabaseStats, error) {
+	ctx, done := startQuery(ctx, "stats")
+	defer done()
+
+	version, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := &models.DatabaseStats{SchemaVersion: version, Rows: make(map[string]int64, len(statsTables))}
+
+	var pageSize, pageCount, freePages int64
+	for _, pragma := range []struct {
+		name string
+		dest *int64
+	}{
+		{"page_size", &pageSize},
+		{"page_count", &pageCount},
+		{"freelist_count", &freePages},
+	} {
+		if err := s.db.QueryRowContext(ctx, `PRAGMA `+pragma.name).Scan(pragma.dest); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", pragma.name, err)
+		}
+	}
+	stats.SizeBytes = pageSize * pageCount
+	stats.FreeBytes = pageSize * freePages
+
+	for _, table := range statsTables {
+		var count int64
+		if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM `+table).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count %s rows: %w", table, err)
+		}
+		stats.Rows[table] = count
+	}
+
+	if stats.Oldest, err = s.observationBound(ctx, true); err != nil {
+		return nil, err
+	}
+	if stats.Newest, err = s.observationBound(ctx, false); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// observationBound returns the oldest or newest observation time, or nil if
+// there are none. Like historyQuery, the SQL ordering of the stored text is
+// only trusted to the day, so the exact bound is found among the
+// observations within a day of the first row.
+func (s *DatabaseService) observationBound(ctx context.Context, oldest bool) (*time.Time, error) {
+	order, condition := "ASC", "timestamp < ?"
+	if !oldest {
+		order, condition = "DESC", "timestamp >= ?"
+	}
+
+	var first time.Time
+	err := s.db.QueryRowContext(ctx, `SELECT timestamp FROM weather_data ORDER BY timestamp `+order+` LIMIT 1`).Scan(&first)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query observation times: %w", err)
+	}
+
+	limit := first.UTC().AddDate(0, 0, 2)
+	if !oldest {
+		limit = first.UTC().AddDate(0, 0, -1)
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT timestamp FROM weather_data WHERE `+condition, limit.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query observation times: %w", err)
+	}
+	defer rows.Close()
+
+	bound := first
+	for rows.Next() {
+		var timestamp time.Time
+		if err := rows.Scan(&timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan observation time: %w", err)
+		}
+		if (oldest && timestamp.Before(bound)) || (!oldest && timestamp.After(bound)) {
+			bound = timestamp
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return &bound, nil
+}
+
+// PruneBefore deletes raw observations taken before cutoff and the hourly
+// and daily aggregates of periods that ended by then, batchSize raw rows per
+// transaction. Unlike the retention job, nothing is rolled up first.
+func (s *DatabaseService) PruneBefore(ctx context.Context, cutoff time.Time, batchSize int) (*models.PruneReport, error) {
+	ctx, done := startQuery(ctx, "prune")
+	defer done()
+
+	report := &models.PruneReport{}
+	afterID := 0
+	for {
+		expired, lastID, scanned, err := s.expiredWeatherData(ctx, cutoff, afterID, batchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(expired) > 0 {
+			deleted, err := s.deleteWeatherData(ctx, expired)
+			report.Observations += deleted
+			if err != nil {
+				return report, err
+			}
+		}
+		if scanned < batchSize {
+			break
+		}
+		afterID = lastID
+	}
+
+	hourly, err := s.DeleteHourlyWeatherBefore(ctx, cutoff)
+	if err != nil {
+		return report, err
+	}
+	report.Hourly = hourly
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM weather_daily WHERE bucket < ?`,
+		cutoff.UTC().Format(aggregateBuckets[dailyTable]))
+	if err != nil {
+		return report, fmt.Errorf("failed to delete daily weather: %w", err)
+	}
+	report.Daily, err = result.RowsAffected()
+
+	return report, err
+}
+
+// deleteWeatherData deletes observations by id in one transaction
+func (s *DatabaseService) deleteWeatherData(ctx context.Context, observations []models.WeatherData) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin prune: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `DELETE FROM weather_data WHERE id = ?`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare prune: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, data := range observations {
+		if _, err := stmt.ExecContext(ctx, data.ID); err != nil {
+			return 0, fmt.Errorf("failed to delete weather data: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit prune: %w", err)
+	}
+
+	return int64(len(observations)), nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"weather-dashboard/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseService_BackupRestore(t *testing.T) {
+	testDBPath := "test_maintenance.db"
+	backupPath := "test_maintenance_backup.db"
+	defer os.Remove(testDBPath)
+	defer os.Remove(backupPath)
+
+	dbService, err := NewDatabaseService(testDBPath)
+	require.NoError(t, err)
+	defer dbService.Close()
+
+	ctx := context.Background()
+	save := func(city string) {
+		require.NoError(t, dbService.SaveWeatherData(ctx, &models.WeatherData{
+			City: city, Temperature: 10, Description: "Cloudy", Humidity: 60, Timestamp: time.Now(),
+		}))
+	}
+	save("London")
+	save("Paris")
+
+	require.NoError(t, dbService.Backup(ctx, backupPath))
+	assert.Error(t, dbService.Backup(ctx, backupPath), "an existing backup must not be overwritten")
+
+	save("Berlin")
+	require.NoError(t, dbService.Restore(ctx, backupPath))
+
+	history, err := dbService.GetWeatherHistory(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	for _, data := range history {
+		assert.NotEqual(t, "Berlin", data.City)
+	}
+
+	version, err := dbService.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, schemaVersion, version)
+
+	t.Run("missing backup", func(t *testing.T) {
+		assert.Error(t, dbService.Restore(ctx, "test_missing_backup.db"))
+		_, err := os.Stat("test_missing_backup.db")
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("not a database", func(t *testing.T) {
+		path := "test_not_a_backup.db"
+		require.NoError(t, os.WriteFile(path, []byte("city,timestamp\n"), 0o600))
+		defer os.Remove(path)
+
+		assert.Error(t, dbService.Restore(ctx, path))
+		history, err := dbService.GetWeatherHistory(ctx, 10)
+		require.NoError(t, err)
+		assert.Len(t, history, 2)
+	})
+}
+
+func TestDatabaseService_StatsAndPrune(t *testing.T) {
+	testDBPath := "test_prune.db"
+	defer os.Remove(testDBPath)
+
+	dbService, err := NewDatabaseService(testDBPath)
+	require.NoError(t, err)
+	defer dbService.Close()
+
+	ctx := context.Background()
+	stats, err := dbService.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, schemaVersion, stats.SchemaVersion)
+	assert.Positive(t, stats.SizeBytes)
+	assert.Nil(t, stats.Oldest)
+	assert.Nil(t, stats.Newest)
+
+	est, cest := time.FixedZone("EST", -5*3600), time.FixedZone("CEST", 2*3600)
+	oldest := time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC)
+	newest := time.Date(2024, 6, 1, 1, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{
+		// Stored text sorts these in the opposite order to their times
+		oldest.In(cest),
+		oldest.Add(time.Hour).In(est),
+		time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC),
+		newest.In(est),
+		newest.Add(-time.Hour).In(cest),
+	} {
+		require.NoError(t, dbService.SaveWeatherData(ctx, &models.WeatherData{
+			City: "London", Temperature: 10, Description: "Cloudy", Humidity: 60, Timestamp: at,
+		}))
+	}
+	_, err = dbService.db.Exec(`INSERT INTO weather_hourly VALUES ('London', '2024-03-01T10:00:00Z', '', '', 1, 1, 1, 1, 1), ('London', '2024-05-01T10:00:00Z', '', '', 1, 1, 1, 1, 1)`)
+	require.NoError(t, err)
+	_, err = dbService.db.Exec(`INSERT INTO weather_daily VALUES ('London', '2024-03-01', '', '', 1, 1, 1, 1, 1)`)
+	require.NoError(t, err)
+
+	stats, err = dbService.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"weather_data": 5, "weather_hourly": 2, "weather_daily": 1, "upstream_usage": 0}, stats.Rows)
+	require.NotNil(t, stats.Oldest)
+	require.NotNil(t, stats.Newest)
+	assert.True(t, stats.Oldest.Equal(oldest), "oldest was %s", stats.Oldest)
+	assert.True(t, stats.Newest.Equal(newest), "newest was %s", stats.Newest)
+
+	report, err := dbService.PruneBefore(ctx, time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC), 2)
+	require.NoError(t, err)
+	assert.Equal(t, &models.PruneReport{Observations: 3, Hourly: 1, Daily: 1}, report)
+
+	stats, err = dbService.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Rows["weather_data"])
+	assert.True(t, stats.Oldest.Equal(newest.Add(-time.Hour)))
+
+	problems, err := dbService.IntegrityCheck(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
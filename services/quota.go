@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"weather-dashboard/config"
+	"weather-dashboard/models"
+)
+
+// ProviderWeatherAPI identifies WeatherAPI.com in usage accounting
+const ProviderWeatherAPI = "weatherapi"
+
+// UsageStore persists upstream call counters
+type UsageStore interface {
+	IncrementUpstreamUsage(ctx context.Context, provider string, at time.Time) error
+	GetUpstreamUsage(ctx context.Context, provider string, at time.Time) (*models.UpstreamUsage, error)
+}
+
+// QuotaTracker counts upstream calls for a provider and enforces the
+// configured daily and monthly budgets
+type QuotaTracker struct {
+	provider string
+	cfg      config.QuotaConfig
+	store    UsageStore
+	mu       sync.Mutex
+	now      func() time.Time
+}
+
+// NewQuotaTracker creates a new quota tracker for a provider
+func NewQuotaTracker(provider string, cfg config.QuotaConfig, store UsageStore) *QuotaTracker {
+	return &QuotaTracker{
+		provider: provider,
+		cfg:      cfg,
+		store:    store,
+		now:      time.Now,
+	}
+}
+
+// Reserve records an upstream call, or returns models.ErrQuotaExceeded
+// without recording it if the budget is used up
+func (q *QuotaTracker) Reserve(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	usage, err := q.store.GetUpstreamUsage(ctx, q.provider, now)
+	if err != nil {
+		return fmt.Errorf("failed to check quota: %w", err)
+	}
+
+	if q.exhausted(usage) {
+		return models.ErrQuotaExceeded
+	}
+
+	if err := q.store.IncrementUpstreamUsage(ctx, q.provider, now); err != nil {
+		return fmt.Errorf("failed to record upstream call: %w", err)
+	}
+
+	return nil
+}
+
+// Usage returns the current usage against the configured limits
+func (q *QuotaTracker) Usage(ctx context.Context) (*models.UpstreamUsage, error) {
+	usage, err := q.store.GetUpstreamUsage(ctx, q.provider, q.now())
+	if err != nil {
+		return nil, err
+	}
+
+	usage.DailyLimit = q.cfg.DailyLimit
+	usage.MonthlyLimit = q.cfg.MonthlyLimit
+	usage.Exhausted = q.exhausted(usage)
+
+	return usage, nil
+}
+
+// DegradeToCache reports whether stored observations should be served
+// once the budget is exhausted
+func (q *QuotaTracker) DegradeToCache() bool {
+	return q.cfg.Mode == config.QuotaModeCache
+}
+
+// exhausted reports whether either budget has been reached
+func (q *QuotaTracker) exhausted(usage *models.UpstreamUsage) bool {
+	if q.cfg.DailyLimit > 0 && usage.DailyCalls >= q.cfg.DailyLimit {
+		return true
+	}
+	if q.cfg.MonthlyLimit > 0 && usage.MonthlyCalls >= q.cfg.MonthlyLimit {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"weather-dashboard/config"
+	"weather-dashboard/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaTracker(t *testing.T) {
+	testDBPath := "test_quota.db"
+	defer os.Remove(testDBPath)
+
+	dbService, err := NewDatabaseService(testDBPath)
+	require.NoError(t, err)
+	defer dbService.Close()
+
+	t.Run("DailyLimit", func(t *testing.T) {
+		tracker := NewQuotaTracker("daily", config.QuotaConfig{DailyLimit: 2, Mode: config.QuotaModeRefuse}, dbService)
+
+		require.NoError(t, tracker.Reserve(context.Background()))
+		require.NoError(t, tracker.Reserve(context.Background()))
+		assert.ErrorIs(t, tracker.Reserve(context.Background()), models.ErrQuotaExceeded)
+
+		usage, err := tracker.Usage(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 2, usage.DailyCalls)
+		assert.Equal(t, 2, usage.MonthlyCalls)
+		assert.Equal(t, 2, usage.DailyLimit)
+		assert.True(t, usage.Exhausted)
+		assert.False(t, tracker.DegradeToCache())
+
+		// A new day resets the daily budget
+		tracker.now = func() time.Time { return time.Now().AddDate(0, 0, 1) }
+		assert.NoError(t, tracker.Reserve(context.Background()))
+	})
+
+	t.Run("MonthlyLimit", func(t *testing.T) {
+		tracker := NewQuotaTracker("monthly", config.QuotaConfig{MonthlyLimit: 1, Mode: config.QuotaModeCache}, dbService)
+
+		require.NoError(t, tracker.Reserve(context.Background()))
+		assert.ErrorIs(t, tracker.Reserve(context.Background()), models.ErrQuotaExceeded)
+		assert.True(t, tracker.DegradeToCache())
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		tracker := NewQuotaTracker("unlimited", config.QuotaConfig{Mode: config.QuotaModeRefuse}, dbService)
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, tracker.Reserve(context.Background()))
+		}
+
+		usage, err := tracker.Usage(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 5, usage.DailyCalls)
+		assert.False(t, usage.Exhausted)
+	})
+}
+
+func TestWeatherService_QuotaExceeded(t *testing.T) {
+	testDBPath := "test_quota_weather.db"
+	defer os.Remove(testDBPath)
+
+	dbService, err := NewDatabaseService(testDBPath)
+	require.NoError(t, err)
+	defer dbService.Close()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.WeatherAPISearchResult{})
+	}))
+	defer server.Close()
+
+	cfg := &config.WeatherConfig{
+		APIKey:    "test-key",
+		SearchURL: server.URL + "/v1/search.json",
+		CacheTTL:  time.Hour,
+	}
+
+	require.NoError(t, dbService.SaveWeatherData(context.Background(), &models.WeatherData{
+		City:        "London",
+		Temperature: 15.5,
+		Description: "Partly cloudy",
+		Humidity:    65,
+		Timestamp:   time.Now(),
+	}))
+
+	t.Run("RefuseMode", func(t *testing.T) {
+		service := NewWeatherService(cfg)
+		service.SetQuota(NewQuotaTracker("refuse", config.QuotaConfig{DailyLimit: 1, Mode: config.QuotaModeRefuse}, dbService), dbService)
+
+		_, err := service.SearchCity(context.Background(), "london")
+		require.NoError(t, err)
+
+		_, err = service.GetWeatherByCity(context.Background(), "london")
+		assert.ErrorIs(t, err, models.ErrQuotaExceeded)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("CacheMode", func(t *testing.T) {
+		service := NewWeatherService(cfg)
+		service.SetQuota(NewQuotaTracker("cache", config.QuotaConfig{DailyLimit: 1, Mode: config.QuotaModeCache}, dbService), dbService)
+		calls = 0
+
+		_, err := service.SearchCity(context.Background(), "london")
+		require.NoError(t, err)
+
+		weatherData, err := service.GetWeatherByCity(context.Background(), "london")
+		require.NoError(t, err)
+		assert.True(t, weatherData.Cached)
+		assert.Equal(t, "London", weatherData.City)
+		assert.Equal(t, 1, calls)
+
+		// Nothing cached for this city
+		_, err = service.GetWeatherByCity(context.Background(), "paris")
+		assert.ErrorIs(t, err, models.ErrQuotaExceeded)
+	})
+	t.Run("CacheModeExpired", func(t *testing.T) {
+		expiring := *cfg
+		expiring.CacheTTL = time.Nanosecond
+		service := NewWeatherService(&expiring)
+		service.SetQuota(NewQuotaTracker("expired", config.QuotaConfig{DailyLimit: 1, Mode: config.QuotaModeCache}, dbService), dbService)
+
+		_, err := service.SearchCity(context.Background(), "london")
+		require.NoError(t, err)
+
+		_, err = service.GetWeatherByCity(context.Background(), "london")
+		assert.ErrorIs(t, err, models.ErrQuotaExceeded)
+	})
+	t.Run("CacheModeDisabled", func(t *testing.T) {
+		disabled := *cfg
+		disabled.CacheTTL = 0
+		service := NewWeatherService(&disabled)
+		service.SetQuota(NewQuotaTracker("disabled", config.QuotaConfig{DailyLimit: 1, Mode: config.QuotaModeCache}, dbService), dbService)
+
+		_, err := service.SearchCity(context.Background(), "london")
+		require.NoError(t, err)
+
+		// A cache TTL of 0 serves no stored reading, however fresh
+		_, err = service.GetWeatherByCity(context.Background(), "london")
+		assert.ErrorIs(t, err, models.ErrQuotaExceeded)
+	})
+}
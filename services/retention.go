@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"weather-dashboard/config"
+	"weather-dashboard/models"
+)
+
+// retentionBatchSize is the number of raw rows rolled up per transaction
+const retentionBatchSize = 1000
+
+// RetentionStore rolls up, deletes and compacts stored observations
+type RetentionStore interface {
+	RollUpWeatherData(ctx context.Context, cutoff time.Time, batchSize int) (int, error)
+	DeleteHourlyWeatherBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	Optimize(ctx context.Context) error
+	Vacuum(ctx context.Context) error
+	LastVacuum(ctx context.Context) (time.Time, error)
+	RecordVacuum(ctx context.Context, at time.Time) error
+}
+
+// RetentionJob applies the retention policy: raw observations older than
+// the raw retention are rolled up into hourly and daily aggregates and
+// deleted, old hourly aggregates are dropped, and the database is
+// optimized and periodically vacuumed
+type RetentionJob struct {
+	cfg   config.RetentionConfig
+	store RetentionStore
+	now   func() time.Time
+}
+
+// NewRetentionJob creates a new retention job. The last vacuum time is kept
+// in the store, so restarts do not postpone vacuuming; on a database with
+// no record the first vacuum happens one vacuum interval after the first run.
+func NewRetentionJob(cfg config.RetentionConfig, store RetentionStore) *RetentionJob {
+	return &RetentionJob{
+		cfg:   cfg,
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Run applies the policy immediately and then every interval until ctx is
+// cancelled. Failures are logged and retried on the next run.
+func (j *RetentionJob) Run(ctx context.Context) {
+	if j.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := j.RunOnce(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "retention run failed", "error", err)
+		} else {
+			slog.InfoContext(ctx, "retention run completed",
+				"rolled_up", report.RolledUp, "deleted_hourly", report.DeletedHourly, "vacuumed", report.Vacuumed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce applies the retention policy once
+func (j *RetentionJob) RunOnce(ctx context.Context) (*models.RetentionReport, error) {
+	report := &models.RetentionReport{}
+	now := j.now()
+
+	if j.cfg.RawDays > 0 {
+		rolledUp, err := j.store.RollUpWeatherData(ctx, now.AddDate(0, 0, -j.cfg.RawDays), retentionBatchSize)
+		report.RolledUp = rolledUp
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if j.cfg.HourlyDays > 0 {
+		deleted, err := j.store.DeleteHourlyWeatherBefore(ctx, now.AddDate(0, 0, -j.cfg.HourlyDays))
+		if err != nil {
+			return report, err
+		}
+		report.DeletedHourly = int(deleted)
+	}
+
+	if err := j.store.Optimize(ctx); err != nil {
+		return report, err
+	}
+
+	if j.cfg.VacuumInterval > 0 {
+		vacuumed, err := j.vacuum(ctx, now)
+		report.Vacuumed = vacuumed
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// vacuum vacuums the database if the vacuum interval has passed since the
+// recorded last vacuum, and reports whether it did
+func (j *RetentionJob) vacuum(ctx context.Context, now time.Time) (bool, error) {
+	lastVacuum, err := j.store.LastVacuum(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// Start the interval from the first run rather than vacuuming at once
+	if lastVacuum.IsZero() {
+		return false, j.store.RecordVacuum(ctx, now)
+	}
+
+	if now.Sub(lastVacuum) < j.cfg.VacuumInterval {
+		return false, nil
+	}
+
+	if err := j.store.Vacuum(ctx); err != nil {
+		return false, err
+	}
+	return true, j.store.RecordVacuum(ctx, now)
+}
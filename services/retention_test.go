@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"weather-dashboard/config"
+	"weather-dashboard/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionJob_RunOnce(t *testing.T) {
+	testDBPath := "test_retention.db"
+	defer os.Remove(testDBPath)
+
+	dbService, err := NewDatabaseService(testDBPath)
+	require.NoError(t, err)
+	defer dbService.Close()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	save := func(city string, temperature float64, humidity int, at time.Time) {
+		err := dbService.SaveWeatherData(context.Background(), &models.WeatherData{
+			City: city, Temperature: temperature, Description: "Cloudy", Humidity: humidity, Timestamp: at,
+		})
+		require.NoError(t, err)
+	}
+
+	old := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	save("London", 10, 60, old.Add(10*time.Minute))
+	save("london", 14, 80, old.Add(50*time.Minute).In(time.FixedZone("BST", 3600)))
+	save("London", 20, 50, old.Add(3*time.Hour))
+	save("Paris", 15, 70, old)
+	save("London", 18, 55, now.Add(-time.Hour))
+
+	job := NewRetentionJob(config.RetentionConfig{RawDays: 30, HourlyDays: 90, VacuumInterval: 24 * time.Hour}, dbService)
+	job.now = func() time.Time { return now }
+	require.NoError(t, dbService.RecordVacuum(context.Background(), now))
+
+	report, err := job.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &models.RetentionReport{RolledUp: 4}, report)
+
+	history, err := dbService.GetWeatherHistory(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, 18.0, history[0].Temperature)
+
+	type bucket struct {
+		samples            int
+		min, max, avg, hum float64
+	}
+	aggregate := func(table, city, key string) bucket {
+		var b bucket
+		err := dbService.db.QueryRow(
+			`SELECT samples, temperature_min, temperature_max, temperature_sum / samples, humidity_sum * 1.0 / samples FROM `+table+` WHERE city = ? AND bucket = ?`,
+			city, key).Scan(&b.samples, &b.min, &b.max, &b.avg, &b.hum)
+		require.NoError(t, err)
+		return b
+	}
+	assert.Equal(t, bucket{2, 10, 14, 12, 70}, aggregate("weather_hourly", "LONDON", "2024-04-01T09:00:00Z"))
+	assert.Equal(t, bucket{1, 20, 20, 20, 50}, aggregate("weather_hourly", "London", "2024-04-01T12:00:00Z"))
+	assert.Equal(t, bucket{3, 10, 20, 44.0 / 3, 190.0 / 3}, aggregate("weather_daily", "London", "2024-04-01"))
+	assert.Equal(t, bucket{1, 15, 15, 15, 70}, aggregate("weather_daily", "Paris", "2024-04-01"))
+
+	t.Run("later rows merge into existing buckets", func(t *testing.T) {
+		save("London", 6, 90, old.Add(30*time.Minute))
+
+		report, err := job.RunOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.RolledUp)
+		assert.Equal(t, bucket{3, 6, 14, 10, 230.0 / 3}, aggregate("weather_hourly", "London", "2024-04-01T09:00:00Z"))
+	})
+
+	t.Run("hourly aggregates expire and the database is vacuumed", func(t *testing.T) {
+		job.now = func() time.Time { return now.AddDate(0, 1, 0) }
+
+		report, err := job.RunOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, &models.RetentionReport{RolledUp: 1, DeletedHourly: 3, Vacuumed: true}, report)
+
+		var hourly, daily int
+		require.NoError(t, dbService.db.QueryRow(`SELECT count(*) FROM weather_hourly WHERE bucket < '2024-05-01'`).Scan(&hourly))
+		require.NoError(t, dbService.db.QueryRow(`SELECT count(*) FROM weather_daily`).Scan(&daily))
+		assert.Zero(t, hourly)
+		assert.Equal(t, 3, daily)
+	})
+}
+
+func TestRetentionJob_VacuumSurvivesRestart(t *testing.T) {
+	testDBPath := "test_retention_vacuum.db"
+	defer os.Remove(testDBPath)
+
+	dbService, err := NewDatabaseService(testDBPath)
+	require.NoError(t, err)
+	defer dbService.Close()
+
+	ctx := context.Background()
+	cfg := config.RetentionConfig{VacuumInterval: 7 * 24 * time.Hour}
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// Each run uses a new job, as after a restart
+	runAt := func(at time.Time) bool {
+		job := NewRetentionJob(cfg, dbService)
+		job.now = func() time.Time { return at }
+		report, err := job.RunOnce(ctx)
+		require.NoError(t, err)
+		return report.Vacuumed
+	}
+
+	assert.False(t, runAt(start), "first run only records the time")
+	recorded, err := dbService.LastVacuum(ctx)
+	require.NoError(t, err)
+	assert.True(t, recorded.Equal(start))
+
+	assert.False(t, runAt(start.AddDate(0, 0, 3)))
+	assert.True(t, runAt(start.AddDate(0, 0, 7)))
+	assert.False(t, runAt(start.AddDate(0, 0, 8)))
+
+	recorded, err = dbService.LastVacuum(ctx)
+	require.NoError(t, err)
+	assert.True(t, recorded.Equal(start.AddDate(0, 0, 7)))
+}
+
+func TestRetentionJob_Disabled(t *testing.T) {
+	testDBPath := "test_retention_disabled.db"
+	defer os.Remove(testDBPath)
+
+	dbService, err := NewDatabaseService(testDBPath)
+	require.NoError(t, err)
+	defer dbService.Close()
+
+	err = dbService.SaveWeatherData(context.Background(), &models.WeatherData{
+		City: "London", Temperature: 10, Description: "Cloudy", Humidity: 60, Timestamp: time.Now().AddDate(-5, 0, 0),
+	})
+	require.NoError(t, err)
+
+	report, err := NewRetentionJob(config.RetentionConfig{}, dbService).RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &models.RetentionReport{}, report)
+
+	history, err := dbService.GetWeatherHistory(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+}
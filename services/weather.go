@@ -1,115 +1,518 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"weather-dashboard/config"
 	"weather-dashboard/handlers"
+	"weather-dashboard/internal/backoff"
+	"weather-dashboard/logging"
+	"weather-dashboard/metrics"
 	"weather-dashboard/models"
+	"weather-dashboard/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Upstream endpoint names used in metrics
+const (
+	EndpointSearch   = "search"
+	EndpointCurrent  = "current"
+	EndpointForecast = "forecast"
 )
 
+// WeatherCache provides previously stored observations and stores readings
+// refreshed in the background
+type WeatherCache interface {
+	GetLatestWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error)
+	SaveWeatherData(ctx context.Context, data *models.WeatherData) error
+}
+
 // WeatherService handles weather API operations
 type WeatherService struct {
-	config *config.WeatherConfig
-	client *http.Client
+	config  atomic.Pointer[config.WeatherConfig]
+	keys    atomic.Pointer[keyRing]
+	client  *http.Client
+	breaker *circuitBreaker
+	quota   *QuotaTracker
+	cache   WeatherCache
+
+	// Background refreshes of cities served stale, keyed by lower-cased name
+	refreshMu  sync.Mutex
+	refreshing map[string]bool
+	refreshes  sync.WaitGroup
+	closed     bool
+	// closing is cancelled by Close to abandon running refreshes
+	closing     context.Context
+	stopRefresh context.CancelFunc
 }
 
+// refreshTimeout bounds a background refresh of a stale reading
+const refreshTimeout = 30 * time.Second
+
+// defaultTimeout applies when the config does not set an upstream timeout
+const defaultTimeout = 10 * time.Second
+
 // NewWeatherService creates a new weather service
 func NewWeatherService(cfg *config.WeatherConfig) *WeatherService {
-	return &WeatherService{
-		config: cfg,
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	service := &WeatherService{
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: timeout,
 		},
+		breaker:    newCircuitBreaker(ProviderWeatherAPI, cfg.BreakerThreshold, cfg.BreakerCooldown),
+		refreshing: make(map[string]bool),
 	}
+	service.closing, service.stopRefresh = context.WithCancel(context.Background())
+	service.Reconfigure(cfg)
+	return service
 }
 
-// SearchCity searches for a city using WeatherAPI
-func (s *WeatherService) SearchCity(city string) ([]models.WeatherAPISearchResult, error) {
-	baseURL := s.config.SearchURL
-	params := url.Values{}
-	params.Add("key", s.config.APIKey)
-	params.Add("q", city)
+// Close cancels background refreshes and waits for them to finish, so the
+// cache can be closed afterwards. No new refreshes start once it is called.
+func (s *WeatherService) Close() {
+	s.refreshMu.Lock()
+	s.closed = true
+	s.refreshMu.Unlock()
+
+	s.stopRefresh()
+	s.refreshes.Wait()
+}
+
+// Reconfigure swaps in new provider settings such as the API keys, cache
+// TTL and retry policy. The upstream timeout and circuit breaker are fixed
+// when the service is created.
+func (s *WeatherService) Reconfigure(cfg *config.WeatherConfig) {
+	s.config.Store(cfg)
+	s.keys.Store(newKeyRing(cfg.Keys()))
+}
 
+// SetQuota enables upstream call budgeting. When the tracker is configured
+// to degrade, city lookups are served from cache once the budget is used up.
+// The cache also backs stale serving when the upstream fails; quota may be
+// nil to use it for that alone.
+func (s *WeatherService) SetQuota(quota *QuotaTracker, cache WeatherCache) {
+	s.quota = quota
+	s.cache = cache
+}
+
+// reserveCall accounts for a single upstream call
+func (s *WeatherService) reserveCall(ctx context.Context) error {
+	if s.quota == nil {
+		return nil
+	}
+	return s.quota.Reserve(ctx)
+}
+
+// get performs a budgeted GET request against an upstream endpoint and
+// returns the response body. Transient failures are retried with
+// exponential backoff, and calls fail fast while the circuit breaker is open.
+func (s *WeatherService) get(ctx context.Context, endpoint, baseURL string, params url.Values) ([]byte, error) {
+	cfg := s.config.Load()
+	retryPolicy := backoff.Policy{BaseDelay: cfg.RetryBaseDelay, MaxDelay: cfg.RetryMaxDelay}
+
+	for attempt := 0; ; attempt++ {
+		if !s.breaker.allow() {
+			return nil, fmt.Errorf("%w: circuit breaker open", models.ErrUpstreamUnavailable)
+		}
+
+		if err := s.reserveCall(ctx); err != nil {
+			s.breaker.abort()
+			return nil, err
+		}
+
+		body, status, retryAfter, err := s.do(ctx, endpoint, baseURL, params)
+
+		// Our own cancellation says nothing about the upstream, so the
+		// call is released without recording an outcome
+		if err != nil && ctx.Err() != nil {
+			s.breaker.abort()
+			return nil, err
+		}
+
+		// Outages count against the breaker; client errors do not
+		transient := err != nil && (status == 0 || status >= http.StatusInternalServerError)
+		s.breaker.record(!transient)
+
+		retryable := transient || status == http.StatusTooManyRequests
+		if err == nil || !retryable || attempt >= cfg.MaxRetries {
+			return body, err
+		}
+
+		delay, ok := retryPolicy.Delay(attempt, retryAfter)
+		if !ok {
+			return nil, err
+		}
+
+		metrics.ObserveRetry(ProviderWeatherAPI, endpoint)
+		slog.DebugContext(ctx, "retrying upstream request",
+			"endpoint", endpoint, "attempt", attempt+1, "delay_ms", delay.Milliseconds(), "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// do performs a single GET request using the next API key, recording call
+// metrics. A key rejected by the provider is skipped for a while.
+func (s *WeatherService) do(ctx context.Context, endpoint, baseURL string, params url.Values) (body []byte, status int, retryAfter time.Duration, err error) {
+	keys := s.keys.Load()
+	key := keys.pick()
+	params.Set("key", key)
 	requestURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
 
-	resp, err := s.client.Get(requestURL)
+	ctx, span := tracing.StartSpan(ctx, ProviderWeatherAPI+"."+endpoint,
+		attribute.String("upstream.provider", ProviderWeatherAPI),
+		attribute.String("upstream.endpoint", endpoint))
+
+	start := time.Now()
+	defer func() {
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		tracing.EndSpan(span, err)
+		metrics.ObserveUpstream(ProviderWeatherAPI, endpoint, start, err)
+		logUpstreamCall(ctx, endpoint, requestURL, status, start, err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search city: %w", err)
+		return nil, 0, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("%w: failed to call %s endpoint: %w", models.ErrUpstreamUnavailable, endpoint, stripURL(err))
 	}
 	defer resp.Body.Close()
 
+	status = resp.StatusCode
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		keys.reject(key)
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+		return nil, status, backoff.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), upstreamStatusError(resp.StatusCode)
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, status, 0, fmt.Errorf("%w: failed to read response body: %w", models.ErrUpstreamUnavailable, err)
+	}
+
+	return body, status, 0, nil
+}
+
+// upstreamStatusError classifies a non-200 response from the provider
+func upstreamStatusError(status int) error {
+	var kind error
+	switch {
+	case status == http.StatusBadRequest:
+		kind = models.ErrInvalidInput
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		kind = models.ErrUnauthorized
+	case status == http.StatusTooManyRequests:
+		kind = models.ErrQuotaExceeded
+	default:
+		kind = models.ErrUpstreamUnavailable
+	}
+	return fmt.Errorf("%w: API request failed with status: %d", kind, status)
+}
+
+// stripURL removes the request URL, which contains the API key, from
+// errors returned by the HTTP client
+func stripURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// logUpstreamCall logs a single upstream call with the API key redacted
+func logUpstreamCall(ctx context.Context, endpoint, requestURL string, status int, start time.Time, err error) {
+	attrs := []any{
+		"provider", ProviderWeatherAPI,
+		"endpoint", endpoint,
+		"url", logging.RedactURL(requestURL),
+		"status", status,
+		"duration_ms", time.Since(start).Milliseconds(),
+	}
+
+	if err != nil {
+		slog.WarnContext(ctx, "upstream request failed", append(attrs, "error", err)...)
+		return
 	}
+	slog.InfoContext(ctx, "upstream request", attrs...)
+}
+
+// SearchCity searches for a city using WeatherAPI
+func (s *WeatherService) SearchCity(ctx context.Context, city string) ([]models.WeatherAPISearchResult, error) {
+	params := url.Values{}
+	params.Add("q", city)
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := s.get(ctx, EndpointSearch, s.config.Load().SearchURL, params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	var results []models.WeatherAPISearchResult
 	if err := json.Unmarshal(body, &results); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal search results: %w", err)
+		return nil, fmt.Errorf("%w: failed to unmarshal search results: %w", models.ErrUpstreamUnavailable, err)
 	}
 
 	return results, nil
 }
 
 // GetWeatherByCoordinates fetches weather data for given coordinates
-func (s *WeatherService) GetWeatherByCoordinates(lat, lon string) (*models.WeatherData, error) {
-	baseURL := s.config.CurrentURL
+func (s *WeatherService) GetWeatherByCoordinates(ctx context.Context, lat, lon string) (*models.WeatherData, error) {
 	params := url.Values{}
-	params.Add("key", s.config.APIKey)
 	params.Add("q", fmt.Sprintf("%s,%s", lat, lon))
 
-	requestURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
-
-	resp, err := s.client.Get(requestURL)
+	body, err := s.get(ctx, EndpointCurrent, s.config.Load().CurrentURL, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get weather by coordinates: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+	var result models.WeatherAPICurrentResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal weather data: %w", models.ErrUpstreamUnavailable, err)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	return s.transformWeatherData(&result), nil
+}
+
+// Ping verifies the upstream provider is reachable. Any HTTP response counts
+// as reachable; no API key is sent so the call does not use quota.
+func (s *WeatherService) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.Load().BaseURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	var result models.WeatherAPICurrentResult
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal weather data: %w", err)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("upstream unreachable: %w", err)
 	}
+	resp.Body.Close()
 
-	return s.transformWeatherData(&result), nil
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("upstream returned status: %d", resp.StatusCode)
+	}
+
+	return nil
 }
 
 // GetWeatherByCity fetches weather data for a city
-func (s *WeatherService) GetWeatherByCity(city string) (*models.WeatherData, error) {
+func (s *WeatherService) GetWeatherByCity(ctx context.Context, city string) (_ *models.WeatherData, err error) {
+	ctx, span := tracing.StartSpan(ctx, "WeatherService.GetWeatherByCity", attribute.String("city", city))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	weatherData, err := s.fetchWeatherByCity(ctx, city)
+	switch {
+	case errors.Is(err, models.ErrQuotaExceeded):
+		return s.cachedWeatherByCity(ctx, city, err)
+	case errors.Is(err, models.ErrUpstreamUnavailable), errors.Is(err, models.ErrUnauthorized):
+		return s.staleWeatherByCity(ctx, city, err)
+	}
+	return weatherData, err
+}
+
+// GetForecast fetches a daily forecast for a city, including today
+func (s *WeatherService) GetForecast(ctx context.Context, city string, days int) (_ *models.Forecast, err error) {
+	ctx, span := tracing.StartSpan(ctx, "WeatherService.GetForecast", attribute.String("city", city))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	results, err := s.SearchCity(ctx, city)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search city: %w", err)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("city %w: %s", models.ErrNotFound, city)
+	}
+
+	params := url.Values{}
+	params.Add("q", fmt.Sprintf("%f,%f", results[0].Lat, results[0].Lon))
+	params.Add("days", strconv.Itoa(days))
+
+	body, err := s.get(ctx, EndpointForecast, s.config.Load().ForecastURL, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forecast: %w", err)
+	}
+
+	var result models.WeatherAPIForecastResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal forecast: %w", models.ErrUpstreamUnavailable, err)
+	}
+
+	return transformForecast(&result), nil
+}
+
+// transformForecast converts a WeatherAPI forecast to our format
+func transformForecast(result *models.WeatherAPIForecastResult) *models.Forecast {
+	forecast := &models.Forecast{
+		City:    result.Location.Name,
+		Country: result.Location.Country,
+		State:   result.Location.Region,
+		Days:    make([]models.ForecastDay, 0, len(result.Forecast.ForecastDay)),
+	}
+	for _, day := range result.Forecast.ForecastDay {
+		forecast.Days = append(forecast.Days, models.ForecastDay{
+			Date:           day.Date,
+			MinTemperature: day.Day.MinTempC,
+			MaxTemperature: day.Day.MaxTempC,
+			AvgTemperature: day.Day.AvgTempC,
+			Humidity:       int(math.Round(day.Day.AvgHumidity)),
+			ChanceOfRain:   day.Day.DailyChanceOfRain,
+			Description:    day.Day.Condition.Text,
+			Icon:           "https:" + day.Day.Condition.Icon,
+			ConditionCode:  day.Day.Condition.Code,
+		})
+	}
+	return forecast
+}
+
+// fetchWeatherByCity resolves a city and fetches its current weather upstream
+func (s *WeatherService) fetchWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
 	// First search for the city to get coordinates
-	results, err := s.SearchCity(city)
+	results, err := s.SearchCity(ctx, city)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search city: %w", err)
 	}
 
 	if len(results) == 0 {
-		return nil, fmt.Errorf("city not found: %s", city)
+		return nil, fmt.Errorf("city %w: %s", models.ErrNotFound, city)
 	}
 
 	// Use the first result to get weather data
 	lat := fmt.Sprintf("%f", results[0].Lat)
 	lon := fmt.Sprintf("%f", results[0].Lon)
 
-	return s.GetWeatherByCoordinates(lat, lon)
+	return s.GetWeatherByCoordinates(ctx, lat, lon)
+}
+
+// cachedWeatherByCity serves the latest stored observation for a city when
+// the upstream budget is exhausted, or returns quotaErr if there is none
+// younger than the configured cache TTL. A cache TTL of 0 disables the
+// fallback.
+func (s *WeatherService) cachedWeatherByCity(ctx context.Context, city string, quotaErr error) (*models.WeatherData, error) {
+	cacheTTL := s.config.Load().CacheTTL
+	if s.quota == nil || !s.quota.DegradeToCache() || cacheTTL <= 0 {
+		return nil, quotaErr
+	}
+
+	cached := s.latestStored(ctx, city, cacheTTL)
+	if cached == nil {
+		return nil, quotaErr
+	}
+	return cached, nil
+}
+
+// staleWeatherByCity serves the latest stored observation, marked stale,
+// when the upstream fails and refreshes it in the background. It returns
+// upstreamErr if stale serving is disabled or there is no observation
+// younger than the configured max staleness.
+func (s *WeatherService) staleWeatherByCity(ctx context.Context, city string, upstreamErr error) (*models.WeatherData, error) {
+	maxStaleness := s.config.Load().MaxStaleness
+	if maxStaleness <= 0 {
+		return nil, upstreamErr
+	}
+
+	stale := s.latestStored(ctx, city, maxStaleness)
+	if stale == nil {
+		return nil, upstreamErr
+	}
+
+	slog.WarnContext(ctx, "serving stale weather", "city", city, "age_seconds", stale.AgeSeconds, "error", upstreamErr)
+	stale.Stale = true
+	s.refreshInBackground(ctx, city)
+	return stale, nil
+}
+
+// latestStored returns the latest stored observation for a city if it is
+// no older than maxAge, or nil
+func (s *WeatherService) latestStored(ctx context.Context, city string, maxAge time.Duration) *models.WeatherData {
+	if s.cache == nil {
+		return nil
+	}
+
+	cached, err := s.cache.GetLatestWeatherByCity(ctx, city)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to look up stored weather", "city", city, "error", err)
+		cached = nil
+	}
+	if cached != nil && time.Since(cached.Timestamp) > maxAge {
+		cached = nil
+	}
+	metrics.ObserveCacheLookup(cached != nil)
+	if cached == nil {
+		return nil
+	}
+
+	cached.Cached = true
+	cached.AgeSeconds = int64(time.Since(cached.Timestamp).Seconds())
+	return cached
+}
+
+// refreshInBackground fetches a fresh reading for a city and stores it,
+// unless a refresh for that city is already running or the service is
+// closed. The refresh outlives the request that triggered it but not Close.
+func (s *WeatherService) refreshInBackground(ctx context.Context, city string) {
+	key := strings.ToLower(city)
+
+	s.refreshMu.Lock()
+	if s.closed || s.refreshing[key] {
+		s.refreshMu.Unlock()
+		return
+	}
+	s.refreshing[key] = true
+	// Added under the lock so Close cannot start waiting in between
+	s.refreshes.Add(1)
+	s.refreshMu.Unlock()
+
+	go func() {
+		defer s.refreshes.Done()
+		defer func() {
+			s.refreshMu.Lock()
+			delete(s.refreshing, key)
+			s.refreshMu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshTimeout)
+		defer cancel()
+		stop := context.AfterFunc(s.closing, cancel)
+		defer stop()
+
+		weatherData, err := s.fetchWeatherByCity(ctx, city)
+		if err != nil {
+			slog.DebugContext(ctx, "background refresh failed", "city", city, "error", err)
+			return
+		}
+		if err := s.cache.SaveWeatherData(ctx, weatherData); err != nil {
+			slog.ErrorContext(ctx, "failed to save refreshed weather", "city", city, "error", err)
+			return
+		}
+		slog.InfoContext(ctx, "refreshed stale weather", "city", city)
+	}()
 }
 
 // transformWeatherData transforms API response to our model
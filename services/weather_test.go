@@ -1,9 +1,12 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -12,6 +15,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestNewWeatherService(t *testing.T) {
@@ -24,10 +31,31 @@ func TestNewWeatherService(t *testing.T) {
 
 	service := NewWeatherService(cfg)
 	assert.NotNil(t, service)
-	assert.Equal(t, cfg, service.config)
+	assert.Equal(t, cfg, service.config.Load())
 	assert.NotNil(t, service.client)
 }
 
+func TestWeatherService_Reconfigure(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.URL.Query().Get("key"))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.WeatherAPISearchResult{})
+	}))
+	defer server.Close()
+
+	service := NewWeatherService(&config.WeatherConfig{APIKey: "old-key", SearchURL: server.URL})
+
+	_, err := service.SearchCity(context.Background(), "london")
+	require.NoError(t, err)
+
+	service.Reconfigure(&config.WeatherConfig{APIKey: "new-key", SearchURL: server.URL})
+
+	_, err = service.SearchCity(context.Background(), "london")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old-key", "new-key"}, keys)
+}
+
 func TestWeatherService_SearchCity(t *testing.T) {
 	// Create a test server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -65,7 +93,7 @@ func TestWeatherService_SearchCity(t *testing.T) {
 	service := NewWeatherService(cfg)
 
 	// Test search
-	results, err := service.SearchCity("london")
+	results, err := service.SearchCity(context.Background(), "london")
 	require.NoError(t, err)
 	require.Len(t, results, 1)
 
@@ -92,7 +120,7 @@ func TestWeatherService_SearchCityEmptyResults(t *testing.T) {
 
 	service := NewWeatherService(cfg)
 
-	results, err := service.SearchCity("nonexistent")
+	results, err := service.SearchCity(context.Background(), "nonexistent")
 	require.NoError(t, err)
 	assert.Len(t, results, 0)
 }
@@ -112,9 +140,51 @@ func TestWeatherService_SearchCityError(t *testing.T) {
 
 	service := NewWeatherService(cfg)
 
-	_, err := service.SearchCity("london")
+	_, err := service.SearchCity(context.Background(), "london")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "API request failed with status: 500")
+	assert.ErrorIs(t, err, models.ErrUpstreamUnavailable)
+}
+
+func TestWeatherService_UpstreamErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected error
+	}{
+		{"bad request", http.StatusBadRequest, "", models.ErrInvalidInput},
+		{"unauthorized", http.StatusUnauthorized, "", models.ErrUnauthorized},
+		{"forbidden", http.StatusForbidden, "", models.ErrUnauthorized},
+		{"rate limited", http.StatusTooManyRequests, "", models.ErrQuotaExceeded},
+		{"server error", http.StatusBadGateway, "", models.ErrUpstreamUnavailable},
+		{"malformed body", http.StatusOK, "not json", models.ErrUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			service := NewWeatherService(&config.WeatherConfig{APIKey: "test-key", SearchURL: server.URL})
+
+			_, err := service.SearchCity(context.Background(), "london")
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.Close()
+
+		service := NewWeatherService(&config.WeatherConfig{APIKey: "test-key", SearchURL: server.URL})
+
+		_, err := service.SearchCity(context.Background(), "london")
+		assert.ErrorIs(t, err, models.ErrUpstreamUnavailable)
+	})
 }
 
 func TestWeatherService_GetWeatherByCoordinates(t *testing.T) {
@@ -156,7 +226,7 @@ func TestWeatherService_GetWeatherByCoordinates(t *testing.T) {
 	service := NewWeatherService(cfg)
 
 	// Test weather by coordinates
-	weatherData, err := service.GetWeatherByCoordinates("51.5074", "-0.1278")
+	weatherData, err := service.GetWeatherByCoordinates(context.Background(), "51.5074", "-0.1278")
 	require.NoError(t, err)
 	assert.NotNil(t, weatherData)
 
@@ -213,7 +283,7 @@ func TestWeatherService_GetWeatherByCity(t *testing.T) {
 	service := NewWeatherService(cfg)
 
 	// Test weather by city
-	weatherData, err := service.GetWeatherByCity("london")
+	weatherData, err := service.GetWeatherByCity(context.Background(), "london")
 	require.NoError(t, err)
 	assert.NotNil(t, weatherData)
 
@@ -243,9 +313,62 @@ func TestWeatherService_GetWeatherByCityNotFound(t *testing.T) {
 	service := NewWeatherService(cfg)
 
 	// Test city not found
-	_, err := service.GetWeatherByCity("nonexistent")
+	_, err := service.GetWeatherByCity(context.Background(), "nonexistent")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "city not found: nonexistent")
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func TestWeatherService_GetForecast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/search.json" {
+			json.NewEncoder(w).Encode([]models.WeatherAPISearchResult{
+				{Name: "London", Region: "England", Country: "United Kingdom", Lat: 51.5074, Lon: -0.1278},
+			})
+			return
+		}
+
+		assert.Equal(t, "/v1/forecast.json", r.URL.Path)
+		assert.Equal(t, "51.507400,-0.127800", r.URL.Query().Get("q"))
+		assert.Equal(t, "2", r.URL.Query().Get("days"))
+		w.Write([]byte(`{
+			"location": {"name": "London", "region": "England", "country": "United Kingdom"},
+			"forecast": {"forecastday": [
+				{"date": "2024-03-01", "day": {"maxtemp_c": 12.5, "mintemp_c": 4.1, "avgtemp_c": 8.3, "avghumidity": 71.6, "daily_chance_of_rain": 80,
+					"condition": {"text": "Light rain", "icon": "//cdn.weatherapi.com/weather/64x64/day/296.png", "code": 1183}}},
+				{"date": "2024-03-02", "day": {"maxtemp_c": 14, "mintemp_c": 6, "avgtemp_c": 10, "avghumidity": 60, "daily_chance_of_rain": 0,
+					"condition": {"text": "Sunny", "icon": "//cdn.weatherapi.com/weather/64x64/day/113.png", "code": 1000}}}
+			]}
+		}`))
+	}))
+	defer server.Close()
+
+	service := NewWeatherService(&config.WeatherConfig{
+		APIKey:      "test-key",
+		SearchURL:   server.URL + "/v1/search.json",
+		ForecastURL: server.URL + "/v1/forecast.json",
+	})
+
+	forecast, err := service.GetForecast(context.Background(), "london", 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, "London", forecast.City)
+	assert.Equal(t, "England", forecast.State)
+	assert.Equal(t, "United Kingdom", forecast.Country)
+	require.Len(t, forecast.Days, 2)
+	assert.Equal(t, models.ForecastDay{
+		Date:           "2024-03-01",
+		MinTemperature: 4.1,
+		MaxTemperature: 12.5,
+		AvgTemperature: 8.3,
+		Humidity:       72,
+		ChanceOfRain:   80,
+		Description:    "Light rain",
+		Icon:           "https://cdn.weatherapi.com/weather/64x64/day/296.png",
+		ConditionCode:  1183,
+	}, forecast.Days[0])
+	assert.Equal(t, "Sunny", forecast.Days[1].Description)
 }
 
 func TestWeatherService_TransformWeatherData(t *testing.T) {
@@ -292,7 +415,451 @@ func TestWeatherService_Timeout(t *testing.T) {
 	service := NewWeatherService(cfg)
 
 	// Test timeout - expect JSON unmarshal error since the response is not a valid array
-	_, err := service.SearchCity("london")
+	_, err := service.SearchCity(context.Background(), "london")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to unmarshal search results")
 }
+
+func TestWeatherService_Ping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// No API key is sent, so the provider rejects the request
+		assert.Empty(t, r.URL.Query().Get("key"))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+
+	service := NewWeatherService(&config.WeatherConfig{BaseURL: server.URL + "/v1"})
+	assert.NoError(t, service.Ping(context.Background()))
+
+	server.Close()
+	assert.Error(t, service.Ping(context.Background()))
+}
+
+func TestWeatherService_ContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cfg := &config.WeatherConfig{
+		APIKey:    "test-key",
+		SearchURL: server.URL + "/v1/search.json",
+	}
+
+	service := NewWeatherService(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := service.SearchCity(ctx, "london")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestWeatherService_ErrorsDoNotLeakAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	cfg := &config.WeatherConfig{
+		APIKey:    "super-secret-key",
+		SearchURL: server.URL + "/v1/search.json",
+	}
+
+	service := NewWeatherService(cfg)
+
+	_, err := service.SearchCity(context.Background(), "london")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "super-secret-key")
+}
+
+func TestWeatherService_KeyRotation(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		keys = append(keys, key)
+		if key == "revoked-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.WeatherAPISearchResult{})
+	}))
+	defer server.Close()
+
+	service := NewWeatherService(&config.WeatherConfig{
+		APIKey:    "revoked-key",
+		APIKeys:   []string{"good-key"},
+		SearchURL: server.URL,
+	})
+
+	_, err := service.SearchCity(context.Background(), "london")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "revoked-key")
+
+	for i := 0; i < 3; i++ {
+		_, err = service.SearchCity(context.Background(), "london")
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"revoked-key", "good-key", "good-key", "good-key"}, keys)
+}
+
+func TestWeatherService_Retries(t *testing.T) {
+	retryConfig := func(url string) *config.WeatherConfig {
+		return &config.WeatherConfig{
+			APIKey:         "test-key",
+			SearchURL:      url,
+			MaxRetries:     2,
+			RetryBaseDelay: time.Millisecond,
+			RetryMaxDelay:  2 * time.Second,
+		}
+	}
+
+	t.Run("transient failures retried", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode([]models.WeatherAPISearchResult{{Name: "London"}})
+		}))
+		defer server.Close()
+
+		results, err := NewWeatherService(retryConfig(server.URL)).SearchCity(context.Background(), "london")
+		require.NoError(t, err)
+		assert.Equal(t, "London", results[0].Name)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		_, err := NewWeatherService(retryConfig(server.URL)).SearchCity(context.Background(), "london")
+		assert.ErrorIs(t, err, models.ErrUpstreamUnavailable)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("client errors not retried", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		_, err := NewWeatherService(retryConfig(server.URL)).SearchCity(context.Background(), "london")
+		assert.ErrorIs(t, err, models.ErrInvalidInput)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("retry after honored", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode([]models.WeatherAPISearchResult{})
+		}))
+		defer server.Close()
+
+		start := time.Now()
+		_, err := NewWeatherService(retryConfig(server.URL)).SearchCity(context.Background(), "london")
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("retry after beyond max delay not retried", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		_, err := NewWeatherService(retryConfig(server.URL)).SearchCity(context.Background(), "london")
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("cancellation stops retries", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		cfg := retryConfig(server.URL)
+		cfg.RetryBaseDelay = time.Second
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := NewWeatherService(cfg).SearchCity(ctx, "london")
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+}
+
+func TestWeatherService_CircuitBreaker(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	service := NewWeatherService(&config.WeatherConfig{
+		APIKey:           "test-key",
+		SearchURL:        server.URL,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := service.SearchCity(context.Background(), "london")
+		assert.ErrorIs(t, err, models.ErrUpstreamUnavailable)
+	}
+
+	_, err := service.SearchCity(context.Background(), "london")
+	assert.ErrorIs(t, err, models.ErrUpstreamUnavailable)
+	assert.Contains(t, err.Error(), "circuit breaker open")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestWeatherService_CircuitBreakerCancelledProbe(t *testing.T) {
+	var hang int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&hang) == 1 {
+			<-release
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	service := NewWeatherService(&config.WeatherConfig{
+		APIKey:           "test-key",
+		SearchURL:        server.URL,
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Minute,
+	})
+	now := time.Now()
+	service.breaker.now = func() time.Time { return now }
+
+	_, err := service.SearchCity(context.Background(), "london")
+	assert.ErrorIs(t, err, models.ErrUpstreamUnavailable)
+	assert.Equal(t, breakerOpen, service.breaker.state)
+
+	// The probe is cancelled before the upstream answers, which must not
+	// close the breaker
+	now = now.Add(time.Minute)
+	atomic.StoreInt32(&hang, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = service.SearchCity(ctx, "london")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, breakerHalfOpen, service.breaker.state)
+
+	// The next call is let through as the probe and its failure reopens
+	atomic.StoreInt32(&hang, 0)
+	_, err = service.SearchCity(context.Background(), "london")
+	assert.ErrorIs(t, err, models.ErrUpstreamUnavailable)
+	assert.NotContains(t, err.Error(), "circuit breaker open")
+	assert.Equal(t, breakerOpen, service.breaker.state)
+}
+
+func TestWeatherService_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/search.json" {
+			json.NewEncoder(w).Encode([]models.WeatherAPISearchResult{{Name: "London", Lat: 51.5, Lon: -0.12}})
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.WeatherConfig{
+		APIKey:     "test-key",
+		SearchURL:  server.URL + "/v1/search.json",
+		CurrentURL: server.URL + "/v1/current.json",
+	}
+
+	service := NewWeatherService(cfg)
+	_, err := service.GetWeatherByCity(context.Background(), "london")
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "weatherapi.search", spans[0].Name())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, "weatherapi.current", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "WeatherService.GetWeatherByCity", spans[2].Name())
+
+	// Upstream spans are children of the service span
+	assert.Equal(t, spans[2].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, spans[2].SpanContext().SpanID(), spans[1].Parent().SpanID())
+}
+
+// fakeCache is an in-memory WeatherCache
+type fakeCache struct {
+	mu     sync.Mutex
+	latest *models.WeatherData
+	saved  []*models.WeatherData
+}
+
+func (c *fakeCache) GetLatestWeatherByCity(ctx context.Context, city string) (*models.WeatherData, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.latest == nil {
+		return nil, nil
+	}
+	latest := *c.latest
+	return &latest, nil
+}
+
+func (c *fakeCache) SaveWeatherData(ctx context.Context, data *models.WeatherData) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saved = append(c.saved, data)
+	return nil
+}
+
+func TestWeatherService_StaleWhileRevalidate(t *testing.T) {
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/search.json" {
+			json.NewEncoder(w).Encode([]models.WeatherAPISearchResult{{Name: "London", Lat: 51.5074, Lon: -0.1278}})
+			return
+		}
+		result := models.WeatherAPICurrentResult{}
+		result.Location.Name = "London"
+		result.Current.TempC = 18
+		json.NewEncoder(w).Encode(result)
+	}))
+	defer server.Close()
+
+	newService := func(maxStaleness time.Duration, cache *fakeCache) *WeatherService {
+		service := NewWeatherService(&config.WeatherConfig{
+			APIKey:       "test-key",
+			SearchURL:    server.URL + "/search.json",
+			CurrentURL:   server.URL + "/current.json",
+			MaxStaleness: maxStaleness,
+		})
+		service.SetQuota(nil, cache)
+		return service
+	}
+
+	t.Run("serves stale reading and refreshes", func(t *testing.T) {
+		healthy.Store(false)
+		cache := &fakeCache{latest: &models.WeatherData{City: "London", Temperature: 12, Timestamp: time.Now().Add(-time.Hour)}}
+		service := newService(6*time.Hour, cache)
+
+		weatherData, err := service.GetWeatherByCity(context.Background(), "london")
+		require.NoError(t, err)
+		assert.True(t, weatherData.Stale)
+		assert.True(t, weatherData.Cached)
+		assert.Equal(t, 12.0, weatherData.Temperature)
+		assert.InDelta(t, 3600, weatherData.AgeSeconds, 5)
+
+		// The first refresh fails while the upstream is down
+		service.refreshes.Wait()
+		assert.Empty(t, cache.saved)
+
+		healthy.Store(true)
+		defer healthy.Store(false)
+		service.refreshInBackground(context.Background(), "london")
+		service.refreshes.Wait()
+		require.Len(t, cache.saved, 1)
+		assert.Equal(t, 18.0, cache.saved[0].Temperature)
+		assert.False(t, cache.saved[0].Stale)
+	})
+
+	t.Run("too old", func(t *testing.T) {
+		healthy.Store(false)
+		cache := &fakeCache{latest: &models.WeatherData{City: "London", Timestamp: time.Now().Add(-2 * time.Hour)}}
+		service := newService(time.Hour, cache)
+
+		_, err := service.GetWeatherByCity(context.Background(), "london")
+		assert.ErrorIs(t, err, models.ErrUpstreamUnavailable)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		healthy.Store(false)
+		cache := &fakeCache{latest: &models.WeatherData{City: "London", Timestamp: time.Now()}}
+		service := newService(0, cache)
+
+		_, err := service.GetWeatherByCity(context.Background(), "london")
+		assert.ErrorIs(t, err, models.ErrUpstreamUnavailable)
+	})
+}
+
+func TestWeatherService_Close(t *testing.T) {
+	// The upstream hangs until the refresh gives up
+	requested := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	cache := &fakeCache{latest: &models.WeatherData{City: "London", Timestamp: time.Now().Add(-time.Hour)}}
+	service := NewWeatherService(&config.WeatherConfig{
+		APIKey:       "test-key",
+		SearchURL:    server.URL + "/search.json",
+		CurrentURL:   server.URL + "/current.json",
+		MaxStaleness: 6 * time.Hour,
+		Timeout:      time.Minute,
+	})
+	service.SetQuota(nil, cache)
+
+	service.refreshInBackground(context.Background(), "london")
+	<-requested
+
+	closed := make(chan struct{})
+	go func() {
+		service.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not cancel the running refresh")
+	}
+	assert.Empty(t, cache.saved)
+
+	// No refreshes start once closed
+	service.refreshInBackground(context.Background(), "paris")
+	service.refreshMu.Lock()
+	assert.Empty(t, service.refreshing)
+	service.refreshMu.Unlock()
+}
@@ -105,7 +105,7 @@ const ErrorHandler = {
 // API service
 const WeatherAPI = {
     async fetchWeather(city) {
-        const response = await fetch(`/api/weather/${encodeURIComponent(city)}`);
+        const response = await fetch(`/api/v1/weather/${encodeURIComponent(city)}`);
         
         if (!response.ok) {
             const errorData = await response.json();
@@ -116,7 +116,7 @@ const WeatherAPI = {
     },
 
     async fetchWeatherByCoordinates(latitude, longitude) {
-        const response = await fetch(`/api/weather/coordinates/${latitude}/${longitude}`);
+        const response = await fetch(`/api/v1/weather/coordinates/${latitude}/${longitude}`);
         
         if (!response.ok) {
             const errorData = await response.json();
@@ -127,7 +127,7 @@ const WeatherAPI = {
     },
 
     async fetchHistory() {
-        const response = await fetch('/api/history');
+        const response = await fetch('/api/v1/history');
         return await response.json();
     }
 };
@@ -252,13 +252,14 @@ func TestIntegration_ErrorHandling(t *testing.T) {
 		w := httptest.NewRecorder()
 		r.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusNotFound, w.Code)
 
 		var errorResponse models.APIError
 		err = json.Unmarshal(w.Body.Bytes(), &errorResponse)
 		require.NoError(t, err)
 
 		assert.Contains(t, errorResponse.Error, "city not found")
+		assert.Equal(t, models.ErrorCodeNotFound, errorResponse.Code)
 	})
 
 	t.Run("InvalidCoordinates", func(t *testing.T) {
@@ -1,51 +1,146 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// City name length limits, in characters
+const (
+	MinCityNameLength = 2
+	MaxCityNameLength = 100
 )
 
+// Coordinate ranges in degrees
+const (
+	MaxLatitude  = 90
+	MaxLongitude = 180
+)
+
+var (
+	// cityNamePattern allows letters and combining marks from any script,
+	// spaces, hyphens and apostrophes
+	cityNamePattern = regexp.MustCompile(`^[\p{L}\p{M}\s\-'’]+$`)
+
+	// coordinatePattern allows plain decimal numbers
+	coordinatePattern = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// ValidateCityName reports why a city name is invalid, or nil if it is valid
+func ValidateCityName(city string) error {
+	cleaned := strings.TrimSpace(norm.NFC.String(city))
+	length := utf8.RuneCountInString(cleaned)
+
+	switch {
+	case cleaned == "":
+		return errors.New("is required")
+	case length < MinCityNameLength:
+		return fmt.Errorf("must be at least %d characters", MinCityNameLength)
+	case length > MaxCityNameLength:
+		return fmt.Errorf("must be at most %d characters", MaxCityNameLength)
+	case !cityNamePattern.MatchString(cleaned):
+		return errors.New("may only contain letters, spaces, hyphens and apostrophes")
+	}
+
+	return nil
+}
+
 // IsValidCityName checks if a city name is valid
 func IsValidCityName(city string) bool {
-	if strings.TrimSpace(city) == "" {
-		return false
-	}
+	return ValidateCityName(city) == nil
+}
 
-	// Check for minimum length
-	if len(strings.TrimSpace(city)) < 2 {
-		return false
+// SanitizeCityName cleans and normalizes city name input: Unicode NFC
+// form, single spaces and title case
+func SanitizeCityName(city string) string {
+	// Compose accents so "Zürich" and "Zürich" are the same city
+	cleaned := norm.NFC.String(city)
+
+	// Trim whitespace and remove extra spaces
+	cleaned = spacePattern.ReplaceAllString(strings.TrimSpace(cleaned), " ")
+
+	return titleCase(cleaned)
+}
+
+// titleCase upper-cases the first letter of each word, where words are
+// separated by spaces or hyphens, and lower-cases the rest
+func titleCase(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	startOfWord := true
+	for _, r := range s {
+		if startOfWord {
+			b.WriteRune(unicode.ToTitle(r))
+		} else {
+			b.WriteRune(unicode.ToLower(r))
+		}
+		startOfWord = r == ' ' || r == '-'
 	}
 
-	// Check for valid characters (letters, spaces, hyphens, apostrophes)
-	validPattern := regexp.MustCompile(`^[a-zA-Z\s\-']+$`)
-	return validPattern.MatchString(city)
+	return b.String()
 }
 
-// SanitizeCityName cleans and normalizes city name input
-func SanitizeCityName(city string) string {
-	// Trim whitespace
-	cleaned := strings.TrimSpace(city)
+// ParseLatitude parses a latitude in degrees, checking it is within ±90
+func ParseLatitude(lat string) (float64, error) {
+	return parseCoordinate(lat, MaxLatitude)
+}
+
+// ParseLongitude parses a longitude in degrees, checking it is within ±180
+func ParseLongitude(lon string) (float64, error) {
+	return parseCoordinate(lon, MaxLongitude)
+}
+
+// parseCoordinate parses a decimal coordinate within ±limit
+func parseCoordinate(value string, limit float64) (float64, error) {
+	if value == "" {
+		return 0, errors.New("is required")
+	}
+
+	if !coordinatePattern.MatchString(value) {
+		return 0, errors.New("must be a decimal number")
+	}
 
-	// Convert to title case
-	cleaned = strings.Title(strings.ToLower(cleaned))
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, errors.New("must be a decimal number")
+	}
 
-	// Remove extra spaces
-	spacePattern := regexp.MustCompile(`\s+`)
-	cleaned = spacePattern.ReplaceAllString(cleaned, " ")
+	if err := CheckCoordinateRange(parsed, limit); err != nil {
+		return 0, err
+	}
 
-	return cleaned
+	return parsed, nil
 }
 
-// IsValidCoordinate checks if latitude and longitude are valid
-func IsValidCoordinate(lat, lon string) bool {
-	// Basic validation - could be enhanced with proper coordinate validation
-	if lat == "" || lon == "" {
-		return false
+// CheckCoordinateRange reports an error if value is outside ±limit
+func CheckCoordinateRange(value, limit float64) error {
+	if value < -limit || value > limit {
+		return fmt.Errorf("must be between -%g and %g", limit, limit)
 	}
+	return nil
+}
 
-	// Check if they can be parsed as floats
-	latPattern := regexp.MustCompile(`^-?\d+(\.\d+)?$`)
-	lonPattern := regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+// FormatCoordinate formats a coordinate without redundant digits
+func FormatCoordinate(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
 
-	return latPattern.MatchString(lat) && lonPattern.MatchString(lon)
+// IsValidCoordinate checks if latitude and longitude are valid decimal
+// numbers within range
+func IsValidCoordinate(lat, lon string) bool {
+	if _, err := ParseLatitude(lat); err != nil {
+		return false
+	}
+	_, err := ParseLongitude(lon)
+	return err == nil
 }
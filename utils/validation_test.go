@@ -1,9 +1,11 @@
 package utils
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsValidCityName(t *testing.T) {
@@ -32,6 +34,51 @@ func TestIsValidCityName(t *testing.T) {
 			city:     "King's Lynn",
 			expected: true,
 		},
+		{
+			name:     "valid city name with accents",
+			city:     "São Paulo",
+			expected: true,
+		},
+		{
+			name:     "valid city name with umlaut",
+			city:     "Zürich",
+			expected: true,
+		},
+		{
+			name:     "valid city name with decomposed accent",
+			city:     "Zu\u0308rich",
+			expected: true,
+		},
+		{
+			name:     "valid city name in non-Latin script",
+			city:     "東京",
+			expected: true,
+		},
+		{
+			name:     "valid city name in Cyrillic",
+			city:     "Нижний Новгород",
+			expected: true,
+		},
+		{
+			name:     "valid city name with combining vowel signs",
+			city:     "नई दिल्ली",
+			expected: true,
+		},
+		{
+			name:     "valid city name with typographic apostrophe",
+			city:     "L’Aquila",
+			expected: true,
+		},
+		{
+			name:     "too long",
+			city:     strings.Repeat("a", MaxCityNameLength+1),
+			expected: false,
+		},
+		{
+			name:     "single non-ASCII character",
+			city:     "Å",
+			expected: false,
+		},
 		{
 			name:     "empty string",
 			city:     "",
@@ -133,6 +180,26 @@ func TestSanitizeCityName(t *testing.T) {
 			input:    "London",
 			expected: "London",
 		},
+		{
+			name:     "accented characters",
+			input:    "  são   PAULO ",
+			expected: "São Paulo",
+		},
+		{
+			name:     "decomposed accent is composed",
+			input:    "zu\u0308rich",
+			expected: "Zürich",
+		},
+		{
+			name:     "hyphenated",
+			input:    "saint-jean",
+			expected: "Saint-Jean",
+		},
+		{
+			name:     "apostrophe does not start a word",
+			input:    "king's lynn",
+			expected: "King's Lynn",
+		},
 	}
 
 	for _, tt := range tests {
@@ -220,13 +287,25 @@ func TestIsValidCoordinate(t *testing.T) {
 			name:     "latitude out of range (too high)",
 			lat:      "91.0",
 			lon:      "0.1278",
-			expected: true, // Current validation only checks format, not range
+			expected: false,
 		},
 		{
 			name:     "longitude out of range (too high)",
 			lat:      "51.5074",
 			lon:      "181.0",
-			expected: true, // Current validation only checks format, not range
+			expected: false,
+		},
+		{
+			name:     "latitude out of range (too low)",
+			lat:      "-90.5",
+			lon:      "0",
+			expected: false,
+		},
+		{
+			name:     "coordinates on the boundary",
+			lat:      "-90",
+			lon:      "180",
+			expected: true,
 		},
 	}
 
@@ -238,6 +317,31 @@ func TestIsValidCoordinate(t *testing.T) {
 	}
 }
 
+func TestParseCoordinates(t *testing.T) {
+	lat, err := ParseLatitude("-33.8688")
+	require.NoError(t, err)
+	assert.Equal(t, -33.8688, lat)
+
+	lon, err := ParseLongitude("151.2093")
+	require.NoError(t, err)
+	assert.Equal(t, 151.2093, lon)
+
+	_, err = ParseLatitude("")
+	assert.EqualError(t, err, "is required")
+
+	_, err = ParseLatitude("north")
+	assert.EqualError(t, err, "must be a decimal number")
+
+	_, err = ParseLatitude("90.1")
+	assert.EqualError(t, err, "must be between -90 and 90")
+
+	_, err = ParseLongitude("-180.1")
+	assert.EqualError(t, err, "must be between -180 and 180")
+
+	assert.Equal(t, "51.5", FormatCoordinate(51.5000))
+	assert.Equal(t, "-0.1278", FormatCoordinate(-0.1278))
+}
+
 func TestEdgeCases(t *testing.T) {
 	t.Run("city name with only spaces and special characters", func(t *testing.T) {
 		assert.False(t, IsValidCityName("   @#$%   "))